@@ -0,0 +1,200 @@
+package s3manager
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Role describes what a user may do with the buckets it is granted on.
+// Every role includes the permissions of the roles below it.
+type Role int
+
+// Available roles.
+const (
+	// RoleNone grants no access at all.
+	RoleNone Role = iota
+	// RoleViewer allows browsing and downloading objects.
+	RoleViewer
+	// RoleUploader additionally allows uploading objects.
+	RoleUploader
+	// RoleEditor additionally allows deleting objects.
+	RoleEditor
+	// RoleAdmin additionally allows creating and deleting buckets and editing bucket policies.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:     "none",
+	RoleViewer:   "viewer",
+	RoleUploader: "uploader",
+	RoleEditor:   "editor",
+	RoleAdmin:    "admin",
+}
+
+// String returns the name of the role.
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+// ParseRole returns the role with the given name.
+func ParseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if strings.EqualFold(name, roleName) {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("invalid role: %s", name)
+}
+
+// RoleBinding grants a role to users and groups on the buckets matching the
+// Instance and Bucket glob patterns. An empty pattern matches everything.
+type RoleBinding struct {
+	Users    []string
+	Groups   []string
+	Role     string
+	Instance string
+	Bucket   string
+}
+
+// roleBinding is a validated RoleBinding.
+type roleBinding struct {
+	users    []string
+	groups   []string
+	role     Role
+	instance string
+	bucket   string
+}
+
+// AccessPolicy decides which role a user holds on a bucket. A nil
+// *AccessPolicy grants everyone full access.
+type AccessPolicy struct {
+	bindings []roleBinding
+}
+
+// NewAccessPolicy creates an AccessPolicy from the given role bindings.
+func NewAccessPolicy(bindings []RoleBinding) (*AccessPolicy, error) {
+	policy := &AccessPolicy{bindings: make([]roleBinding, 0, len(bindings))}
+
+	for i, b := range bindings {
+		role, err := ParseRole(b.Role)
+		if err != nil {
+			return nil, fmt.Errorf("role binding %d: %w", i+1, err)
+		}
+		if len(b.Users) == 0 && len(b.Groups) == 0 {
+			return nil, fmt.Errorf("role binding %d: no users or groups given", i+1)
+		}
+
+		instance, bucket := b.Instance, b.Bucket
+		if instance == "" {
+			instance = "*"
+		}
+		if bucket == "" {
+			bucket = "*"
+		}
+		for _, pattern := range []string{instance, bucket} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("role binding %d: invalid pattern %q: %w", i+1, pattern, err)
+			}
+		}
+
+		policy.bindings = append(policy.bindings, roleBinding{
+			users:    b.Users,
+			groups:   b.Groups,
+			role:     role,
+			instance: instance,
+			bucket:   bucket,
+		})
+	}
+
+	return policy, nil
+}
+
+// RoleFor returns the highest role user holds on bucket in the named instance.
+// If bucket is empty, the highest role user holds on any bucket of the
+// instance is returned.
+func (p *AccessPolicy) RoleFor(user *User, instance, bucket string) Role {
+	if p == nil {
+		return RoleAdmin
+	}
+	if user == nil {
+		return RoleNone
+	}
+
+	role := RoleNone
+	for _, b := range p.bindings {
+		if b.role <= role || !b.appliesTo(user) {
+			continue
+		}
+		if ok, _ := path.Match(b.instance, instance); !ok {
+			continue
+		}
+		if bucket != "" {
+			if ok, _ := path.Match(b.bucket, bucket); !ok {
+				continue
+			}
+		}
+		role = b.role
+	}
+	return role
+}
+
+// InstanceRoleFor returns the highest role user holds on every bucket of the
+// named instance, i.e. from bindings that do not restrict buckets.
+func (p *AccessPolicy) InstanceRoleFor(user *User, instance string) Role {
+	if p == nil {
+		return RoleAdmin
+	}
+	if user == nil {
+		return RoleNone
+	}
+
+	role := RoleNone
+	for _, b := range p.bindings {
+		if b.role <= role || b.bucket != "*" || !b.appliesTo(user) {
+			continue
+		}
+		if ok, _ := path.Match(b.instance, instance); !ok {
+			continue
+		}
+		role = b.role
+	}
+	return role
+}
+
+// GlobalRoleFor returns the highest role user holds through bindings that
+// name no instance or bucket, i.e. on every bucket of every instance,
+// including those that don't exist yet.
+func (p *AccessPolicy) GlobalRoleFor(user *User) Role {
+	if p == nil {
+		return RoleAdmin
+	}
+	if user == nil {
+		return RoleNone
+	}
+
+	role := RoleNone
+	for _, b := range p.bindings {
+		if b.role > role && b.instance == "*" && b.bucket == "*" && b.appliesTo(user) {
+			role = b.role
+		}
+	}
+	return role
+}
+
+// appliesTo reports whether the binding names user or one of its groups.
+func (b roleBinding) appliesTo(user *User) bool {
+	if slices.Contains(b.users, user.Name) {
+		return true
+	}
+	for _, group := range user.Groups {
+		if slices.Contains(b.groups, group) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,274 @@
+package s3manager_test
+
+import (
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestNewAccessPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it          string
+		bindings    []s3manager.RoleBinding
+		expectError bool
+	}{
+		{
+			it: "accepts valid bindings",
+			bindings: []s3manager.RoleBinding{
+				{Groups: []string{"data-team"}, Role: "editor", Instance: "scratch"},
+				{Users: []string{"alice"}, Role: "Admin", Bucket: "logs-*"},
+			},
+		},
+		{
+			it: "returns error for an unknown role",
+			bindings: []s3manager.RoleBinding{
+				{Users: []string{"alice"}, Role: "superuser"},
+			},
+			expectError: true,
+		},
+		{
+			it: "returns error for a binding without subjects",
+			bindings: []s3manager.RoleBinding{
+				{Role: "viewer"},
+			},
+			expectError: true,
+		},
+		{
+			it: "returns error for an invalid pattern",
+			bindings: []s3manager.RoleBinding{
+				{Users: []string{"alice"}, Role: "viewer", Bucket: "[logs"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			policy, err := s3manager.NewAccessPolicy(tc.bindings)
+			if tc.expectError {
+				is.True(err != nil)
+				is.True(policy == nil)
+			} else {
+				is.NoErr(err)
+				is.True(policy != nil)
+			}
+		})
+	}
+}
+
+func TestAccessPolicyRoleFor(t *testing.T) {
+	t.Parallel()
+
+	policy, err := s3manager.NewAccessPolicy([]s3manager.RoleBinding{
+		{Groups: []string{"data-team"}, Role: "editor", Instance: "scratch"},
+		{Groups: []string{"data-team"}, Role: "viewer", Instance: "prod"},
+		{Users: []string{"bob"}, Role: "uploader", Instance: "prod", Bucket: "incoming-*"},
+		{Users: []string{"root"}, Role: "admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &s3manager.User{Name: "alice", Groups: []string{"data-team"}}
+	bob := &s3manager.User{Name: "bob"}
+
+	cases := []struct {
+		it           string
+		policy       *s3manager.AccessPolicy
+		user         *s3manager.User
+		instance     string
+		bucket       string
+		expectedRole s3manager.Role
+	}{
+		{
+			it:           "grants a group role on a matching instance",
+			policy:       policy,
+			user:         alice,
+			instance:     "scratch",
+			bucket:       "anything",
+			expectedRole: s3manager.RoleEditor,
+		},
+		{
+			it:           "grants a lower role on another instance",
+			policy:       policy,
+			user:         alice,
+			instance:     "prod",
+			bucket:       "anything",
+			expectedRole: s3manager.RoleViewer,
+		},
+		{
+			it:           "grants a user role on buckets matching the glob",
+			policy:       policy,
+			user:         bob,
+			instance:     "prod",
+			bucket:       "incoming-2024",
+			expectedRole: s3manager.RoleUploader,
+		},
+		{
+			it:           "grants nothing on buckets not matching the glob",
+			policy:       policy,
+			user:         bob,
+			instance:     "prod",
+			bucket:       "archive",
+			expectedRole: s3manager.RoleNone,
+		},
+		{
+			it:           "returns the highest role on any bucket if no bucket is given",
+			policy:       policy,
+			user:         bob,
+			instance:     "prod",
+			expectedRole: s3manager.RoleUploader,
+		},
+		{
+			it:           "grants nothing on unmatched instances",
+			policy:       policy,
+			user:         alice,
+			instance:     "other",
+			expectedRole: s3manager.RoleNone,
+		},
+		{
+			it:           "grants nothing to anonymous users",
+			policy:       policy,
+			instance:     "scratch",
+			expectedRole: s3manager.RoleNone,
+		},
+		{
+			it:           "grants full access without a policy",
+			user:         bob,
+			instance:     "prod",
+			bucket:       "archive",
+			expectedRole: s3manager.RoleAdmin,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			is.Equal(tc.expectedRole, tc.policy.RoleFor(tc.user, tc.instance, tc.bucket))
+		})
+	}
+}
+
+func TestAccessPolicyInstanceRoleFor(t *testing.T) {
+	t.Parallel()
+
+	policy, err := s3manager.NewAccessPolicy([]s3manager.RoleBinding{
+		{Groups: []string{"platform"}, Role: "admin", Instance: "tenant-*"},
+		{Users: []string{"bob"}, Role: "admin", Instance: "prod", Bucket: "incoming-*"},
+		{Users: []string{"bob"}, Role: "viewer", Instance: "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		it           string
+		policy       *s3manager.AccessPolicy
+		user         *s3manager.User
+		instance     string
+		expectedRole s3manager.Role
+	}{
+		{
+			it:           "grants a role on matching instances",
+			policy:       policy,
+			user:         &s3manager.User{Name: "alice", Groups: []string{"platform"}},
+			instance:     "tenant-a",
+			expectedRole: s3manager.RoleAdmin,
+		},
+		{
+			it:           "ignores bindings restricted to some buckets",
+			policy:       policy,
+			user:         &s3manager.User{Name: "bob"},
+			instance:     "prod",
+			expectedRole: s3manager.RoleViewer,
+		},
+		{
+			it:           "grants nothing to anonymous users",
+			policy:       policy,
+			instance:     "tenant-a",
+			expectedRole: s3manager.RoleNone,
+		},
+		{
+			it:           "grants full access without a policy",
+			user:         &s3manager.User{Name: "bob"},
+			instance:     "prod",
+			expectedRole: s3manager.RoleAdmin,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			is.Equal(tc.expectedRole, tc.policy.InstanceRoleFor(tc.user, tc.instance))
+		})
+	}
+}
+
+func TestAccessPolicyGlobalRoleFor(t *testing.T) {
+	t.Parallel()
+
+	policy, err := s3manager.NewAccessPolicy([]s3manager.RoleBinding{
+		{Groups: []string{"ops"}, Role: "admin"},
+		{Groups: []string{"platform"}, Role: "admin", Instance: "tenant-*"},
+		{Users: []string{"bob"}, Role: "admin", Instance: "?"},
+		{Users: []string{"bob"}, Role: "viewer", Instance: "*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		it           string
+		policy       *s3manager.AccessPolicy
+		user         *s3manager.User
+		expectedRole s3manager.Role
+	}{
+		{
+			it:           "grants the role of bindings for all instances",
+			policy:       policy,
+			user:         &s3manager.User{Name: "carol", Groups: []string{"ops"}},
+			expectedRole: s3manager.RoleAdmin,
+		},
+		{
+			it:           "ignores bindings restricted to some instances",
+			policy:       policy,
+			user:         &s3manager.User{Name: "alice", Groups: []string{"platform"}},
+			expectedRole: s3manager.RoleNone,
+		},
+		{
+			it:           "ignores patterns that merely match the wildcard",
+			policy:       policy,
+			user:         &s3manager.User{Name: "bob"},
+			expectedRole: s3manager.RoleViewer,
+		},
+		{
+			it:           "grants nothing to anonymous users",
+			policy:       policy,
+			expectedRole: s3manager.RoleNone,
+		},
+		{
+			it:           "grants full access without a policy",
+			user:         &s3manager.User{Name: "bob"},
+			expectedRole: s3manager.RoleAdmin,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			is.Equal(tc.expectedRole, tc.policy.GlobalRoleFor(tc.user))
+		})
+	}
+}
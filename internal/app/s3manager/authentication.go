@@ -0,0 +1,34 @@
+package s3manager
+
+import (
+	"context"
+	"net/http"
+)
+
+// User represents an authenticated user of the application.
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator guards the application's routes against unauthenticated access.
+type Authenticator interface {
+	// Middleware wraps next so that it is only reached by authenticated requests.
+	// The authenticated user is available to next via UserFromContext.
+	Middleware(next http.Handler) http.Handler
+}
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// ContextWithUser returns a copy of ctx carrying user.
+func ContextWithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated user stored in ctx, if any.
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey).(*User)
+	return user, ok && user != nil
+}
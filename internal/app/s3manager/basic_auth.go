@@ -0,0 +1,80 @@
+package s3manager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when an unknown user tries to log in so that
+// the response time does not reveal which user names exist.
+var dummyHash = []byte("$2a$10$HP7GX4wAPdejQCdekGvTge88Vve88pteR/bG3um2yzfDTg2VUPYKm")
+
+// BasicAuthenticator authenticates users with HTTP Basic authentication
+// against bcrypt hashes read from an htpasswd file.
+type BasicAuthenticator struct {
+	realm  string
+	hashes map[string][]byte
+}
+
+// NewBasicAuthenticator creates a BasicAuthenticator from the contents of an
+// htpasswd file. Only bcrypt hashes (as created by `htpasswd -B`) are supported.
+func NewBasicAuthenticator(htpasswd io.Reader, realm string) (*BasicAuthenticator, error) {
+	hashes := make(map[string][]byte)
+
+	scanner := bufio.NewScanner(htpasswd)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, hash, found := strings.Cut(line, ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid htpasswd entry on line %d", lineNumber)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("unsupported password hash for user %s on line %d: only bcrypt is supported", name, lineNumber)
+		}
+		hashes[name] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading htpasswd: %w", err)
+	}
+
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no users found in htpasswd")
+	}
+
+	return &BasicAuthenticator{realm: realm, hashes: hashes}, nil
+}
+
+// Middleware implements Authenticator.
+func (a *BasicAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, password, ok := r.BasicAuth()
+		if !ok || !a.verify(name, password) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.realm))
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), &User{Name: name})))
+	})
+}
+
+// verify reports whether password matches the stored hash for name.
+func (a *BasicAuthenticator) verify(name, password string) bool {
+	hash, exists := a.hashes[name]
+	if !exists {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
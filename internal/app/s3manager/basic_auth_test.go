@@ -0,0 +1,150 @@
+package s3manager_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewBasicAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		it          string
+		htpasswd    string
+		expectError bool
+	}{
+		{
+			it:       "parses bcrypt entries, comments and blank lines",
+			htpasswd: "# users\n\nalice:" + string(hash) + "\n",
+		},
+		{
+			it:          "rejects entries without a hash",
+			htpasswd:    "alice\n",
+			expectError: true,
+		},
+		{
+			it:          "rejects non-bcrypt hashes",
+			htpasswd:    "alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n",
+			expectError: true,
+		},
+		{
+			it:          "rejects files without users",
+			htpasswd:    "# nobody here\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			auth, err := s3manager.NewBasicAuthenticator(strings.NewReader(tc.htpasswd), "S3 Manager")
+			if tc.expectError {
+				is.True(err != nil)
+				is.True(auth == nil)
+			} else {
+				is.NoErr(err)
+				is.True(auth != nil)
+			}
+		})
+	}
+}
+
+func TestBasicAuthenticatorMiddleware(t *testing.T) {
+	t.Parallel()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		it                   string
+		username             string
+		password             string
+		sendCredentials      bool
+		expectedStatusCode   int
+		expectedBodyContains string
+	}{
+		{
+			it:                   "passes the authenticated user to the next handler",
+			username:             "alice",
+			password:             "secret",
+			sendCredentials:      true,
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "hello alice",
+		},
+		{
+			it:                   "rejects a wrong password",
+			username:             "alice",
+			password:             "wrong",
+			sendCredentials:      true,
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedBodyContains: "Unauthorized",
+		},
+		{
+			it:                   "rejects an unknown user",
+			username:             "mallory",
+			password:             "secret",
+			sendCredentials:      true,
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedBodyContains: "Unauthorized",
+		},
+		{
+			it:                   "rejects requests without credentials",
+			expectedStatusCode:   http.StatusUnauthorized,
+			expectedBodyContains: "Unauthorized",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			auth, err := s3manager.NewBasicAuthenticator(strings.NewReader("alice:"+string(hash)), "S3 Manager")
+			is.NoErr(err)
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, ok := s3manager.UserFromContext(r.Context())
+				is.True(ok)
+				_, _ = io.WriteString(w, "hello "+user.Name)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			is.NoErr(err)
+			if tc.sendCredentials {
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+
+			rr := httptest.NewRecorder()
+			auth.Middleware(next).ServeHTTP(rr, req)
+			resp := rr.Result()
+			defer func() {
+				err = resp.Body.Close()
+				is.NoErr(err)
+			}()
+			body, err := io.ReadAll(resp.Body)
+			is.NoErr(err)
+
+			is.Equal(tc.expectedStatusCode, resp.StatusCode)
+			is.True(strings.Contains(string(body), tc.expectedBodyContains))
+			if tc.expectedStatusCode == http.StatusUnauthorized {
+				is.True(strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Basic"))
+			}
+		})
+	}
+}
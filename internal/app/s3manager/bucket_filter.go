@@ -0,0 +1,42 @@
+package s3manager
+
+import (
+	"fmt"
+	"path"
+)
+
+// BucketFilter restricts which buckets of an instance can be accessed at all,
+// regardless of the user's role. Buckets matching a Deny pattern are never
+// accessible; if Allow is not empty, only buckets matching one of its
+// patterns are. Patterns use path.Match syntax.
+type BucketFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// NewBucketFilter creates a BucketFilter, validating its patterns.
+func NewBucketFilter(allow, deny []string) (BucketFilter, error) {
+	for _, pattern := range append(append([]string{}, allow...), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return BucketFilter{}, fmt.Errorf("invalid bucket pattern %q: %w", pattern, err)
+		}
+	}
+	return BucketFilter{Allow: allow, Deny: deny}, nil
+}
+
+// Allows reports whether bucket may be accessed.
+func (f BucketFilter) Allows(bucket string) bool {
+	if matchesAny(f.Deny, bucket) {
+		return false
+	}
+	return len(f.Allow) == 0 || matchesAny(f.Allow, bucket)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
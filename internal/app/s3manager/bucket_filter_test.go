@@ -0,0 +1,70 @@
+package s3manager_test
+
+import (
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestBucketFilterAllows(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it       string
+		allow    []string
+		deny     []string
+		bucket   string
+		expected bool
+	}{
+		{
+			it:       "allows everything without patterns",
+			bucket:   "any-bucket",
+			expected: true,
+		},
+		{
+			it:       "allows buckets matching an allow pattern",
+			allow:    []string{"exact", "team-*"},
+			bucket:   "team-data",
+			expected: true,
+		},
+		{
+			it:       "rejects buckets not matching any allow pattern",
+			allow:    []string{"exact", "team-*"},
+			bucket:   "other",
+			expected: false,
+		},
+		{
+			it:       "rejects buckets matching a deny pattern",
+			deny:     []string{"*-secret"},
+			bucket:   "team-secret",
+			expected: false,
+		},
+		{
+			it:       "lets deny patterns win over allow patterns",
+			allow:    []string{"team-*"},
+			deny:     []string{"team-secret"},
+			bucket:   "team-secret",
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			filter, err := s3manager.NewBucketFilter(tc.allow, tc.deny)
+			is.NoErr(err)
+			is.Equal(tc.expected, filter.Allows(tc.bucket))
+		})
+	}
+}
+
+func TestNewBucketFilterRejectsInvalidPatterns(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	_, err := s3manager.NewBucketFilter(nil, []string{"[unterminated"})
+	is.True(err != nil)
+}
@@ -106,6 +106,8 @@ func listObjectsForBucketView(ctx context.Context, s3 S3, bucketName, path strin
 
 // collectObjects drains an S3 ListObjects channel into a slice, returning the
 // first error encountered (if any) instead of a partial, half-listed result.
+// The marker object of the folder at path itself is left out, as it would
+// otherwise be listed as a nameless entry inside the folder.
 func collectObjects(ctx context.Context, s3 S3, bucketName, path string, opts minio.ListObjectsOptions) ([]objectWithIcon, error) {
 	var objs []objectWithIcon
 	objectCh := s3.ListObjects(ctx, bucketName, opts)
@@ -113,6 +115,9 @@ func collectObjects(ctx context.Context, s3 S3, bucketName, path string, opts mi
 		if object.Err != nil {
 			return nil, object.Err
 		}
+		if path != "" && object.Key == path {
+			continue
+		}
 		objs = append(objs, toObjectWithIcon(object, path))
 	}
 	return objs, nil
@@ -134,15 +139,22 @@ func friendlyListObjectsErrorMessage(err error, bucketName, instanceName string)
 }
 
 // toObjectWithIcon converts a minio.ObjectInfo into the template-facing objectWithIcon.
+// Keys ending with a slash are folders, either common prefixes or the
+// zero-byte marker objects of empty folders, and have no size to show.
 func toObjectWithIcon(object minio.ObjectInfo, path string) objectWithIcon {
+	isFolder := strings.HasSuffix(object.Key, "/")
+	sizeDisplay := FormatFileSize(object.Size)
+	if isFolder {
+		sizeDisplay = ""
+	}
 	return objectWithIcon{
 		Key:            object.Key,
 		Size:           object.Size,
-		SizeDisplay:    FormatFileSize(object.Size),
+		SizeDisplay:    sizeDisplay,
 		LastModified:   object.LastModified,
 		Owner:          object.Owner.DisplayName,
 		Icon:           icon(object.Key),
-		IsFolder:       strings.HasSuffix(object.Key, "/"),
+		IsFolder:       isFolder,
 		DisplayName:    strings.TrimSuffix(strings.TrimPrefix(object.Key, path), "/"),
 		VersionID:      object.VersionID,
 		IsLatest:       object.IsLatest,
@@ -157,6 +169,8 @@ func HandleBucketView(s3 S3, templates fs.FS, allowDelete bool, listRecursive bo
 		BucketName          string
 		Objects             []objectWithIcon
 		AllowDelete         bool
+		CanUpload           bool
+		CanManageBucket     bool
 		Paths               []string
 		CurrentPath         string
 		Endpoint            string
@@ -176,6 +190,8 @@ func HandleBucketView(s3 S3, templates fs.FS, allowDelete bool, listRecursive bo
 		ShowVersions        bool
 		VersionsUnavailable bool
 		ShowMetadata        bool
+		MaxPresignExpiry    int
+		SharesEnabled       bool
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -255,6 +271,8 @@ func HandleBucketView(s3 S3, templates fs.FS, allowDelete bool, listRecursive bo
 			BucketName:          bucketName,
 			Objects:             objs,
 			AllowDelete:         allowDelete,
+			CanUpload:           true,
+			CanManageBucket:     true,
 			Paths:               removeEmptyStrings(strings.Split(path, "/")),
 			CurrentPath:         path,
 			Endpoint:            s3.EndpointURL().String(),
@@ -274,6 +292,7 @@ func HandleBucketView(s3 S3, templates fs.FS, allowDelete bool, listRecursive bo
 			ShowVersions:        versionsShown,
 			VersionsUnavailable: versionsUnavailable,
 			ShowMetadata:        showMetadata,
+			MaxPresignExpiry:    int(maxPresignedExpiry / time.Second),
 		}
 
 		funcMap := template.FuncMap{
@@ -167,6 +167,21 @@ func TestHandleBucketView(t *testing.T) {
 			expectedStatusCode:   http.StatusOK,
 			expectedBodyContains: "def",
 		},
+		{
+			it: "renders an empty folder without its marker",
+			listObjectsFunc: func(context.Context, string, minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+				objCh := make(chan minio.ObjectInfo)
+				go func() {
+					objCh <- minio.ObjectInfo{Key: "abc/def/"}
+					close(objCh)
+				}()
+				return objCh
+			},
+			bucketName:           "BUCKET-NAME",
+			path:                 "abc/def/",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "No objects in",
+		},
 		{
 			it: "setting rootUrl works",
 			listObjectsFunc: func(context.Context, string, minio.ListObjectsOptions) <-chan minio.ObjectInfo {
@@ -10,22 +10,26 @@ import (
 // HandleBucketsView renders all buckets on an HTML page.
 func HandleBucketsView(s3 S3, templates fs.FS, allowDelete bool, rootURL string, bucketName string) http.HandlerFunc {
 	type pageData struct {
-		RootURL      string
-		Buckets      []any
-		AllowDelete  bool
-		CurrentS3    *S3Instance
-		S3Instances  []*S3Instance
-		HasError     bool
-		ErrorMessage string
+		RootURL           string
+		Buckets           []any
+		AllowDelete       bool
+		CanCreateBucket   bool
+		CanAddInstance    bool
+		CanRemoveInstance bool
+		CurrentS3         *S3Instance
+		S3Instances       []*S3Instance
+		HasError          bool
+		ErrorMessage      string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		buckets, err := s3.ListBuckets(r.Context())
 
 		data := pageData{
-			RootURL:     rootURL,
-			AllowDelete: allowDelete,
-			HasError:    false,
+			RootURL:         rootURL,
+			AllowDelete:     allowDelete,
+			CanCreateBucket: true,
+			HasError:        false,
 		}
 
 		if err != nil {
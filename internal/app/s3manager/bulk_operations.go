@@ -70,7 +70,7 @@ func HandleBulkDeleteObjects(s3 S3) http.HandlerFunc {
 }
 
 // HandleBulkDownloadObjects downloads multiple objects as a ZIP archive.
-func HandleBulkDownloadObjects(s3 S3) http.HandlerFunc {
+func HandleBulkDownloadObjects(s3 S3, sseInfo SSEType) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		bucketName := mux.Vars(r)["bucketName"]
 
@@ -92,6 +92,12 @@ func HandleBulkDownloadObjects(s3 S3) http.HandlerFunc {
 			return
 		}
 
+		sse, err := readEncryption(sseInfo)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
 		// Set headers for ZIP download
 		timestamp := time.Now().Format("20060102-150405")
 		zipFilename := fmt.Sprintf("%s-%s.zip", bucketName, timestamp)
@@ -109,8 +115,13 @@ func HandleBulkDownloadObjects(s3 S3) http.HandlerFunc {
 
 		// Add each object to the ZIP
 		for _, key := range keys {
+			// Stop once the client is gone or the server shuts down
+			if r.Context().Err() != nil {
+				return
+			}
+
 			// Get the object from S3
-			object, err := s3.GetObject(r.Context(), bucketName, key, minio.GetObjectOptions{})
+			object, err := s3.GetObject(r.Context(), bucketName, key, minio.GetObjectOptions{ServerSideEncryption: sse})
 			if err != nil {
 				// Log error but continue with other files
 				continue
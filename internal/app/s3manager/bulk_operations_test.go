@@ -147,7 +147,7 @@ func TestHandleBulkDownloadObjects(t *testing.T) {
 			s3 := &mocks.S3Mock{}
 
 			r := mux.NewRouter()
-			r.Handle("/api/buckets/{bucketName}/objects/bulk-download", s3manager.HandleBulkDownloadObjects(s3)).Methods(http.MethodGet)
+			r.Handle("/api/buckets/{bucketName}/objects/bulk-download", s3manager.HandleBulkDownloadObjects(s3, s3manager.SSEType{})).Methods(http.MethodGet)
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
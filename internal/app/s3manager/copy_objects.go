@@ -0,0 +1,257 @@
+package s3manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// maxCopySize is the size of the largest object S3 copies in a single
+// request. Larger objects are copied in parts.
+const maxCopySize = 5 << 30
+
+// objectLocation is an object along with the encryption of its bucket.
+type objectLocation struct {
+	Bucket string
+	Key    string
+	SSE    SSEType
+}
+
+// CopyRequest represents the request body for copying objects.
+type CopyRequest struct {
+	// Prefix is the folder the keys are relative to. Without keys, every
+	// object below it is copied.
+	Prefix            string   `json:"prefix"`
+	Keys              []string `json:"keys"`
+	DestinationBucket string   `json:"destination_bucket"`
+	DestinationPrefix string   `json:"destination_prefix"`
+	// IfExists is either "skip" (the default) or "overwrite".
+	IfExists string `json:"if_exists"`
+}
+
+// CopyResult is the outcome of copying a single object. Its status is
+// "copied", "skipped" or "failed".
+type CopyResult struct {
+	Key         string `json:"key"`
+	Destination string `json:"destination"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// CopyResponse represents the response body for copying objects.
+type CopyResponse struct {
+	Objects []CopyResult `json:"objects"`
+}
+
+// HandleCopyObjectsWithManager copies objects server side from a bucket to
+// another bucket or folder of the same instance. The keys are copied right
+// away and reported in a CopyResponse, while a folder without keys is copied
+// by a job.
+func HandleCopyObjectsWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withJobs(manager, RoleViewer, func(current *S3Instance, jobs *jobRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			bucketName := mux.Vars(r)["bucketName"]
+
+			var req CopyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+				return
+			}
+			if req.IfExists == "" {
+				req.IfExists = "skip"
+			}
+			sameBucket := req.DestinationBucket == bucketName
+			switch {
+			case req.DestinationBucket == "":
+				http.Error(w, "destination_bucket must not be empty", http.StatusBadRequest)
+				return
+			case req.Prefix != "" && !strings.HasSuffix(req.Prefix, "/"):
+				http.Error(w, "prefix must end with a slash", http.StatusBadRequest)
+				return
+			case req.DestinationPrefix != "" && !strings.HasSuffix(req.DestinationPrefix, "/"):
+				http.Error(w, "destination_prefix must end with a slash", http.StatusBadRequest)
+				return
+			case req.IfExists != "skip" && req.IfExists != "overwrite":
+				http.Error(w, "if_exists must be skip or overwrite", http.StatusBadRequest)
+				return
+			case sameBucket && req.Prefix == req.DestinationPrefix:
+				http.Error(w, "source and destination must differ", http.StatusBadRequest)
+				return
+			case sameBucket && len(req.Keys) == 0 && strings.HasPrefix(req.DestinationPrefix, req.Prefix):
+				http.Error(w, "destination must not be inside the source folder", http.StatusBadRequest)
+				return
+			}
+			for _, key := range req.Keys {
+				if !strings.HasPrefix(key, req.Prefix) || strings.HasSuffix(key, "/") {
+					http.Error(w, fmt.Sprintf("key %s is not an object below prefix", key), http.StatusBadRequest)
+					return
+				}
+			}
+			if !authorize(manager, w, r, current, req.DestinationBucket, RoleUploader) {
+				return
+			}
+
+			// Fail early if the destination bucket can't be used, e.g.
+			// because it doesn't exist.
+			if _, err := destinationExists(r.Context(), current.Client, req.DestinationBucket, req.DestinationPrefix); err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+
+			s3 := current.Client
+			source := objectLocation{Bucket: bucketName, SSE: current.Features.SSEFor(bucketName)}
+			destination := objectLocation{Bucket: req.DestinationBucket, SSE: current.Features.SSEFor(req.DestinationBucket)}
+
+			if len(req.Keys) > 0 {
+				results := make([]CopyResult, 0, len(req.Keys))
+				for _, key := range req.Keys {
+					source.Key = key
+					destination.Key = req.DestinationPrefix + strings.TrimPrefix(key, req.Prefix)
+					results = append(results, copyWithPolicy(r.Context(), s3, source, destination, -1, req.IfExists))
+				}
+				writeJSON(w, http.StatusOK, CopyResponse{Objects: results})
+				return
+			}
+
+			job := Job{
+				Type:              "copy",
+				Instance:          current.Name,
+				Bucket:            bucketName,
+				Source:            req.Prefix,
+				DestinationBucket: req.DestinationBucket,
+				Destination:       req.DestinationPrefix,
+			}
+			if user, ok := UserFromContext(r.Context()); ok {
+				job.CreatedBy = user.Name
+			}
+			job, err := jobs.start(job, func(ctx context.Context, progress *jobProgress) error {
+				return copyFolder(ctx, s3, source, destination, req.Prefix, req.DestinationPrefix, req.IfExists, progress)
+			})
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusAccepted, job)
+		}
+	})
+}
+
+// copyWithPolicy copies source to destination unless destination exists and
+// ifExists is "skip". A negative size is looked up.
+func copyWithPolicy(ctx context.Context, s3 S3, source, destination objectLocation, size int64, ifExists string) CopyResult {
+	result := CopyResult{Key: source.Key, Destination: destination.Key, Status: "copied"}
+	if ifExists == "skip" {
+		exists, err := destinationExists(ctx, s3, destination.Bucket, destination.Key)
+		if err != nil {
+			result.Status, result.Error = "failed", err.Error()
+			return result
+		}
+		if exists {
+			result.Status = "skipped"
+			return result
+		}
+	}
+	if err := copyObject(ctx, s3, source, destination, size); err != nil {
+		result.Status, result.Error = "failed", err.Error()
+	}
+	return result
+}
+
+// copyFolder copies every object below the source prefix to the destination
+// prefix. The listing is streamed and every destination key is looked up on
+// its own, so folders of any size are copied without holding their keys in
+// memory. Objects that fail to copy are reported and skipped.
+func copyFolder(ctx context.Context, s3 S3, source, destination objectLocation, sourcePrefix, destinationPrefix, ifExists string, progress *jobProgress) error {
+	for object := range s3.ListObjects(ctx, source.Bucket, minio.ListObjectsOptions{Prefix: sourcePrefix, Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("error listing objects: %w", object.Err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		progress.add()
+		source.Key = object.Key
+		destination.Key = destinationPrefix + strings.TrimPrefix(object.Key, sourcePrefix)
+		if ifExists == "skip" {
+			exists, err := destinationExists(ctx, s3, destination.Bucket, destination.Key)
+			if err != nil {
+				progress.fail(object.Key, err)
+				continue
+			}
+			if exists {
+				progress.skip()
+				continue
+			}
+		}
+		if err := copyObject(ctx, s3, source, destination, object.Size); err != nil {
+			progress.fail(object.Key, err)
+			continue
+		}
+		progress.done()
+	}
+	return nil
+}
+
+// copyObject copies an object of size bytes server side. Its metadata, tags
+// and content type are kept, and the copy is encrypted like its bucket. A
+// negative size is looked up.
+func copyObject(ctx context.Context, s3 S3, source, destination objectLocation, size int64) error {
+	srcSSE, err := readEncryption(source.SSE)
+	if err != nil {
+		return err
+	}
+	dstSSE, err := serverSideEncryption(destination.SSE)
+	if err != nil {
+		return err
+	}
+	dst := minio.CopyDestOptions{Bucket: destination.Bucket, Object: destination.Key, Encryption: dstSSE}
+	src := minio.CopySrcOptions{Bucket: source.Bucket, Object: source.Key, Encryption: srcSSE}
+
+	copyAtOnce := func() error {
+		if _, err := s3.CopyObject(ctx, dst, src); err != nil {
+			return fmt.Errorf("error copying object: %w", err)
+		}
+		return nil
+	}
+	if size >= 0 && size <= maxCopySize {
+		return copyAtOnce()
+	}
+	info, err := s3.StatObject(ctx, source.Bucket, source.Key, minio.StatObjectOptions{ServerSideEncryption: srcSSE})
+	if err != nil {
+		return fmt.Errorf("error getting object: %w", err)
+	}
+	if info.Size <= maxCopySize {
+		return copyAtOnce()
+	}
+
+	// A copy in parts starts a new object, so its content type, metadata
+	// and tags have to be set explicitly.
+	tags, err := s3.GetObjectTagging(ctx, source.Bucket, source.Key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting object tags: %w", err)
+	}
+	dst.ReplaceMetadata = true
+	dst.UserMetadata = maps.Clone(info.UserMetadata)
+	if dst.UserMetadata == nil {
+		dst.UserMetadata = map[string]string{}
+	}
+	dst.UserMetadata["Content-Type"] = info.ContentType
+	for _, header := range []string{"Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Language"} {
+		if value := info.Metadata.Get(header); value != "" {
+			dst.UserMetadata[header] = value
+		}
+	}
+	dst.ReplaceTags = true
+	dst.UserTags = tags.ToMap()
+
+	if _, err := s3.ComposeObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("error copying object: %w", err)
+	}
+	return nil
+}
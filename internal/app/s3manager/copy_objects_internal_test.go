@@ -0,0 +1,67 @@
+package s3manager
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
+)
+
+func TestCopyObject(t *testing.T) {
+	t.Parallel()
+
+	source := objectLocation{Bucket: "source", Key: "videos/raw.mp4"}
+	destination := objectLocation{Bucket: "destination", Key: "raw.mp4"}
+
+	t.Run("copies small objects at once", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var copied minio.CopyDestOptions
+		s3 := &stubS3{
+			copyObject: func(_ context.Context, dst minio.CopyDestOptions, _ minio.CopySrcOptions) (minio.UploadInfo, error) {
+				copied = dst
+				return minio.UploadInfo{}, nil
+			},
+		}
+
+		is.NoErr(copyObject(context.Background(), s3, source, destination, maxCopySize))
+		is.Equal("raw.mp4", copied.Object)
+		is.True(!copied.ReplaceMetadata) // metadata is copied by S3
+	})
+
+	t.Run("copies large objects in parts with their metadata and tags", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var composed minio.CopyDestOptions
+		s3 := &stubS3{
+			statObject: func(context.Context, string, string, minio.StatObjectOptions) (minio.ObjectInfo, error) {
+				return minio.ObjectInfo{
+					Size:         maxCopySize + 1,
+					ContentType:  "video/mp4",
+					Metadata:     http.Header{"Cache-Control": {"no-cache"}},
+					UserMetadata: minio.StringMap{"Camera": "front"},
+				}, nil
+			},
+			getObjectTagging: func(context.Context, string, string, minio.GetObjectTaggingOptions) (*tags.Tags, error) {
+				return tags.NewTags(map[string]string{"project": "launch"}, true)
+			},
+			composeObject: func(_ context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error) {
+				is.Equal(1, len(srcs))
+				is.Equal("videos/raw.mp4", srcs[0].Object)
+				composed = dst
+				return minio.UploadInfo{}, nil
+			},
+		}
+
+		is.NoErr(copyObject(context.Background(), s3, source, destination, -1))
+		is.True(composed.ReplaceMetadata)
+		is.Equal(map[string]string{"Camera": "front", "Content-Type": "video/mp4", "Cache-Control": "no-cache"}, composed.UserMetadata)
+		is.True(composed.ReplaceTags)
+		is.Equal(map[string]string{"project": "launch"}, composed.UserTags)
+	})
+}
@@ -0,0 +1,267 @@
+package s3manager_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+)
+
+// newCopyRouter serves the copy and job API of manager.
+func newCopyRouter(manager *s3manager.MultiS3Manager) *mux.Router {
+	r := mux.NewRouter()
+	r.Handle("/{instance}/api/buckets/{bucketName}/copy", s3manager.HandleCopyObjectsWithManager(manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs/{jobId}", s3manager.HandleGetJobWithManager(manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs/{jobId}", s3manager.HandleCancelJobWithManager(manager)).Methods(http.MethodDelete)
+	return r
+}
+
+func TestHandleCopyObjectsWithManager(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		config               func(*s3manager.S3InstanceConfig)
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains string
+		expectedResults      []s3manager.CopyResult
+		expectedKeys         []string
+	}{
+		{
+			it:                 "copies objects to another bucket",
+			body:               `{"prefix": "docs/", "keys": ["docs/a.txt", "docs/sub/b.txt"], "destination_bucket": "backup", "destination_prefix": "2026/"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResults: []s3manager.CopyResult{
+				{Key: "docs/a.txt", Destination: "2026/a.txt", Status: "copied"},
+				{Key: "docs/sub/b.txt", Destination: "2026/sub/b.txt", Status: "copied"},
+			},
+			expectedKeys: []string{"2026/a.txt", "2026/sub/b.txt", "old/a.txt"},
+		},
+		{
+			it:                 "skips existing objects by default",
+			body:               `{"prefix": "docs/", "keys": ["docs/a.txt", "docs/c.txt"], "destination_bucket": "backup", "destination_prefix": "old/"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResults: []s3manager.CopyResult{
+				{Key: "docs/a.txt", Destination: "old/a.txt", Status: "skipped"},
+				{Key: "docs/c.txt", Destination: "old/c.txt", Status: "copied"},
+			},
+			expectedKeys: []string{"old/a.txt", "old/c.txt"},
+		},
+		{
+			it:                 "overwrites existing objects if asked to",
+			body:               `{"prefix": "docs/", "keys": ["docs/a.txt"], "destination_bucket": "backup", "destination_prefix": "old/", "if_exists": "overwrite"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResults: []s3manager.CopyResult{
+				{Key: "docs/a.txt", Destination: "old/a.txt", Status: "copied"},
+			},
+			expectedKeys: []string{"old/a.txt"},
+		},
+		{
+			it:                 "reports objects that fail to copy",
+			body:               `{"keys": ["missing.txt"], "destination_bucket": "backup"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResults: []s3manager.CopyResult{
+				{Key: "missing.txt", Destination: "missing.txt", Status: "failed", Error: "error getting object: The specified key does not exist."},
+			},
+			expectedKeys: []string{"old/a.txt"},
+		},
+		{
+			it:                 "copies objects within the bucket",
+			body:               `{"prefix": "docs/", "keys": ["docs/a.txt"], "destination_bucket": "bucket", "destination_prefix": "copy/"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedResults: []s3manager.CopyResult{
+				{Key: "docs/a.txt", Destination: "copy/a.txt", Status: "copied"},
+			},
+			expectedKeys: []string{"old/a.txt"},
+		},
+		{
+			it:                   "returns 404 for a missing destination bucket",
+			body:                 `{"keys": ["docs/a.txt"], "destination_bucket": "missing"}`,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedBodyContains: "The specified bucket does not exist",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires access to the destination bucket",
+			config:               func(config *s3manager.S3InstanceConfig) { config.DeniedBuckets = []string{"backup"} },
+			body:                 `{"keys": ["docs/a.txt"], "destination_bucket": "backup"}`,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "access to bucket backup is not allowed",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires a destination bucket",
+			body:                 `{"keys": ["docs/a.txt"]}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination_bucket must not be empty",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires the prefix to be a folder",
+			body:                 `{"prefix": "docs", "destination_bucket": "backup"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "prefix must end with a slash",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires the destination prefix to be a folder",
+			body:                 `{"prefix": "docs/", "destination_bucket": "backup", "destination_prefix": "new"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination_prefix must end with a slash",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires a known policy for existing objects",
+			body:                 `{"prefix": "docs/", "destination_bucket": "backup", "if_exists": "rename"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "if_exists must be skip or overwrite",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires a different destination",
+			body:                 `{"prefix": "docs/", "destination_bucket": "bucket", "destination_prefix": "docs/"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "source and destination must differ",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "refuses to copy a folder into itself",
+			body:                 `{"prefix": "docs/", "destination_bucket": "bucket", "destination_prefix": "docs/copy/"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination must not be inside the source folder",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+		{
+			it:                   "requires keys below the prefix",
+			body:                 `{"prefix": "docs/", "keys": ["other.txt"], "destination_bucket": "backup"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "key other.txt is not an object below prefix",
+			expectedKeys:         []string{"old/a.txt"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			buckets := &fakeBuckets{objects: map[string][]string{
+				"bucket": {"docs/a.txt", "docs/c.txt", "docs/sub/b.txt", "other.txt"},
+				"backup": {"old/a.txt"},
+			}}
+			ts := httptest.NewServer(buckets)
+			t.Cleanup(ts.Close)
+
+			config := fakeS3InstanceConfig("primary", ts)
+			if tc.config != nil {
+				tc.config(&config)
+			}
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+			is.NoErr(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/copy", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			newCopyRouter(manager).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)                             // status code
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains)) // body
+			if tc.expectedResults != nil {
+				var resp s3manager.CopyResponse
+				is.NoErr(json.NewDecoder(rr.Body).Decode(&resp))
+				is.Equal(tc.expectedResults, resp.Objects) // results
+			}
+			is.Equal(tc.expectedKeys, buckets.keys("backup")) // destination keys
+		})
+	}
+}
+
+func TestHandleCopyObjectsWithManagerFolder(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	buckets := &fakeBuckets{objects: map[string][]string{
+		"bucket": {"docs/a.txt", "docs/sub/b.txt", "other.txt"},
+		"backup": {"docs/a.txt"},
+	}}
+	ts := httptest.NewServer(buckets)
+	t.Cleanup(ts.Close)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{fakeS3InstanceConfig("primary", ts)})
+	is.NoErr(err)
+	r := newCopyRouter(manager)
+
+	req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/copy", strings.NewReader(`{"prefix": "docs/", "destination_bucket": "backup", "destination_prefix": "docs/"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	is.Equal(http.StatusAccepted, rr.Code)
+
+	var job s3manager.Job
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+	is.Equal("copy", job.Type)
+	is.Equal("backup", job.DestinationBucket)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == s3manager.JobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/primary/api/buckets/bucket/jobs/"+job.ID, nil))
+		is.Equal(http.StatusOK, rr.Code)
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+	}
+
+	is.Equal(s3manager.JobSucceeded, job.Status) // job finished
+	is.Equal(2, job.Total)
+	is.Equal(1, job.Done)
+	is.Equal(1, job.Skipped)
+	is.Equal([]string{"docs/a.txt", "docs/sub/b.txt"}, buckets.keys("backup"))
+	is.Equal([]string{"docs/a.txt", "docs/sub/b.txt", "other.txt"}, buckets.keys("bucket")) // source is kept
+}
+
+func TestHandleCancelJobWithManagerCopy(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	buckets := &fakeBuckets{objects: map[string][]string{
+		"bucket": {"docs/a.txt"},
+		"backup": {},
+	}}
+	ts := httptest.NewServer(buckets)
+	t.Cleanup(ts.Close)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{fakeS3InstanceConfig("primary", ts)})
+	is.NoErr(err)
+	policy, err := s3manager.NewAccessPolicy([]s3manager.RoleBinding{
+		{Users: []string{"alice"}, Role: "uploader", Instance: "primary"},
+		{Users: []string{"bob"}, Role: "viewer", Instance: "primary"},
+		{Users: []string{"bob"}, Role: "uploader", Instance: "primary", Bucket: "bucket"},
+	})
+	is.NoErr(err)
+	manager.SetAccessPolicy(policy)
+	r := newCopyRouter(manager)
+
+	as := func(name string, req *http.Request) *http.Request {
+		return req.WithContext(s3manager.ContextWithUser(req.Context(), &s3manager.User{Name: name}))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/copy", strings.NewReader(`{"prefix": "docs/", "destination_bucket": "backup"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, as("alice", req))
+	is.Equal(http.StatusAccepted, rr.Code)
+	var job s3manager.Job
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, as("bob", httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/bucket/jobs/"+job.ID, nil)))
+	is.Equal(http.StatusForbidden, rr.Code) // canceling requires writing to the destination bucket
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, as("alice", httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/bucket/jobs/"+job.ID, nil)))
+	is.Equal(http.StatusNoContent, rr.Code)
+}
@@ -0,0 +1,71 @@
+package s3manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// Folder is a folder of a bucket, i.e. a prefix ending with a slash.
+type Folder struct {
+	Path string `json:"path"`
+}
+
+// HandleCreateFolder creates an empty folder. As S3 only knows objects, the
+// folder is a zero-byte marker object whose key is the folder's path. It
+// keeps the folder listed until objects are put in it.
+func HandleCreateFolder(s3 S3, sseInfo SSEType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucketName"]
+
+		var folder Folder
+		if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+			handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+			return
+		}
+		if folder.Path != "" && !strings.HasSuffix(folder.Path, "/") {
+			folder.Path += "/"
+		}
+		switch {
+		case folder.Path == "" || folder.Path == "/":
+			http.Error(w, "path must not be empty", http.StatusBadRequest)
+			return
+		case strings.HasPrefix(folder.Path, "/"):
+			http.Error(w, "path must not start with a slash", http.StatusBadRequest)
+			return
+		case strings.Contains(folder.Path, "//"):
+			http.Error(w, "path must not contain empty folder names", http.StatusBadRequest)
+			return
+		case len(folder.Path) > maxPathLength:
+			http.Error(w, fmt.Sprintf("path must not be longer than %d bytes", maxPathLength), http.StatusBadRequest)
+			return
+		}
+
+		exists, err := destinationExists(r.Context(), s3, bucketName, folder.Path)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		if exists {
+			http.Error(w, fmt.Sprintf("folder %s already exists", folder.Path), http.StatusConflict)
+			return
+		}
+
+		sse, err := serverSideEncryption(sseInfo)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		_, err = s3.PutObject(r.Context(), bucketName, folder.Path, strings.NewReader(""), 0, minio.PutObjectOptions{ServerSideEncryption: sse})
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error creating folder: %w", err))
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, folder)
+	}
+}
@@ -0,0 +1,116 @@
+package s3manager_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/cloudlena/s3manager/internal/app/s3manager/mocks"
+	"github.com/matryer/is"
+	"github.com/minio/minio-go/v7"
+)
+
+func TestHandleCreateFolder(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		body                 string
+		existing             []string
+		putObjectErr         error
+		expectedStatusCode   int
+		expectedBodyContains string
+		expectedKey          string
+	}{
+		{
+			it:                   "creates a folder marker",
+			body:                 `{"path": "docs/new"}`,
+			expectedStatusCode:   http.StatusCreated,
+			expectedBodyContains: `"path":"docs/new/"`,
+			expectedKey:          "docs/new/",
+		},
+		{
+			it:                 "keeps a trailing slash",
+			body:               `{"path": "new/"}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedKey:        "new/",
+		},
+		{
+			it:                   "requires a path",
+			body:                 `{"path": ""}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path must not be empty",
+		},
+		{
+			it:                   "rejects a leading slash",
+			body:                 `{"path": "/docs"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path must not start with a slash",
+		},
+		{
+			it:                   "rejects empty folder names",
+			body:                 `{"path": "docs//new"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path must not contain empty folder names",
+		},
+		{
+			it:                   "rejects paths that are too long",
+			body:                 `{"path": "` + strings.Repeat("a", 1024) + `"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path must not be longer than 1024 bytes",
+		},
+		{
+			it:                   "returns conflict if the folder exists",
+			body:                 `{"path": "docs"}`,
+			existing:             []string{"docs/a.txt"},
+			expectedStatusCode:   http.StatusConflict,
+			expectedBodyContains: "folder docs/ already exists",
+		},
+		{
+			it:                   "returns error if there is an S3 error",
+			body:                 `{"path": "docs"}`,
+			putObjectErr:         errS3,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "mocked s3 error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			var putKey string
+			var putSize int64 = -1
+			s3 := &mocks.S3Mock{
+				ListObjectsFunc: func(context.Context, string, minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+					objCh := make(chan minio.ObjectInfo, len(tc.existing))
+					for _, key := range tc.existing {
+						objCh <- minio.ObjectInfo{Key: key}
+					}
+					close(objCh)
+					return objCh
+				},
+				PutObjectFunc: func(_ context.Context, _ string, objectName string, _ io.Reader, size int64, _ minio.PutObjectOptions) (minio.UploadInfo, error) {
+					putKey, putSize = objectName, size
+					return minio.UploadInfo{}, tc.putObjectErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/buckets/bucket/folders", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			s3manager.HandleCreateFolder(s3, s3manager.SSEType{}).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)                             // status code
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains)) // body
+			if tc.expectedKey != "" {
+				is.Equal(tc.expectedKey, putKey) // marker key
+				is.Equal(int64(0), putSize)      // marker is empty
+			}
+		})
+	}
+}
@@ -1,75 +1,159 @@
 package s3manager
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"mime"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/encrypt"
 )
 
-// HandleCreateObject uploads a new object.
+// uploadPartSize is the size of the parts uploads of unknown size are split
+// into. Each upload buffers one part in memory, and objects can be at most
+// 10000 parts large.
+const uploadPartSize = 16 << 20 // 16 MiB
+
+// maxPathLength is the maximum length of an object key.
+const maxPathLength = 1024
+
+// UploadResult is the outcome of uploading a single file.
+type UploadResult struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// UploadResponse lists the outcome of every file of an upload.
+type UploadResponse struct {
+	Objects []UploadResult `json:"objects"`
+}
+
+// HandleCreateObject uploads new objects. The request is a multipart form
+// with any number of "file" parts, each stored at the "path" given by the
+// field preceding it. Files are streamed to S3 part by part, so they are never
+// buffered on disk, which is why their path must be known before they are
+// read.
 func HandleCreateObject(s3 S3, sseInfo SSEType) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		bucketName := mux.Vars(r)["bucketName"]
 
-		err := r.ParseMultipartForm(32 << 20) // 32 Mb
+		reader, err := r.MultipartReader()
 		if err != nil {
 			handleHTTPError(w, fmt.Errorf("error parsing multipart form: %w", err))
 			return
 		}
-		file, fileHeader, err := r.FormFile("file")
-		path := r.FormValue("path")
-		if err != nil {
-			handleHTTPError(w, fmt.Errorf("error getting file from form: %w", err))
-			return
-		}
-		defer func() {
-			if cErr := file.Close(); cErr != nil {
-				log.Printf("error closing file: %v", cErr)
-			}
-		}()
 
-		contentType := fileHeader.Header.Get("Content-Type")
-		if contentType == "" || contentType == "application/octet-stream" {
-			contentType = mime.TypeByExtension(filepath.Ext(fileHeader.Filename))
-		}
-		if contentType == "" {
-			contentType = "application/octet-stream"
-		}
-		opts := minio.PutObjectOptions{ContentType: contentType}
-
-		if sseInfo.Type == "KMS" {
-			opts.ServerSideEncryption, err = encrypt.NewSSEKMS(sseInfo.Key, nil)
+		var (
+			response UploadResponse
+			path     string
+			firstErr error
+		)
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
 			if err != nil {
-				handleHTTPError(w, fmt.Errorf("error setting SSE-KMS key: %w", err))
+				handleHTTPError(w, fmt.Errorf("error reading multipart form: %w", err))
 				return
 			}
+
+			switch part.FormName() {
+			case "path":
+				path, err = readPath(part)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			case "file":
+				if path == "" {
+					// Files uploaded before are kept, but this one can't be streamed.
+					http.Error(w, fmt.Sprintf("file %q is not preceded by a path, path fields must precede their file", part.FileName()), http.StatusBadRequest)
+					return
+				}
+				result := UploadResult{Path: path}
+				result.Size, err = putPart(r.Context(), s3, bucketName, path, part, sseInfo)
+				if err != nil {
+					result.Error = err.Error()
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+				response.Objects = append(response.Objects, result)
+				path = ""
+			}
+			_ = part.Close()
 		}
 
-		if sseInfo.Type == "SSE" {
-			opts.ServerSideEncryption = encrypt.NewSSE()
+		if len(response.Objects) == 0 {
+			http.Error(w, "no file provided", http.StatusBadRequest)
+			return
+		}
+		if path != "" {
+			// Files uploaded before can't be moved to the intended path.
+			http.Error(w, fmt.Sprintf("path %q is not followed by a file, path fields must precede their file", path), http.StatusBadRequest)
+			return
 		}
 
-		if sseInfo.Type == "SSE-C" {
-			opts.ServerSideEncryption, err = encrypt.NewSSEC([]byte(sseInfo.Key))
-			if err != nil {
-				handleHTTPError(w, fmt.Errorf("error setting SSE-C key: %w", err))
-				return
+		code := http.StatusCreated
+		if firstErr != nil {
+			code = http.StatusInternalServerError
+			if strings.Contains(firstErr.Error(), ErrBucketDoesNotExist) {
+				code = http.StatusNotFound
 			}
+			log.Println(firstErr)
 		}
-
-		size := fileHeader.Size
-		_, err = s3.PutObject(r.Context(), bucketName, path, file, size, opts)
-		if err != nil {
-			handleHTTPError(w, fmt.Errorf("error putting object: %w", err))
-			return
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("error encoding JSON: %v", err)
 		}
+	}
+}
 
-		w.WriteHeader(http.StatusCreated)
+// readPath reads the value of a path field.
+func readPath(part *multipart.Part) (string, error) {
+	path, err := io.ReadAll(io.LimitReader(part, maxPathLength+1))
+	if err != nil {
+		return "", fmt.Errorf("error reading path: %w", err)
+	}
+	if len(path) > maxPathLength {
+		return "", fmt.Errorf("path must not be longer than %d bytes", maxPathLength)
+	}
+	return string(path), nil
+}
+
+// putPart streams a file part to path and returns the number of bytes stored.
+func putPart(ctx context.Context, s3 S3, bucketName, path string, part *multipart.Part, sseInfo SSEType) (int64, error) {
+	sse, err := serverSideEncryption(sseInfo)
+	if err != nil {
+		return 0, err
+	}
+	opts := minio.PutObjectOptions{ContentType: contentType(part.Header.Get("Content-Type"), path), PartSize: uploadPartSize, ServerSideEncryption: sse}
+	info, err := s3.PutObject(ctx, bucketName, path, part, -1, opts)
+	if err != nil {
+		return 0, fmt.Errorf("error putting object %s: %w", path, err)
+	}
+	return info.Size, nil
+}
+
+// contentType returns the content type an object at path is stored with. If
+// the client didn't send a specific one, it is guessed from the extension.
+func contentType(sent, path string) string {
+	if sent == "" || sent == "application/octet-stream" {
+		sent = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if sent == "" {
+		sent = "application/octet-stream"
 	}
+	return sent
 }
@@ -3,6 +3,7 @@ package s3manager_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -30,32 +31,34 @@ func TestHandleCreateObject(t *testing.T) {
 		expectedBodyContains string
 	}{
 		{
-			it: "uploads an object successfully",
+			it: "rejects a file before its path",
 			putObjectFunc: func(context.Context, string, string, io.Reader, int64, minio.PutObjectOptions) (minio.UploadInfo, error) {
 				return minio.UploadInfo{}, nil
 			},
-			fileName:           "test.txt",
-			filePath:           "test.txt",
-			expectedStatusCode: http.StatusCreated,
+			fileName:             "test.txt",
+			filePath:             "test.txt",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path fields must precede their file",
 		},
 		{
-			it: "uploads with explicit path",
+			it: "rejects a file before an explicit path",
 			putObjectFunc: func(context.Context, string, string, io.Reader, int64, minio.PutObjectOptions) (minio.UploadInfo, error) {
 				return minio.UploadInfo{}, nil
 			},
-			fileName:           "test.txt",
-			filePath:           "folder/test.txt",
-			expectedStatusCode: http.StatusCreated,
+			fileName:             "test.txt",
+			filePath:             "folder/test.txt",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: `file "test.txt" is not preceded by a path`,
 		},
 		{
-			it: "returns error if there is an S3 error",
+			it: "rejects a file before its path without putting it",
 			putObjectFunc: func(context.Context, string, string, io.Reader, int64, minio.PutObjectOptions) (minio.UploadInfo, error) {
 				return minio.UploadInfo{}, errS3
 			},
 			fileName:             "test.txt",
 			filePath:             "test.txt",
-			expectedStatusCode:   http.StatusInternalServerError,
-			expectedBodyContains: "mocked s3 error",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path fields must precede their file",
 		},
 	}
 
@@ -100,6 +103,121 @@ func TestHandleCreateObject(t *testing.T) {
 
 			is.Equal(tc.expectedStatusCode, resp.StatusCode)
 			is.True(strings.Contains(string(respBody), tc.expectedBodyContains))
+			is.Equal(0, len(s3.PutObjectCalls())) // rejected before streaming
+		})
+	}
+}
+
+func TestHandleCreateObjectMultipleFiles(t *testing.T) {
+	t.Parallel()
+
+	type field struct {
+		name, value string
+		file        bool
+	}
+
+	cases := []struct {
+		it                 string
+		fields             []field
+		expectedStatusCode int
+		expectedResponse   s3manager.UploadResponse
+		expectedBody       string
+	}{
+		{
+			it: "streams every file to its path",
+			fields: []field{
+				{name: "path", value: "docs/a.txt"},
+				{name: "file", value: "first", file: true},
+				{name: "path", value: "docs/b.txt"},
+				{name: "file", value: "second", file: true},
+				{name: "path", value: "docs/fail.txt"},
+				{name: "file", value: "third", file: true},
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse: s3manager.UploadResponse{Objects: []s3manager.UploadResult{
+				{Path: "docs/a.txt", Size: 5},
+				{Path: "docs/b.txt", Size: 6},
+				{Path: "docs/fail.txt", Error: "error putting object docs/fail.txt: mocked s3 error"},
+			}},
+		},
+		{
+			it: "rejects a path after the last file",
+			fields: []field{
+				{name: "path", value: "docs/a.txt"},
+				{name: "file", value: "content", file: true},
+				{name: "path", value: "docs/b.txt"},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "path fields must precede their file",
+		},
+		{
+			it: "rejects a file without a path",
+			fields: []field{
+				{name: "path", value: "docs/a.txt"},
+				{name: "file", value: "first", file: true},
+				{name: "file", value: "second", file: true},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `file "upload.txt" is not preceded by a path`,
+		},
+		{
+			it: "rejects forms without files",
+			fields: []field{
+				{name: "path", value: "docs/a.txt"},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "no file provided",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3 := &mocks.S3Mock{
+				PutObjectFunc: func(_ context.Context, _ string, objectName string, reader io.Reader, _ int64, _ minio.PutObjectOptions) (minio.UploadInfo, error) {
+					if objectName == "docs/fail.txt" {
+						return minio.UploadInfo{}, errS3
+					}
+					content, err := io.ReadAll(reader)
+					if err != nil {
+						return minio.UploadInfo{}, err
+					}
+					return minio.UploadInfo{Key: objectName, Size: int64(len(content))}, nil
+				},
+			}
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			for _, f := range tc.fields {
+				if f.file {
+					part, err := writer.CreateFormFile(f.name, "upload.txt")
+					is.NoErr(err)
+					_, err = io.WriteString(part, f.value)
+					is.NoErr(err)
+				} else {
+					is.NoErr(writer.WriteField(f.name, f.value))
+				}
+			}
+			is.NoErr(writer.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/buckets/my-bucket/objects", &body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rr := httptest.NewRecorder()
+
+			r := mux.NewRouter()
+			r.Handle("/api/buckets/{bucketName}/objects", s3manager.HandleCreateObject(s3, s3manager.SSEType{})).Methods(http.MethodPost)
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			if tc.expectedBody != "" {
+				is.True(strings.Contains(rr.Body.String(), tc.expectedBody))
+				return
+			}
+			var response s3manager.UploadResponse
+			is.NoErr(json.NewDecoder(rr.Body).Decode(&response))
+			is.Equal(tc.expectedResponse, response)
 		})
 	}
 }
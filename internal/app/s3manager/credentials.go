@@ -0,0 +1,123 @@
+package s3manager
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Credentials providers an S3 instance can get its credentials from.
+const (
+	CredentialsStatic      = "static"
+	CredentialsIAM         = "iam"
+	CredentialsEnv         = "env"
+	CredentialsFile        = "file"
+	CredentialsAssumeRole  = "assume_role"
+	CredentialsWebIdentity = "web_identity"
+	CredentialsChain       = "chain"
+)
+
+var credentialsProviders = []string{
+	CredentialsStatic,
+	CredentialsIAM,
+	CredentialsEnv,
+	CredentialsFile,
+	CredentialsAssumeRole,
+	CredentialsWebIdentity,
+	CredentialsChain,
+}
+
+// kubernetesTokenFile is where Kubernetes mounts the service account token.
+const kubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+
+// credentialsProvider returns the credentials provider of config. Without an
+// explicit provider, use_iam selects IAM and static keys are used otherwise.
+func (config S3InstanceConfig) credentialsProvider() string {
+	switch {
+	case config.CredentialsProvider != "":
+		return config.CredentialsProvider
+	case config.UseIam:
+		return CredentialsIAM
+	default:
+		return CredentialsStatic
+	}
+}
+
+// newCredentials creates the credentials of an S3 instance from config.
+func newCredentials(config S3InstanceConfig) (*credentials.Credentials, error) {
+	stsEndpoint := config.STSEndpoint
+	if stsEndpoint == "" {
+		stsEndpoint = credentials.DefaultSTSRoleEndpoint
+	}
+
+	switch provider := config.credentialsProvider(); provider {
+	case CredentialsStatic:
+		var signatureType credentials.SignatureType
+
+		switch config.SignatureType {
+		case "V2":
+			signatureType = credentials.SignatureV2
+		case "V4":
+			signatureType = credentials.SignatureV4
+		case "V4Streaming":
+			signatureType = credentials.SignatureV4Streaming
+		case "Anonymous":
+			signatureType = credentials.SignatureAnonymous
+		default:
+			return nil, fmt.Errorf("invalid SIGNATURE_TYPE: %s", config.SignatureType)
+		}
+
+		return credentials.NewStatic(config.AccessKeyID, config.SecretAccessKey, "", signatureType), nil
+	case CredentialsIAM:
+		return credentials.NewIAM(config.IamEndpoint), nil
+	case CredentialsEnv:
+		return credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+		}), nil
+	case CredentialsFile:
+		return credentials.NewFileAWSCredentials(config.CredentialsFile, config.CredentialsProfile), nil
+	case CredentialsAssumeRole:
+		creds, err := credentials.NewSTSAssumeRole(stsEndpoint, credentials.STSAssumeRoleOptions{
+			AccessKey:       config.AccessKeyID,
+			SecretKey:       config.SecretAccessKey,
+			Location:        config.Region,
+			RoleARN:         config.RoleARN,
+			RoleSessionName: config.RoleSessionName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error creating assume role credentials for instance %s: %w", config.Name, err)
+		}
+		return creds, nil
+	case CredentialsWebIdentity:
+		tokenFile := config.WebIdentityTokenFile
+		if tokenFile == "" {
+			tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+		}
+		if tokenFile == "" {
+			tokenFile = kubernetesTokenFile
+		}
+
+		// The token is read on every refresh, so rotated tokens are picked up.
+		getToken := func() (*credentials.WebIdentityToken, error) {
+			token, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading web identity token: %w", err)
+			}
+			return &credentials.WebIdentityToken{Token: string(token)}, nil
+		}
+		return credentials.NewSTSWebIdentity(stsEndpoint, getToken, func(i *credentials.STSWebIdentity) {
+			i.RoleARN = config.RoleARN
+		})
+	case CredentialsChain:
+		return credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{Filename: config.CredentialsFile, Profile: config.CredentialsProfile},
+			&credentials.IAM{Endpoint: config.IamEndpoint},
+		}), nil
+	default:
+		return nil, fmt.Errorf("invalid credentials provider: %s", provider)
+	}
+}
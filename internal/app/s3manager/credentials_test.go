@@ -0,0 +1,120 @@
+package s3manager_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestMultiS3ManagerCredentialsProviders(t *testing.T) {
+	t.Parallel()
+
+	// The fake STS answers every request with temporary credentials whose
+	// access key names the action that was called.
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := r.FormValue("Action")
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<` + action + `Response xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><` + action + `Result><Credentials>` +
+			`<AccessKeyId>` + action + `</AccessKeyId><SecretAccessKey>secret</SecretAccessKey>` +
+			`<SessionToken>token</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration>` +
+			`</Credentials></` + action + `Result></` + action + `Response>`))
+	}))
+	t.Cleanup(sts.Close)
+
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	err := os.WriteFile(credentialsFile, []byte("[default]\naws_access_key_id = default-key\naws_secret_access_key = secret\n\n[team]\naws_access_key_id = team-key\naws_secret_access_key = secret\n"), 0o600)
+	is.New(t).NoErr(err)
+	tokenFile := filepath.Join(dir, "token")
+	err = os.WriteFile(tokenFile, []byte("service-account-token"), 0o600)
+	is.New(t).NoErr(err)
+
+	cases := []struct {
+		it                  string
+		config              func(s3manager.S3InstanceConfig) s3manager.S3InstanceConfig
+		expectedAccessKeyID string
+	}{
+		{
+			it: "uses static keys by default",
+			config: func(config s3manager.S3InstanceConfig) s3manager.S3InstanceConfig {
+				return config
+			},
+			expectedAccessKeyID: "key",
+		},
+		{
+			it: "reads keys from a shared credentials file",
+			config: func(config s3manager.S3InstanceConfig) s3manager.S3InstanceConfig {
+				config.AccessKeyID, config.SecretAccessKey = "", ""
+				config.CredentialsProvider = s3manager.CredentialsFile
+				config.CredentialsFile, config.CredentialsProfile = credentialsFile, "team"
+				return config
+			},
+			expectedAccessKeyID: "team-key",
+		},
+		{
+			it: "assumes a role",
+			config: func(config s3manager.S3InstanceConfig) s3manager.S3InstanceConfig {
+				config.CredentialsProvider = s3manager.CredentialsAssumeRole
+				config.STSEndpoint = sts.URL
+				config.RoleARN = "arn:aws:iam::123456789012:role/s3manager"
+				return config
+			},
+			expectedAccessKeyID: "AssumeRole",
+		},
+		{
+			it: "assumes a role with a web identity token",
+			config: func(config s3manager.S3InstanceConfig) s3manager.S3InstanceConfig {
+				config.AccessKeyID, config.SecretAccessKey = "", ""
+				config.CredentialsProvider = s3manager.CredentialsWebIdentity
+				config.STSEndpoint = sts.URL
+				config.RoleARN = "arn:aws:iam::123456789012:role/s3manager"
+				config.WebIdentityTokenFile = tokenFile
+				return config
+			},
+			expectedAccessKeyID: "AssumeRoleWithWebIdentity",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			var (
+				mu            sync.Mutex
+				authorization string
+			)
+			s3 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				authorization = r.Header.Get("Authorization")
+				mu.Unlock()
+				w.Header().Set("Content-Type", "application/xml")
+				_, _ = w.Write([]byte(`<ListAllMyBucketsResult><Owner><ID>owner</ID></Owner><Buckets></Buckets></ListAllMyBucketsResult>`))
+			}))
+			t.Cleanup(s3.Close)
+
+			config := tc.config(fakeS3InstanceConfig("primary", s3))
+			is.NoErr(s3manager.ValidateInstanceConfigs([]s3manager.S3InstanceConfig{config}))
+
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+			is.NoErr(err)
+			instance, err := manager.GetInstance("primary")
+			is.NoErr(err)
+
+			_, err = instance.Client.ListBuckets(context.Background())
+			is.NoErr(err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			is.True(strings.Contains(authorization, "Credential="+tc.expectedAccessKeyID+"/")) // signed with the provider's credentials
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package s3manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// DeletePrefixRequest represents the request body for deleting a folder.
+type DeletePrefixRequest struct {
+	Prefix       string `json:"prefix"`
+	Confirmation string `json:"confirmation"`
+	Versions     bool   `json:"versions"`
+	DryRun       bool   `json:"dry_run"`
+}
+
+// DeletePrefixCount is the number of objects a delete would remove.
+type DeletePrefixCount struct {
+	Prefix   string `json:"prefix"`
+	Versions bool   `json:"versions"`
+	Objects  int    `json:"objects"`
+}
+
+// HandleDeletePrefixWithManager deletes a folder with every object below it
+// by a job. The prefix must end with a slash, and the confirmation must repeat
+// it. With versions, every version and delete marker below the prefix is
+// removed, too. A dry run deletes nothing and returns the number of objects
+// that would be removed instead.
+func HandleDeletePrefixWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withJobs(manager, RoleEditor, func(current *S3Instance, jobs *jobRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !current.Features.AllowDelete {
+				deleteDisabled(w, r)
+				return
+			}
+			bucketName := mux.Vars(r)["bucketName"]
+
+			var req DeletePrefixRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+				return
+			}
+			switch {
+			case req.Prefix == "":
+				http.Error(w, "prefix must not be empty", http.StatusBadRequest)
+				return
+			case !strings.HasSuffix(req.Prefix, "/"):
+				http.Error(w, "prefix must end with a slash", http.StatusBadRequest)
+				return
+			}
+
+			opts := minio.ListObjectsOptions{Prefix: req.Prefix, Recursive: true, WithVersions: req.Versions}
+			if req.DryRun {
+				count := DeletePrefixCount{Prefix: req.Prefix, Versions: req.Versions}
+				for object := range current.Client.ListObjects(r.Context(), bucketName, opts) {
+					if object.Err != nil {
+						handleHTTPError(w, fmt.Errorf("error listing objects: %w", object.Err))
+						return
+					}
+					count.Objects++
+				}
+				writeJSON(w, http.StatusOK, count)
+				return
+			}
+
+			if req.Confirmation != req.Prefix {
+				http.Error(w, "confirmation must match the prefix", http.StatusBadRequest)
+				return
+			}
+
+			job := Job{
+				Type:     "delete",
+				Instance: current.Name,
+				Bucket:   bucketName,
+				Source:   req.Prefix,
+			}
+			if user, ok := UserFromContext(r.Context()); ok {
+				job.CreatedBy = user.Name
+			}
+			s3 := current.Client
+			job, err := jobs.start(job, func(ctx context.Context, progress *jobProgress) error {
+				return deletePrefix(ctx, s3, bucketName, opts, progress)
+			})
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusAccepted, job)
+		}
+	})
+}
+
+// deletePrefix removes the objects listed with opts from bucketName. The
+// listing is streamed into the removal, so folders of any size are deleted
+// without holding their keys in memory.
+func deletePrefix(ctx context.Context, s3 S3, bucketName string, opts minio.ListObjectsOptions, progress *jobProgress) error {
+	objectsCh := make(chan minio.ObjectInfo)
+	listErr := make(chan error, 1)
+	go func() {
+		defer close(objectsCh)
+		for object := range s3.ListObjects(ctx, bucketName, opts) {
+			if object.Err != nil {
+				listErr <- fmt.Errorf("error listing objects: %w", object.Err)
+				return
+			}
+			progress.add()
+			select {
+			case objectsCh <- object:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for result := range s3.RemoveObjectsWithResult(ctx, bucketName, objectsCh, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			progress.fail(result.ObjectName, result.Err)
+			continue
+		}
+		progress.done()
+	}
+
+	select {
+	case err := <-listErr:
+		return err
+	default:
+		return nil
+	}
+}
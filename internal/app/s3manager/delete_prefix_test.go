@@ -0,0 +1,140 @@
+package s3manager_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+)
+
+// newDeletePrefixRouter serves the delete prefix and job API of manager.
+func newDeletePrefixRouter(manager *s3manager.MultiS3Manager) *mux.Router {
+	r := newMoveRouter(manager)
+	r.Handle("/{instance}/api/buckets/{bucketName}/delete-prefix", s3manager.HandleDeletePrefixWithManager(manager)).Methods(http.MethodPost)
+	return r
+}
+
+func TestHandleDeletePrefixWithManager(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"docs/", "docs/a.txt", "docs/sub/b.txt", "other.txt"}
+	cases := []struct {
+		it                   string
+		config               func(*s3manager.S3InstanceConfig)
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains string
+	}{
+		{
+			it:                   "counts the objects of a dry run",
+			body:                 `{"prefix": "docs/", "dry_run": true}`,
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"objects":3`,
+		},
+		{
+			it:                   "requires a prefix",
+			body:                 `{"prefix": "", "confirmation": ""}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "prefix must not be empty",
+		},
+		{
+			it:                   "requires the prefix to be a folder",
+			body:                 `{"prefix": "docs", "confirmation": "docs"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "prefix must end with a slash",
+		},
+		{
+			it:                   "requires a matching confirmation",
+			body:                 `{"prefix": "docs/", "confirmation": "doc/"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "confirmation must match the prefix",
+		},
+		{
+			it:                   "requires deleting to be allowed",
+			config:               func(config *s3manager.S3InstanceConfig) { config.AllowDelete = false },
+			body:                 `{"prefix": "docs/", "confirmation": "docs/"}`,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "deleting is disabled",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			buckets := &fakeBuckets{objects: map[string][]string{"bucket": slices.Clone(keys)}}
+			ts := httptest.NewServer(buckets)
+			t.Cleanup(ts.Close)
+
+			config := fakeS3InstanceConfig("primary", ts)
+			config.AllowDelete = true
+			if tc.config != nil {
+				tc.config(&config)
+			}
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+			is.NoErr(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/delete-prefix", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			newDeletePrefixRouter(manager).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)                             // status code
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains)) // body
+			is.Equal(keys, buckets.keys("bucket"))                               // nothing is deleted
+		})
+	}
+}
+
+func TestHandleDeletePrefixWithManagerJob(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	buckets := &fakeBuckets{
+		objects: map[string][]string{"bucket": {"docs/", "docs/a.txt", "docs/locked.txt", "docs/sub/b.txt", "other.txt"}},
+		locked:  []string{"bucket/docs/locked.txt"},
+	}
+	ts := httptest.NewServer(buckets)
+	t.Cleanup(ts.Close)
+
+	config := fakeS3InstanceConfig("primary", ts)
+	config.AllowDelete = true
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+	is.NoErr(err)
+	r := newDeletePrefixRouter(manager)
+
+	req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/delete-prefix", strings.NewReader(`{"prefix": "docs/", "confirmation": "docs/"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	is.Equal(http.StatusAccepted, rr.Code)
+
+	var job s3manager.Job
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+	is.Equal("delete", job.Type)
+	is.Equal("docs/", job.Source)
+	is.Equal(s3manager.JobRunning, job.Status)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == s3manager.JobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/primary/api/buckets/bucket/jobs/"+job.ID, nil))
+		is.Equal(http.StatusOK, rr.Code)
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+	}
+
+	is.Equal(s3manager.JobFailed, job.Status) // an object is locked
+	is.Equal(4, job.Total)
+	is.Equal(3, job.Done)
+	is.Equal(1, job.Failed)
+	is.Equal("docs/locked.txt", job.Errors[0].Key)
+	is.True(strings.Contains(job.Errors[0].Error, "Access Denied"))
+	is.Equal([]string{"docs/locked.txt", "other.txt"}, buckets.keys("bucket"))
+}
@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"strings"
+
+	"github.com/minio/minio-go/v7"
 )
 
 // Error codes that may be returned from an S3 client.
@@ -25,11 +27,14 @@ func handleHTTPError(w http.ResponseWriter, err error) {
 		code = http.StatusUnprocessableEntity
 	}
 
+	var response minio.ErrorResponse
 	switch {
 	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
 		code = http.StatusUnprocessableEntity
 	case strings.Contains(err.Error(), ErrBucketDoesNotExist) || strings.Contains(err.Error(), ErrKeyDoesNotExist):
 		code = http.StatusNotFound
+	case errors.As(err, &response) && response.Code == "NoSuchUpload":
+		code = http.StatusNotFound
 	}
 
 	http.Error(w, err.Error(), code)
@@ -3,6 +3,7 @@ package s3manager
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -11,26 +12,54 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// HandleGenerateURL generates a presigned URL.
-func HandleGenerateURL(s3 S3) http.HandlerFunc {
+// maxPresignedExpiry is the longest time presigned URLs can be valid for.
+const maxPresignedExpiry = 7 * 24 * time.Hour
+
+// HandleGenerateURL generates a presigned URL valid for at most maxExpiry.
+// The URL can be pinned to a version with versionId if showVersions is set
+// and override the response-content-disposition and response-content-type
+// headers of the download. filename is a shorthand for an attachment
+// disposition.
+func HandleGenerateURL(s3 S3, sseInfo SSEType, maxExpiry time.Duration, showVersions bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		bucketName := mux.Vars(r)["bucketName"]
 		objectName := mux.Vars(r)["objectName"]
-		expiry := r.URL.Query().Get("expiry")
+		query := r.URL.Query()
 
-		parsedExpiry, err := strconv.ParseInt(expiry, 10, 0)
-		if err != nil {
-			handleHTTPError(w, fmt.Errorf("error converting expiry: %w", err))
+		if !presigningAllowed(w, sseInfo) {
 			return
 		}
 
-		if parsedExpiry > 7*24*60*60 || parsedExpiry < 1 {
-			handleHTTPError(w, fmt.Errorf("invalid expiry value: %v", parsedExpiry))
+		expiryDuration, err := parseExpiry(query.Get("expiry"), maxExpiry)
+		if err != nil {
+			handleHTTPError(w, err)
 			return
 		}
 
-		expiryDuration := time.Duration(parsedExpiry) * time.Second
 		reqParams := make(url.Values)
+		for _, param := range []string{"versionId", "response-content-disposition", "response-content-type"} {
+			if value := query.Get(param); value != "" {
+				reqParams.Set(param, value)
+			}
+		}
+		// Ignore versionId unless the versions feature is enabled, like
+		// downloads do.
+		if !showVersions {
+			reqParams.Del("versionId")
+		}
+		if filename := query.Get("filename"); filename != "" {
+			if reqParams.Has("response-content-disposition") {
+				http.Error(w, "filename and response-content-disposition cannot be combined", http.StatusBadRequest)
+				return
+			}
+			disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+			if disposition == "" {
+				http.Error(w, "filename is invalid", http.StatusBadRequest)
+				return
+			}
+			reqParams.Set("response-content-disposition", disposition)
+		}
+
 		url, err := s3.PresignedGetObject(r.Context(), bucketName, objectName, expiryDuration, reqParams)
 		if err != nil {
 			handleHTTPError(w, fmt.Errorf("error generating url: %w", err))
@@ -46,3 +75,30 @@ func HandleGenerateURL(s3 S3) http.HandlerFunc {
 		}
 	}
 }
+
+// presignExpiryLimit returns the longest time presigned URLs can be valid for
+// on an instance configured with maxExpiry. Zero allows the longest expiry S3
+// supports.
+func presignExpiryLimit(maxExpiry time.Duration) time.Duration {
+	if maxExpiry <= 0 || maxExpiry > maxPresignedExpiry {
+		return maxPresignedExpiry
+	}
+	return maxExpiry
+}
+
+// parseExpiry parses the number of seconds a presigned URL is valid for.
+func parseExpiry(expiry string, maxExpiry time.Duration) (time.Duration, error) {
+	parsedExpiry, err := strconv.ParseInt(expiry, 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("error converting expiry: %w", err)
+	}
+	return validateExpiry(parsedExpiry, maxExpiry)
+}
+
+// validateExpiry checks that a presigned URL can be valid for expiry seconds.
+func validateExpiry(expiry int64, maxExpiry time.Duration) (time.Duration, error) {
+	if expiry > int64(presignExpiryLimit(maxExpiry)/time.Second) || expiry < 1 {
+		return 0, fmt.Errorf("invalid expiry value: %v", expiry)
+	}
+	return time.Duration(expiry) * time.Second, nil
+}
@@ -26,8 +26,13 @@ func TestHandleGenerateURL(t *testing.T) {
 		it                     string
 		presignedGetObjectFunc func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error)
 		expiry                 string
+		query                  string
+		maxExpiry              time.Duration
+		showVersions           bool
+		sseInfo                s3manager.SSEType
 		expectedStatusCode     int
 		expectedBodyContains   string
+		expectedParams         url.Values
 	}{
 		{
 			it: "generates a presigned URL",
@@ -37,6 +42,76 @@ func TestHandleGenerateURL(t *testing.T) {
 			expiry:               "3600",
 			expectedStatusCode:   http.StatusOK,
 			expectedBodyContains: "s3.example.com",
+			expectedParams:       url.Values{},
+		},
+		{
+			it: "signs the version and response header overrides into the URL",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return presignedURL, nil
+			},
+			expiry:               "3600",
+			query:                "&versionId=v1&response-content-type=text%2Fcsv&response-content-disposition=inline&ignored=1",
+			showVersions:         true,
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "s3.example.com",
+			expectedParams: url.Values{
+				"versionId":                    {"v1"},
+				"response-content-type":        {"text/csv"},
+				"response-content-disposition": {"inline"},
+			},
+		},
+		{
+			it: "ignores the version if versions are not shown",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return presignedURL, nil
+			},
+			expiry:               "3600",
+			query:                "&versionId=v1",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "s3.example.com",
+			expectedParams:       url.Values{},
+		},
+		{
+			it: "downloads as the given filename",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return presignedURL, nil
+			},
+			expiry:               "3600",
+			query:                "&filename=" + url.QueryEscape("Report 2026 (final).pdf"),
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "s3.example.com",
+			expectedParams:       url.Values{"response-content-disposition": {`attachment; filename="Report 2026 (final).pdf"`}},
+		},
+		{
+			it: "encodes filenames that are not ASCII",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return presignedURL, nil
+			},
+			expiry:               "3600",
+			query:                "&filename=" + url.QueryEscape("Bericht März.pdf"),
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "s3.example.com",
+			expectedParams:       url.Values{"response-content-disposition": {"attachment; filename*=utf-8''Bericht%20M%C3%A4rz.pdf"}},
+		},
+		{
+			it: "rejects a filename combined with a content disposition",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return nil, nil
+			},
+			expiry:               "3600",
+			query:                "&filename=report.pdf&response-content-disposition=inline",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "filename and response-content-disposition cannot be combined",
+		},
+		{
+			it: "returns error when expiry exceeds the instance's maximum",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return nil, nil
+			},
+			expiry:               "3601",
+			maxExpiry:            time.Hour,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "invalid expiry value",
 		},
 		{
 			it: "returns error for invalid expiry",
@@ -65,6 +140,16 @@ func TestHandleGenerateURL(t *testing.T) {
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedBodyContains: "invalid expiry value",
 		},
+		{
+			it: "refuses to presign objects encrypted with SSE-C",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return nil, errS3
+			},
+			expiry:               "3600",
+			sseInfo:              s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "not supported for objects encrypted with SSE-C",
+		},
 		{
 			it: "returns error if there is an S3 error",
 			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
@@ -81,17 +166,21 @@ func TestHandleGenerateURL(t *testing.T) {
 			t.Parallel()
 			is := is.New(t)
 
+			var reqParams url.Values
 			s3 := &mocks.S3Mock{
-				PresignedGetObjectFunc: tc.presignedGetObjectFunc,
+				PresignedGetObjectFunc: func(ctx context.Context, bucketName, objectName string, expiry time.Duration, params url.Values) (*url.URL, error) {
+					reqParams = params
+					return tc.presignedGetObjectFunc(ctx, bucketName, objectName, expiry, params)
+				},
 			}
 
 			r := mux.NewRouter()
-			r.Handle("/api/buckets/{bucketName}/objects/{objectName}/url", s3manager.HandleGenerateURL(s3)).Methods(http.MethodGet)
+			r.Handle("/api/buckets/{bucketName}/objects/{objectName}/url", s3manager.HandleGenerateURL(s3, tc.sseInfo, tc.maxExpiry, tc.showVersions)).Methods(http.MethodGet)
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
-			resp, err := http.Get(fmt.Sprintf("%s/api/buckets/my-bucket/objects/my-object/url?expiry=%s", ts.URL, tc.expiry))
+			resp, err := http.Get(fmt.Sprintf("%s/api/buckets/my-bucket/objects/my-object/url?expiry=%s%s", ts.URL, tc.expiry, tc.query))
 			is.NoErr(err)
 			defer func() {
 				err = resp.Body.Close()
@@ -102,6 +191,9 @@ func TestHandleGenerateURL(t *testing.T) {
 
 			is.Equal(tc.expectedStatusCode, resp.StatusCode)
 			is.True(strings.Contains(string(body), tc.expectedBodyContains))
+			if tc.expectedParams != nil {
+				is.Equal(tc.expectedParams, reqParams)
+			}
 		})
 	}
 }
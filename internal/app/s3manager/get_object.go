@@ -2,15 +2,16 @@ package s3manager
 
 import (
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
 )
 
-// HandleGetObject downloads an object to the client.
-func HandleGetObject(s3 S3, forceDownload, showVersions bool) http.HandlerFunc {
+// HandleGetObject downloads an object to the client. It supports range
+// requests, so videos can be scrubbed and interrupted downloads resumed, and
+// conditional requests based on the object's ETag and last modification.
+func HandleGetObject(s3 S3, forceDownload, showVersions bool, sseInfo SSEType) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		bucketName := mux.Vars(r)["bucketName"]
 		objectName := mux.Vars(r)["objectName"]
@@ -21,7 +22,20 @@ func HandleGetObject(s3 S3, forceDownload, showVersions bool) http.HandlerFunc {
 			versionID = r.URL.Query().Get("versionId")
 		}
 
-		object, err := s3.GetObject(r.Context(), bucketName, objectName, minio.GetObjectOptions{VersionID: versionID})
+		sse, err := readEncryption(sseInfo)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		object, err := s3.GetObject(r.Context(), bucketName, objectName, minio.GetObjectOptions{VersionID: versionID, ServerSideEncryption: sse})
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error getting object: %w", err))
+			return
+		}
+		defer func() { _ = object.Close() }()
+
+		info, err := object.Stat()
 		if err != nil {
 			handleHTTPError(w, fmt.Errorf("error getting object: %w", err))
 			return
@@ -30,11 +44,14 @@ func HandleGetObject(s3 S3, forceDownload, showVersions bool) http.HandlerFunc {
 		if forceDownload {
 			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", objectName))
 			w.Header().Set("Content-Type", "application/octet-stream")
+		} else if info.ContentType != "" {
+			w.Header().Set("Content-Type", info.ContentType)
 		}
-		_, err = io.Copy(w, object)
-		if err != nil {
-			handleHTTPError(w, fmt.Errorf("error copying object to response writer: %w", err))
-			return
+		if info.ETag != "" {
+			w.Header().Set("ETag", `"`+info.ETag+`"`)
 		}
+		// The object seeks by requesting ranges from S3, so only the
+		// requested ranges are transferred.
+		http.ServeContent(w, r, objectName, info.LastModified, object)
 	}
 }
@@ -24,14 +24,26 @@ type objectMetadata struct {
 	UserMetadata map[string]string `json:"userMetadata"`
 }
 
-// HandleGetObjectMetadata returns metadata for an object (optionally a specific version).
-func HandleGetObjectMetadata(s3 S3) http.HandlerFunc {
+// HandleGetObjectMetadata returns metadata for an object (optionally a specific
+// version if showVersions is set).
+func HandleGetObjectMetadata(s3 S3, showVersions bool, sseInfo SSEType) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		bucketName := mux.Vars(r)["bucketName"]
 		objectName := mux.Vars(r)["objectName"]
-		versionID := r.URL.Query().Get("versionId")
+		// Ignore versionId unless the versions feature is enabled, like
+		// HandleGetObject does.
+		versionID := ""
+		if showVersions {
+			versionID = r.URL.Query().Get("versionId")
+		}
+
+		sse, err := readEncryption(sseInfo)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
 
-		info, err := s3.StatObject(r.Context(), bucketName, objectName, minio.StatObjectOptions{VersionID: versionID})
+		info, err := s3.StatObject(r.Context(), bucketName, objectName, minio.StatObjectOptions{VersionID: versionID, ServerSideEncryption: sse})
 		if err != nil {
 			handleHTTPError(w, fmt.Errorf("error getting object metadata: %w", err))
 			return
@@ -15,6 +15,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/matryer/is"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 )
 
 func TestHandleGetObjectMetadata(t *testing.T) {
@@ -26,6 +27,8 @@ func TestHandleGetObjectMetadata(t *testing.T) {
 		it                 string
 		statObjectFunc     func(context.Context, string, string, minio.StatObjectOptions) (minio.ObjectInfo, error)
 		queryString        string
+		showVersions       bool
+		sseInfo            s3manager.SSEType
 		expectedStatusCode int
 		expectedBody       map[string]any
 		expectedBodyError  string
@@ -68,6 +71,7 @@ func TestHandleGetObjectMetadata(t *testing.T) {
 				}, nil
 			},
 			queryString:        "?versionId=VERSION-123",
+			showVersions:       true,
 			expectedStatusCode: http.StatusOK,
 			expectedBody: map[string]any{
 				"key":       "OBJECT-NAME",
@@ -75,6 +79,46 @@ func TestHandleGetObjectMetadata(t *testing.T) {
 				"isLatest":  true,
 			},
 		},
+		{
+			it: "ignores the versionId query param unless versions are shown",
+			statObjectFunc: func(_ context.Context, _, _ string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+				is := is.New(t)
+				is.Equal("", opts.VersionID) // old versions stay hidden
+				return minio.ObjectInfo{Key: "OBJECT-NAME", LastModified: lastModified}, nil
+			},
+			queryString:        "?versionId=VERSION-123",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: map[string]any{
+				"key": "OBJECT-NAME",
+			},
+		},
+		{
+			it: "passes the SSE-C key through to StatObjectOptions",
+			statObjectFunc: func(_ context.Context, _, _ string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+				is := is.New(t)
+				is.True(opts.ServerSideEncryption != nil)
+				is.Equal(encrypt.SSEC, opts.ServerSideEncryption.Type())
+				return minio.ObjectInfo{Key: "OBJECT-NAME", LastModified: lastModified}, nil
+			},
+			sseInfo:            s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: map[string]any{
+				"key": "OBJECT-NAME",
+			},
+		},
+		{
+			it: "sends no encryption for SSE encrypted objects",
+			statObjectFunc: func(_ context.Context, _, _ string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+				is := is.New(t)
+				is.Equal(nil, opts.ServerSideEncryption)
+				return minio.ObjectInfo{Key: "OBJECT-NAME", LastModified: lastModified}, nil
+			},
+			sseInfo:            s3manager.SSEType{Type: "SSE"},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: map[string]any{
+				"key": "OBJECT-NAME",
+			},
+		},
 		{
 			it: "derives user metadata from raw headers when UserMetadata is empty",
 			statObjectFunc: func(_ context.Context, _, _ string, _ minio.StatObjectOptions) (minio.ObjectInfo, error) {
@@ -112,7 +156,7 @@ func TestHandleGetObjectMetadata(t *testing.T) {
 			}
 
 			r := mux.NewRouter()
-			r.Handle("/api/buckets/{bucketName}/objects/{objectName}/metadata", s3manager.HandleGetObjectMetadata(s3)).Methods(http.MethodGet)
+			r.Handle("/api/buckets/{bucketName}/objects/{objectName}/metadata", s3manager.HandleGetObjectMetadata(s3, tc.showVersions, tc.sseInfo)).Methods(http.MethodGet)
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
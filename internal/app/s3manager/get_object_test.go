@@ -1,6 +1,7 @@
 package s3manager_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -8,12 +9,15 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloudlena/s3manager/internal/app/s3manager"
 	"github.com/cloudlena/s3manager/internal/app/s3manager/mocks"
 	"github.com/gorilla/mux"
 	"github.com/matryer/is"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 )
 
 func TestHandleGetObject(t *testing.T) {
@@ -26,6 +30,7 @@ func TestHandleGetObject(t *testing.T) {
 		objectName           string
 		queryString          string
 		showVersions         bool
+		sseInfo              s3manager.SSEType
 		expectedStatusCode   int
 		expectedBodyContains string
 	}{
@@ -82,6 +87,45 @@ func TestHandleGetObject(t *testing.T) {
 			expectedStatusCode:   http.StatusInternalServerError,
 			expectedBodyContains: "mocked s3 error",
 		},
+		{
+			it: "passes the SSE-C key through to GetObjectOptions",
+			getObjectFunc: func(_ context.Context, _, _ string, opts minio.GetObjectOptions) (*minio.Object, error) {
+				if opts.ServerSideEncryption == nil || opts.ServerSideEncryption.Type() != encrypt.SSEC {
+					return nil, fmt.Errorf("expected SSE-C encryption, got %v", opts.ServerSideEncryption)
+				}
+				return nil, errS3
+			},
+			bucketName:           "BUCKET-NAME",
+			objectName:           "OBJECT-NAME",
+			sseInfo:              s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "mocked s3 error",
+		},
+		{
+			it: "sends no encryption for SSE-KMS encrypted objects",
+			getObjectFunc: func(_ context.Context, _, _ string, opts minio.GetObjectOptions) (*minio.Object, error) {
+				if opts.ServerSideEncryption != nil {
+					return nil, fmt.Errorf("expected no encryption, got %v", opts.ServerSideEncryption.Type())
+				}
+				return nil, errS3
+			},
+			bucketName:           "BUCKET-NAME",
+			objectName:           "OBJECT-NAME",
+			sseInfo:              s3manager.SSEType{Type: "KMS", Key: "KEY-ID"},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "mocked s3 error",
+		},
+		{
+			it: "returns error if the SSE-C key is invalid",
+			getObjectFunc: func(context.Context, string, string, minio.GetObjectOptions) (*minio.Object, error) {
+				return nil, errS3
+			},
+			bucketName:           "BUCKET-NAME",
+			objectName:           "OBJECT-NAME",
+			sseInfo:              s3manager.SSEType{Type: "SSE-C", Key: "too-short"},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "error setting SSE-C key",
+		},
 	}
 
 	for _, tc := range cases {
@@ -94,7 +138,7 @@ func TestHandleGetObject(t *testing.T) {
 			}
 
 			r := mux.NewRouter()
-			r.Handle("/buckets/{bucketName}/objects/{objectName}", s3manager.HandleGetObject(s3, true, tc.showVersions)).Methods(http.MethodGet)
+			r.Handle("/buckets/{bucketName}/objects/{objectName}", s3manager.HandleGetObject(s3, true, tc.showVersions, tc.sseInfo)).Methods(http.MethodGet)
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -113,3 +157,151 @@ func TestHandleGetObject(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleGetObjectContent(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("0123456789")
+	lastModified := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	// S3 is faked with ServeContent as well, which answers the range
+	// requests the object makes when seeking.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/BUCKET-NAME/video.mp4" {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Header().Set("ETag", `"abc123"`)
+		http.ServeContent(w, r, "video.mp4", lastModified, bytes.NewReader(content))
+	}))
+	t.Cleanup(ts.Close)
+
+	client, err := minio.New(strings.TrimPrefix(ts.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Region: "us-east-1",
+	})
+	is.New(t).NoErr(err)
+
+	cases := []struct {
+		it                 string
+		objectName         string
+		forceDownload      bool
+		header             http.Header
+		expectedStatusCode int
+		expectedBody       string
+		expectedHeader     http.Header
+	}{
+		{
+			it:                 "serves the object with its headers",
+			objectName:         "video.mp4",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "0123456789",
+			expectedHeader: http.Header{
+				"Content-Length": {"10"},
+				"Content-Type":   {"video/mp4"},
+				"Etag":           {`"abc123"`},
+				"Last-Modified":  {"Fri, 02 Jan 2026 15:04:05 GMT"},
+				"Accept-Ranges":  {"bytes"},
+			},
+		},
+		{
+			it:                 "forces downloads",
+			objectName:         "video.mp4",
+			forceDownload:      true,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "0123456789",
+			expectedHeader: http.Header{
+				"Content-Type":        {"application/octet-stream"},
+				"Content-Disposition": {`attachment; filename="video.mp4"`},
+			},
+		},
+		{
+			it:                 "serves a range",
+			objectName:         "video.mp4",
+			header:             http.Header{"Range": {"bytes=2-5"}},
+			expectedStatusCode: http.StatusPartialContent,
+			expectedBody:       "2345",
+			expectedHeader: http.Header{
+				"Content-Length": {"4"},
+				"Content-Range":  {"bytes 2-5/10"},
+			},
+		},
+		{
+			it:                 "resumes from an offset",
+			objectName:         "video.mp4",
+			header:             http.Header{"Range": {"bytes=7-"}},
+			expectedStatusCode: http.StatusPartialContent,
+			expectedBody:       "789",
+			expectedHeader:     http.Header{"Content-Range": {"bytes 7-9/10"}},
+		},
+		{
+			it:                 "rejects an unsatisfiable range",
+			objectName:         "video.mp4",
+			header:             http.Header{"Range": {"bytes=20-"}},
+			expectedStatusCode: http.StatusRequestedRangeNotSatisfiable,
+			expectedHeader:     http.Header{"Content-Range": {"bytes */10"}},
+		},
+		{
+			it:                 "responds not modified to a matching ETag",
+			objectName:         "video.mp4",
+			header:             http.Header{"If-None-Match": {`"abc123"`}},
+			expectedStatusCode: http.StatusNotModified,
+			expectedHeader:     http.Header{"Etag": {`"abc123"`}},
+		},
+		{
+			it:                 "serves the object for a different ETag",
+			objectName:         "video.mp4",
+			header:             http.Header{"If-None-Match": {`"outdated"`}},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "0123456789",
+		},
+		{
+			it:                 "responds not modified if unmodified since the given time",
+			objectName:         "video.mp4",
+			header:             http.Header{"If-Modified-Since": {"Fri, 02 Jan 2026 15:04:05 GMT"}},
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			it:                 "ignores the range if the object changed",
+			objectName:         "video.mp4",
+			header:             http.Header{"Range": {"bytes=2-5"}, "If-Range": {`"outdated"`}},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "0123456789",
+		},
+		{
+			it:                 "returns 404 for a missing object",
+			objectName:         "missing.mp4",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3 := &mocks.S3Mock{GetObjectFunc: client.GetObject}
+
+			r := mux.NewRouter()
+			r.Handle("/buckets/{bucketName}/objects/{objectName}", s3manager.HandleGetObject(s3, tc.forceDownload, false, s3manager.SSEType{})).Methods(http.MethodGet)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/BUCKET-NAME/objects/"+tc.objectName, nil)
+			for name, values := range tc.header {
+				req.Header[name] = values
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			if tc.expectedBody != "" {
+				is.Equal(tc.expectedBody, rr.Body.String())
+			}
+			for name, values := range tc.expectedHeader {
+				is.Equal(values, rr.Header().Values(name))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package s3manager
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// InstanceHealth is the result of the most recent health check of an S3
+// instance.
+type InstanceHealth struct {
+	Healthy     bool
+	Latency     time.Duration
+	CheckedAt   time.Time
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// Health returns the result of the most recent health check of the instance,
+// or nil if it has not been checked yet.
+func (i *S3Instance) Health() *InstanceHealth {
+	return i.health.Load()
+}
+
+// checkHealth probes the instance by listing its buckets and records the
+// result.
+func (i *S3Instance) checkHealth(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := i.Client.ListBuckets(ctx)
+	health := InstanceHealth{
+		Healthy:   err == nil,
+		Latency:   time.Since(start),
+		CheckedAt: start,
+	}
+
+	previous := i.health.Load()
+	if previous != nil {
+		health.LastError, health.LastErrorAt = previous.LastError, previous.LastErrorAt
+	}
+	if err != nil {
+		health.LastError, health.LastErrorAt = err.Error(), start
+	}
+	i.health.Store(&health)
+
+	switch {
+	case err != nil && (previous == nil || previous.Healthy):
+		log.Printf("S3 instance %s is unhealthy: %v", i.Name, err)
+	case err == nil && previous != nil && !previous.Healthy:
+		log.Printf("S3 instance %s is healthy again", i.Name)
+	}
+}
+
+// CheckHealth probes all instances concurrently, each within timeout.
+func (m *MultiS3Manager) CheckHealth(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, instance := range m.GetAllInstances() {
+		wg.Go(func() { instance.checkHealth(ctx, timeout) })
+	}
+	wg.Wait()
+}
+
+// RunHealthChecks checks the health of all instances right away and then
+// every interval, until ctx is done.
+func (m *MultiS3Manager) RunHealthChecks(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckHealth(ctx, timeout)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ready reports whether at least one instance passed its most recent health
+// check.
+func (m *MultiS3Manager) Ready() bool {
+	for _, instance := range m.GetAllInstances() {
+		if health := instance.Health(); health != nil && health.Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleHealthz reports that the server is running.
+func HandleHealthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, "ok\n")
+	}
+}
+
+// HandleReadyz reports whether the server can serve requests, i.e. whether at
+// least one instance is healthy.
+func HandleReadyz(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if !manager.Ready() {
+			http.Error(w, "no healthy S3 instance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, "ok\n")
+	}
+}
@@ -0,0 +1,141 @@
+package s3manager_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestMultiS3ManagerCheckHealth(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	var down atomic.Bool
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		if down.Load() {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<ListAllMyBucketsResult><Owner><ID>owner</ID></Owner><Buckets></Buckets></ListAllMyBucketsResult>`))
+	}))
+	t.Cleanup(flaky.Close)
+	denied := newFakeS3Server(t, true)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		fakeS3InstanceConfig("flaky", flaky),
+		fakeS3InstanceConfig("denied", denied),
+	})
+	is.NoErr(err)
+	flakyInstance, err := manager.GetInstance("flaky")
+	is.NoErr(err)
+	deniedInstance, err := manager.GetInstance("denied")
+	is.NoErr(err)
+
+	// Instances are unknown until checked.
+	is.True(flakyInstance.Health() == nil)
+	is.True(!manager.Ready())
+
+	manager.CheckHealth(context.Background(), time.Second)
+	is.True(flakyInstance.Health().Healthy)
+	is.Equal("", flakyInstance.Health().LastError)
+	is.True(!deniedInstance.Health().Healthy)
+	is.True(strings.Contains(deniedInstance.Health().LastError, "Access Denied"))
+	is.True(manager.Ready())
+
+	down.Store(true)
+	manager.CheckHealth(context.Background(), time.Second)
+	is.True(!flakyInstance.Health().Healthy)
+	is.True(!manager.Ready())
+
+	// The last error is kept after the instance recovered.
+	down.Store(false)
+	manager.CheckHealth(context.Background(), time.Second)
+	health := flakyInstance.Health()
+	is.True(health.Healthy)
+	is.True(strings.Contains(health.LastError, "Access Denied"))
+	is.True(!health.LastErrorAt.IsZero())
+}
+
+func TestHandleHealthEndpoints(t *testing.T) {
+	t.Parallel()
+
+	reachable := newFakeS3Server(t, false)
+
+	cases := []struct {
+		it                 string
+		handler            func(*s3manager.MultiS3Manager) http.Handler
+		checked            bool
+		expectedStatusCode int
+	}{
+		{
+			it:                 "is alive before any check",
+			handler:            func(*s3manager.MultiS3Manager) http.Handler { return s3manager.HandleHealthz() },
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			it:                 "is not ready before any check",
+			handler:            func(m *s3manager.MultiS3Manager) http.Handler { return s3manager.HandleReadyz(m) },
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			it:                 "is ready once an instance is healthy",
+			handler:            func(m *s3manager.MultiS3Manager) http.Handler { return s3manager.HandleReadyz(m) },
+			checked:            true,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{fakeS3InstanceConfig("primary", reachable)})
+			is.NoErr(err)
+			if tc.checked {
+				manager.CheckHealth(context.Background(), time.Second)
+			}
+
+			rr := httptest.NewRecorder()
+			tc.handler(manager).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+		})
+	}
+}
+
+func TestHandleGetS3InstancesHealth(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		fakeS3InstanceConfig("healthy", newFakeS3Server(t, false)),
+		fakeS3InstanceConfig("unhealthy", newFakeS3Server(t, true)),
+	})
+	is.NoErr(err)
+	manager.CheckHealth(context.Background(), time.Second)
+
+	rr := httptest.NewRecorder()
+	s3manager.HandleGetS3Instances(manager).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/s3-instances", nil))
+	is.Equal(http.StatusOK, rr.Code)
+
+	var result struct {
+		Instances []s3manager.S3InstanceInfo `json:"instances"`
+	}
+	is.NoErr(json.Unmarshal(rr.Body.Bytes(), &result))
+	is.Equal(2, len(result.Instances))
+	is.True(result.Instances[0].Health.Healthy)
+	is.True(result.Instances[0].Health.LastErrorAt == nil)
+	is.True(!result.Instances[1].Health.Healthy)
+	is.True(strings.Contains(result.Instances[1].Health.LastError, "Access Denied"))
+}
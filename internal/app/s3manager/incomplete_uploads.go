@@ -0,0 +1,84 @@
+package s3manager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// IncompleteUpload is a multipart upload that was neither completed nor
+// aborted. Its parts are stored, and billed, until it is aborted.
+type IncompleteUpload struct {
+	Key       string    `json:"key"`
+	UploadID  string    `json:"upload_id"`
+	Initiated time.Time `json:"initiated"`
+	Parts     int       `json:"parts"`
+	Size      int64     `json:"size"`
+}
+
+// HandleListIncompleteUploads lists the incomplete multipart uploads of a
+// bucket, optionally only those below the prefix given in the query, with
+// the number and total size of their parts.
+func HandleListIncompleteUploads(s3 S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucketName"]
+
+		uploads, err := listIncompleteUploads(r.Context(), s3, bucketName, r.URL.Query().Get("prefix"))
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		incomplete := make([]IncompleteUpload, 0, len(uploads))
+		for _, upload := range uploads {
+			parts, err := listUploadParts(r.Context(), s3, bucketName, upload.Key, upload.UploadID)
+			var response minio.ErrorResponse
+			if errors.As(err, &response) && response.Code == "NoSuchUpload" {
+				// It was completed or aborted since it was listed.
+				continue
+			}
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+
+			info := IncompleteUpload{Key: upload.Key, UploadID: upload.UploadID, Initiated: upload.Initiated, Parts: len(parts)}
+			for _, part := range parts {
+				info.Size += part.Size
+			}
+			incomplete = append(incomplete, info)
+		}
+		writeJSON(w, http.StatusOK, incomplete)
+	}
+}
+
+// HandleAbortIncompleteUploads aborts all incomplete multipart uploads of a
+// bucket that were initiated more than older_than seconds ago.
+func HandleAbortIncompleteUploads(s3 S3) http.HandlerFunc {
+	type response struct {
+		Aborted int `json:"aborted"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucketName"]
+
+		olderThan, err := strconv.Atoi(r.URL.Query().Get("older_than"))
+		if err != nil || olderThan < 0 {
+			http.Error(w, "older_than must be a number of seconds", http.StatusBadRequest)
+			return
+		}
+
+		cutoff := time.Now().Add(-time.Duration(olderThan) * time.Second)
+		aborted, err := abortUploadsInitiatedBefore(r.Context(), s3, bucketName, cutoff)
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error aborting uploads after aborting %d: %w", aborted, err))
+			return
+		}
+		writeJSON(w, http.StatusOK, response{Aborted: aborted})
+	}
+}
@@ -0,0 +1,159 @@
+package s3manager_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/cloudlena/s3manager/internal/app/s3manager/mocks"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+	"github.com/minio/minio-go/v7"
+)
+
+func TestHandleListIncompleteUploads(t *testing.T) {
+	t.Parallel()
+
+	initiated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		it                   string
+		listUploadsErr       error
+		expectedStatusCode   int
+		expectedUploads      []s3manager.IncompleteUpload
+		expectedBodyContains string
+	}{
+		{
+			it:                 "lists incomplete uploads with the size of their parts",
+			expectedStatusCode: http.StatusOK,
+			expectedUploads: []s3manager.IncompleteUpload{
+				{Key: "disk.img", UploadID: "upload-1", Initiated: initiated, Parts: 2, Size: 30},
+			},
+		},
+		{
+			it:                   "returns error if there is an S3 error",
+			listUploadsErr:       errS3,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "mocked s3 error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3 := &mocks.S3Mock{
+				ListMultipartUploadsFunc: func(_ context.Context, bucket, prefix, _, _, _ string, _ int) (minio.ListMultipartUploadsResult, error) {
+					is.Equal("BUCKET-NAME", bucket)
+					is.Equal("images/", prefix)
+					return minio.ListMultipartUploadsResult{Uploads: []minio.ObjectMultipartInfo{
+						{Key: "disk.img", UploadID: "upload-1", Initiated: initiated},
+						{Key: "done.img", UploadID: "upload-2", Initiated: initiated},
+					}}, tc.listUploadsErr
+				},
+				ListObjectPartsFunc: func(_ context.Context, _, _, uploadID string, _, _ int) (minio.ListObjectPartsResult, error) {
+					if uploadID == "upload-2" {
+						// Completed since it was listed
+						return minio.ListObjectPartsResult{}, minio.ErrorResponse{Code: "NoSuchUpload"}
+					}
+					return minio.ListObjectPartsResult{ObjectParts: []minio.ObjectPart{{PartNumber: 1, Size: 20}, {PartNumber: 2, Size: 10}}}, nil
+				},
+			}
+
+			r := mux.NewRouter()
+			r.Handle("/buckets/{bucketName}/uploads", s3manager.HandleListIncompleteUploads(s3)).Methods(http.MethodGet)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/BUCKET-NAME/uploads?prefix=images/", nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+			if tc.expectedUploads != nil {
+				var uploads []s3manager.IncompleteUpload
+				is.NoErr(json.NewDecoder(rr.Body).Decode(&uploads))
+				is.Equal(tc.expectedUploads, uploads)
+			}
+		})
+	}
+}
+
+func TestHandleAbortIncompleteUploads(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		olderThan            string
+		abortErr             error
+		expectedStatusCode   int
+		expectedAborted      []string
+		expectedBodyContains string
+	}{
+		{
+			it:                   "aborts uploads older than the given age",
+			olderThan:            "86400",
+			expectedStatusCode:   http.StatusOK,
+			expectedAborted:      []string{"old-id"},
+			expectedBodyContains: `"aborted":1`,
+		},
+		{
+			it:                   "aborts all uploads",
+			olderThan:            "0",
+			expectedStatusCode:   http.StatusOK,
+			expectedAborted:      []string{"old-id", "new-id"},
+			expectedBodyContains: `"aborted":2`,
+		},
+		{
+			it:                   "rejects an invalid age",
+			olderThan:            "a-week",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "older_than must be a number of seconds",
+		},
+		{
+			it:                   "returns error if there is an S3 error",
+			olderThan:            "86400",
+			abortErr:             errS3,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedAborted:      []string{"old-id"},
+			expectedBodyContains: "mocked s3 error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			var aborted []string
+			s3 := &mocks.S3Mock{
+				ListMultipartUploadsFunc: func(context.Context, string, string, string, string, string, int) (minio.ListMultipartUploadsResult, error) {
+					return minio.ListMultipartUploadsResult{Uploads: []minio.ObjectMultipartInfo{
+						{Key: "old.img", UploadID: "old-id", Initiated: time.Now().Add(-48 * time.Hour)},
+						{Key: "new.img", UploadID: "new-id", Initiated: time.Now().Add(-time.Second)},
+					}}, nil
+				},
+				AbortMultipartUploadFunc: func(_ context.Context, _, _, uploadID string) error {
+					aborted = append(aborted, uploadID)
+					return tc.abortErr
+				},
+			}
+
+			r := mux.NewRouter()
+			r.Handle("/buckets/{bucketName}/uploads", s3manager.HandleAbortIncompleteUploads(s3)).Methods(http.MethodDelete)
+
+			req := httptest.NewRequest(http.MethodDelete, "/buckets/BUCKET-NAME/uploads?older_than="+tc.olderThan, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.Equal(tc.expectedAborted, aborted)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+		})
+	}
+}
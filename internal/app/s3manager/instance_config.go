@@ -0,0 +1,171 @@
+package s3manager
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var signatureTypes = []string{"V2", "V4", "V4Streaming", "Anonymous"}
+
+// reservedInstanceNames are path segments served by routes outside of any
+// instance, such as share links under /s/.
+var reservedInstanceNames = []string{"s"}
+
+// ConfigError describes an invalid configuration field.
+type ConfigError struct {
+	Field string
+	Err   error
+}
+
+// Error implements error.
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that config describes a usable S3 instance. Fields are
+// reported by their configuration file keys.
+func (config S3InstanceConfig) Validate() error {
+	switch {
+	case config.Name == "":
+		return &ConfigError{Field: "name", Err: errors.New("must be set")}
+	case strings.ContainsAny(config.Name, "/?#"):
+		return &ConfigError{Field: "name", Err: fmt.Errorf("must not contain any of '/?#', got %q", config.Name)}
+	case slices.Contains(reservedInstanceNames, config.Name):
+		return &ConfigError{Field: "name", Err: fmt.Errorf("must not be any of %s, got %q", strings.Join(reservedInstanceNames, ", "), config.Name)}
+	case config.Endpoint == "":
+		return &ConfigError{Field: "endpoint", Err: errors.New("must be set")}
+	case !slices.Contains(signatureTypes, config.SignatureType):
+		return &ConfigError{Field: "signature_type", Err: fmt.Errorf("must be one of %s, got %q", strings.Join(signatureTypes, ", "), config.SignatureType)}
+	}
+
+	switch provider := config.credentialsProvider(); {
+	case !slices.Contains(credentialsProviders, provider):
+		return &ConfigError{Field: "credentials_provider", Err: fmt.Errorf("must be one of %s, got %q", strings.Join(credentialsProviders, ", "), provider)}
+	case config.UseIam && provider != CredentialsIAM:
+		return &ConfigError{Field: "use_iam", Err: fmt.Errorf("must not be enabled for credentials_provider %s", provider)}
+	case provider == CredentialsStatic && config.SignatureType != "Anonymous":
+		if config.AccessKeyID == "" {
+			return &ConfigError{Field: "access_key_id", Err: errors.New("must be set unless use_iam is enabled")}
+		}
+		if config.SecretAccessKey == "" {
+			return &ConfigError{Field: "secret_access_key", Err: errors.New("must be set unless use_iam is enabled")}
+		}
+	case provider == CredentialsAssumeRole:
+		if config.AccessKeyID == "" {
+			return &ConfigError{Field: "access_key_id", Err: errors.New("must be set for credentials_provider assume_role")}
+		}
+		if config.SecretAccessKey == "" {
+			return &ConfigError{Field: "secret_access_key", Err: errors.New("must be set for credentials_provider assume_role")}
+		}
+	}
+
+	switch {
+	case config.TLSMinVersion != "" && tlsVersions[config.TLSMinVersion] == 0:
+		return &ConfigError{Field: "tls_min_version", Err: fmt.Errorf("must be one of 1.2, 1.3, got %q", config.TLSMinVersion)}
+	case config.ClientCertFile != "" && config.ClientKeyFile == "":
+		return &ConfigError{Field: "client_key_file", Err: errors.New("must be set if client_cert_file is set")}
+	case config.ClientKeyFile != "" && config.ClientCertFile == "":
+		return &ConfigError{Field: "client_cert_file", Err: errors.New("must be set if client_key_file is set")}
+	case config.MaxIdleConns < 0:
+		return &ConfigError{Field: "max_idle_conns", Err: errors.New("must not be negative")}
+	case config.MaxIdleConnsPerHost < 0:
+		return &ConfigError{Field: "max_idle_conns_per_host", Err: errors.New("must not be negative")}
+	case config.IdleConnTimeout < 0:
+		return &ConfigError{Field: "idle_conn_timeout", Err: errors.New("must not be negative")}
+	case config.MaxPresignExpiry < 0 || time.Duration(config.MaxPresignExpiry)*time.Second > maxPresignedExpiry:
+		return &ConfigError{Field: "max_presign_expiry", Err: fmt.Errorf("must be between 0 and %d seconds, got %d", int64(maxPresignedExpiry/time.Second), config.MaxPresignExpiry)}
+	}
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return &ConfigError{Field: "proxy_url", Err: err}
+		}
+		if !slices.Contains([]string{"http", "https", "socks5"}, proxyURL.Scheme) || proxyURL.Host == "" {
+			return &ConfigError{Field: "proxy_url", Err: fmt.Errorf("must be an http, https or socks5 URL, got %q", config.ProxyURL)}
+		}
+	}
+
+	if err := validateSSEKey(config.SseType, config.SseKey); err != nil {
+		return &ConfigError{Field: "sse_key", Err: err}
+	}
+	for i, bucketSSE := range config.BucketSSE {
+		if len(bucketSSE.Buckets) == 0 {
+			return &ConfigError{Field: fmt.Sprintf("bucket_sse[%d].buckets", i), Err: errors.New("must be set")}
+		}
+		if _, err := NewBucketFilter(bucketSSE.Buckets, nil); err != nil {
+			return &ConfigError{Field: fmt.Sprintf("bucket_sse[%d].buckets", i), Err: err}
+		}
+		if err := validateSSEKey(bucketSSE.SseType, bucketSSE.SseKey); err != nil {
+			return &ConfigError{Field: fmt.Sprintf("bucket_sse[%d].sse_key", i), Err: err}
+		}
+	}
+
+	if _, err := NewBucketFilter(config.AllowedBuckets, nil); err != nil {
+		return &ConfigError{Field: "allowed_buckets", Err: err}
+	}
+	if _, err := NewBucketFilter(nil, config.DeniedBuckets); err != nil {
+		return &ConfigError{Field: "denied_buckets", Err: err}
+	}
+
+	return nil
+}
+
+// validateSSEKey checks that sseKey can be used for sseType. Other SSE types
+// than KMS and SSE-C don't need a key.
+func validateSSEKey(sseType, sseKey string) error {
+	switch {
+	case sseType == "KMS" && sseKey == "":
+		return errors.New("must be set for sse_type KMS")
+	case sseType == "SSE-C" && len(sseKey) != 32:
+		return errors.New("must be 32 bytes long for sse_type SSE-C")
+	}
+	return nil
+}
+
+// ValidateInstanceConfigs validates every config and checks that instance
+// names are unique. Errors point at the offending field, e.g. instances[1].endpoint.
+func ValidateInstanceConfigs(configs []S3InstanceConfig) error {
+	if len(configs) == 0 {
+		return &ConfigError{Field: "instances", Err: errors.New("at least one instance must be configured")}
+	}
+
+	names := make(map[string]int, len(configs))
+	for i, config := range configs {
+		if err := config.Validate(); err != nil {
+			var configErr *ConfigError
+			if errors.As(err, &configErr) {
+				return &ConfigError{Field: fmt.Sprintf("instances[%d].%s", i, configErr.Field), Err: configErr.Err}
+			}
+			return err
+		}
+		if first, exists := names[config.Name]; exists {
+			return &ConfigError{Field: fmt.Sprintf("instances[%d].name", i), Err: fmt.Errorf("duplicate instance name %q, already used by instances[%d]", config.Name, first)}
+		}
+		names[config.Name] = i
+	}
+
+	return nil
+}
+
+// RestrictToBucket narrows every config to the single bucket. The bucket must
+// be accessible with the buckets the config already allows, so the
+// restriction never grants access to a bucket that was excluded.
+func RestrictToBucket(configs []S3InstanceConfig, bucket string) error {
+	for i := range configs {
+		filter := BucketFilter{Allow: configs[i].AllowedBuckets, Deny: configs[i].DeniedBuckets}
+		if !filter.Allows(bucket) {
+			return &ConfigError{Field: fmt.Sprintf("instances[%d].allowed_buckets", i), Err: fmt.Errorf("does not allow bucket %q", bucket)}
+		}
+		configs[i].AllowedBuckets = []string{bucket}
+	}
+	return nil
+}
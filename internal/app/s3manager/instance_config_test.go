@@ -0,0 +1,284 @@
+package s3manager_test
+
+import (
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestValidateInstanceConfigs(t *testing.T) {
+	t.Parallel()
+
+	valid := s3manager.S3InstanceConfig{
+		Name:            "primary",
+		Endpoint:        "localhost:9000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		SignatureType:   "V4",
+	}
+
+	cases := []struct {
+		it            string
+		configs       func() []s3manager.S3InstanceConfig
+		expectedError string
+	}{
+		{
+			it: "accepts valid instances",
+			configs: func() []s3manager.S3InstanceConfig {
+				second := valid
+				second.Name = "secondary"
+				return []s3manager.S3InstanceConfig{valid, second}
+			},
+		},
+		{
+			it: "accepts IAM instances without keys",
+			configs: func() []s3manager.S3InstanceConfig {
+				iam := valid
+				iam.AccessKeyID, iam.SecretAccessKey, iam.UseIam = "", "", true
+				return []s3manager.S3InstanceConfig{iam}
+			},
+		},
+		{
+			it: "accepts web identity instances without keys",
+			configs: func() []s3manager.S3InstanceConfig {
+				webIdentity := valid
+				webIdentity.AccessKeyID, webIdentity.SecretAccessKey = "", ""
+				webIdentity.CredentialsProvider = s3manager.CredentialsWebIdentity
+				return []s3manager.S3InstanceConfig{webIdentity}
+			},
+		},
+		{
+			it:            "rejects an empty list",
+			configs:       func() []s3manager.S3InstanceConfig { return nil },
+			expectedError: "instances: at least one instance must be configured",
+		},
+		{
+			it: "points at a missing endpoint",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.Name, broken.Endpoint = "secondary", ""
+				return []s3manager.S3InstanceConfig{valid, broken}
+			},
+			expectedError: "instances[1].endpoint: must be set",
+		},
+		{
+			it: "points at an invalid signature type",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.SignatureType = "V5"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].signature_type: must be one of V2, V4, V4Streaming, Anonymous, got "V5"`,
+		},
+		{
+			it: "points at missing credentials",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.SecretAccessKey = ""
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].secret_access_key: must be set unless use_iam is enabled",
+		},
+		{
+			it: "points at an invalid credentials provider",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.CredentialsProvider = "vault"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].credentials_provider: must be one of static, iam, env, file, assume_role, web_identity, chain, got "vault"`,
+		},
+		{
+			it: "points at use_iam combined with another provider",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.UseIam, broken.CredentialsProvider = true, s3manager.CredentialsFile
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].use_iam: must not be enabled for credentials_provider file",
+		},
+		{
+			it: "points at missing keys to assume a role with",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.AccessKeyID, broken.CredentialsProvider = "", s3manager.CredentialsAssumeRole
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].access_key_id: must be set for credentials_provider assume_role",
+		},
+		{
+			it: "points at an unsupported TLS version",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.TLSMinVersion = "1.1"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].tls_min_version: must be one of 1.2, 1.3, got "1.1"`,
+		},
+		{
+			it: "points at a client certificate without key",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.ClientCertFile = "client.pem"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].client_key_file: must be set if client_cert_file is set",
+		},
+		{
+			it: "points at an invalid proxy URL",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.ProxyURL = "proxy.internal:3128"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].proxy_url: must be an http, https or socks5 URL, got "proxy.internal:3128"`,
+		},
+		{
+			it: "points at a name that cannot be used in URLs",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.Name = "team/prod"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].name: must not contain any of '/?#', got "team/prod"`,
+		},
+		{
+			it: "points at a name reserved for share links",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.Name = "s"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].name: must not be any of s, got "s"`,
+		},
+		{
+			it: "points at an invalid bucket pattern",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.DeniedBuckets = []string{"[secret"}
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: `instances[0].denied_buckets: invalid bucket pattern "[secret": syntax error in pattern`,
+		},
+		{
+			it: "points at a missing KMS key",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.SseType = "KMS"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].sse_key: must be set for sse_type KMS",
+		},
+		{
+			it: "points at an SSE-C key of the wrong length",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.SseType, broken.SseKey = "SSE-C", "too-short"
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].sse_key: must be 32 bytes long for sse_type SSE-C",
+		},
+		{
+			it: "points at a bucket encryption without buckets",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.BucketSSE = []s3manager.BucketSSEConfig{{SseType: "SSE"}}
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].bucket_sse[0].buckets: must be set",
+		},
+		{
+			it: "points at a bucket encryption with an invalid key",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.BucketSSE = []s3manager.BucketSSEConfig{
+					{Buckets: []string{"public-*"}, SseType: "SSE"},
+					{Buckets: []string{"secure-*"}, SseType: "SSE-C", SseKey: "too-short"},
+				}
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].bucket_sse[1].sse_key: must be 32 bytes long for sse_type SSE-C",
+		},
+		{
+			it: "points at a presign expiry S3 does not support",
+			configs: func() []s3manager.S3InstanceConfig {
+				broken := valid
+				broken.MaxPresignExpiry = 30 * 24 * 3600
+				return []s3manager.S3InstanceConfig{broken}
+			},
+			expectedError: "instances[0].max_presign_expiry: must be between 0 and 604800 seconds, got 2592000",
+		},
+		{
+			it: "points at duplicate names",
+			configs: func() []s3manager.S3InstanceConfig {
+				return []s3manager.S3InstanceConfig{valid, valid}
+			},
+			expectedError: `instances[1].name: duplicate instance name "primary", already used by instances[0]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			err := s3manager.ValidateInstanceConfigs(tc.configs())
+			if tc.expectedError == "" {
+				is.NoErr(err)
+			} else {
+				is.True(err != nil)
+				is.Equal(tc.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestRestrictToBucket(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                     string
+		allowedBuckets         []string
+		deniedBuckets          []string
+		expectedAllowedBuckets []string
+		expectedError          string
+	}{
+		{
+			it:                     "restricts instances that allow every bucket",
+			expectedAllowedBuckets: []string{"team-bucket"},
+		},
+		{
+			it:                     "narrows the allowed buckets",
+			allowedBuckets:         []string{"team-*", "other"},
+			expectedAllowedBuckets: []string{"team-bucket"},
+		},
+		{
+			it:             "rejects a bucket that is not allowed",
+			allowedBuckets: []string{"other"},
+			expectedError:  `instances[0].allowed_buckets: does not allow bucket "team-bucket"`,
+		},
+		{
+			it:            "rejects a bucket that is denied",
+			deniedBuckets: []string{"team-*"},
+			expectedError: `instances[0].allowed_buckets: does not allow bucket "team-bucket"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			configs := []s3manager.S3InstanceConfig{{Name: "primary", AllowedBuckets: tc.allowedBuckets, DeniedBuckets: tc.deniedBuckets}}
+			err := s3manager.RestrictToBucket(configs, "team-bucket")
+			if tc.expectedError == "" {
+				is.NoErr(err)
+				is.Equal(tc.expectedAllowedBuckets, configs[0].AllowedBuckets)
+			} else {
+				is.True(err != nil)
+				is.Equal(tc.expectedError, err.Error())
+			}
+		})
+	}
+}
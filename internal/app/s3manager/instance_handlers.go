@@ -2,20 +2,54 @@ package s3manager
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
 // S3InstanceInfo represents the information about an S3 instance for API responses
 type S3InstanceInfo struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// Managed reports whether the instance was registered at runtime and can
+	// be changed or removed through the API.
+	Managed bool `json:"managed,omitempty"`
+	// Health is the result of the most recent health check, if any.
+	Health *S3InstanceHealthInfo `json:"health,omitempty"`
+}
+
+// S3InstanceHealthInfo represents the health of an S3 instance for API responses
+type S3InstanceHealthInfo struct {
+	Healthy     bool       `json:"healthy"`
+	LatencyMS   int64      `json:"latency_ms"`
+	CheckedAt   time.Time  `json:"checked_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+func newS3InstanceHealthInfo(health *InstanceHealth) *S3InstanceHealthInfo {
+	if health == nil {
+		return nil
+	}
+	info := &S3InstanceHealthInfo{
+		Healthy:   health.Healthy,
+		LatencyMS: health.Latency.Milliseconds(),
+		CheckedAt: health.CheckedAt,
+		LastError: health.LastError,
+	}
+	if !health.LastErrorAt.IsZero() {
+		info.LastErrorAt = &health.LastErrorAt
+	}
+	return info
 }
 
-// HandleGetS3Instances returns all available S3 instances
+// HandleGetS3Instances returns all S3 instances the user has access to
 func HandleGetS3Instances(manager *MultiS3Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		instances := manager.GetAllInstances()
+		instances := manager.GetAccessibleInstances(r)
 
 		response := struct {
 			Instances []S3InstanceInfo `json:"instances"`
@@ -25,8 +59,10 @@ func HandleGetS3Instances(manager *MultiS3Manager) http.HandlerFunc {
 
 		for i, instance := range instances {
 			response.Instances[i] = S3InstanceInfo{
-				ID:   instance.ID,
-				Name: instance.Name,
+				ID:      instance.ID,
+				Name:    instance.Name,
+				Managed: manager.IsManaged(instance),
+				Health:  newS3InstanceHealthInfo(instance.Health()),
 			}
 		}
 
@@ -38,3 +74,125 @@ func HandleGetS3Instances(manager *MultiS3Manager) http.HandlerFunc {
 		}
 	}
 }
+
+// HandleCreateS3Instance registers a new S3 instance. It requires the admin
+// role on all buckets of all instances.
+func HandleCreateS3Instance(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config, err := decodeInstanceConfig(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		// The name is chosen by the requester, so a role on it would let
+		// admins of e.g. tenant-* register instances with any credentials.
+		if manager.GlobalRoleFor(r) < RoleAdmin {
+			http.Error(w, fmt.Sprintf("Forbidden: %s role required on all instances", RoleAdmin), http.StatusForbidden)
+			return
+		}
+
+		instance, err := manager.AddInstance(r.Context(), config)
+		if err != nil {
+			handleInstanceError(w, fmt.Errorf("error adding instance: %w", err))
+			return
+		}
+
+		writeInstanceInfo(w, http.StatusCreated, instance)
+	}
+}
+
+// HandleUpdateS3Instance changes the configuration of an S3 instance
+// registered at runtime.
+func HandleUpdateS3Instance(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		if manager.InstanceRoleFor(r, current.Name) < RoleAdmin {
+			http.Error(w, fmt.Sprintf("Forbidden: %s role required", RoleAdmin), http.StatusForbidden)
+			return
+		}
+
+		config, err := decodeInstanceConfig(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		instance, err := manager.UpdateInstance(r.Context(), current.ID, config)
+		if err != nil {
+			handleInstanceError(w, fmt.Errorf("error updating instance: %w", err))
+			return
+		}
+
+		writeInstanceInfo(w, http.StatusOK, instance)
+	}
+}
+
+// HandleDeleteS3Instance removes an S3 instance registered at runtime.
+func HandleDeleteS3Instance(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		if manager.InstanceRoleFor(r, current.Name) < RoleAdmin {
+			http.Error(w, fmt.Sprintf("Forbidden: %s role required", RoleAdmin), http.StatusForbidden)
+			return
+		}
+
+		err := manager.RemoveInstance(mux.Vars(r)["instance"])
+		if err != nil {
+			handleInstanceError(w, fmt.Errorf("error removing instance: %w", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// decodeInstanceConfig decodes an instance configuration from the request
+// body, applying the documented defaults of the instance settings.
+func decodeInstanceConfig(r *http.Request) (S3InstanceConfig, error) {
+	config := S3InstanceConfig{
+		UseSSL:        true,
+		SignatureType: "V4",
+		AllowDelete:   true,
+		ForceDownload: true,
+		ShowMetadata:  true,
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return S3InstanceConfig{}, fmt.Errorf("error decoding body JSON: %w", err)
+	}
+	return config, nil
+}
+
+// handleInstanceError responds to errors from changing instances at runtime.
+func handleInstanceError(w http.ResponseWriter, err error) {
+	var configErr *ConfigError
+	switch {
+	case errors.As(err, &configErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrInstanceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInstanceExists), errors.Is(err, ErrInstanceNotManaged):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInstanceUnreachable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		handleHTTPError(w, err)
+	}
+}
+
+func writeInstanceInfo(w http.ResponseWriter, code int, instance *S3Instance) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(S3InstanceInfo{ID: instance.ID, Name: instance.Name, Managed: true})
+	if err != nil {
+		handleHTTPError(w, fmt.Errorf("error encoding JSON: %w", err))
+		return
+	}
+}
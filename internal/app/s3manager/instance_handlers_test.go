@@ -5,9 +5,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/gorilla/mux"
 	"github.com/matryer/is"
 )
 
@@ -95,3 +98,163 @@ func TestHandleGetS3Instances(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleChangeS3Instances(t *testing.T) {
+	t.Parallel()
+
+	reachable := newFakeS3Server(t, false)
+	endpoint := strings.TrimPrefix(reachable.URL, "http://")
+	ops := &s3manager.User{Name: "carol", Groups: []string{"ops"}}
+	platform := &s3manager.User{Name: "alice", Groups: []string{"platform"}}
+	viewer := &s3manager.User{Name: "bob"}
+
+	cases := []struct {
+		it                   string
+		method               string
+		path                 string
+		body                 string
+		user                 *s3manager.User
+		expectedStatusCode   int
+		expectedBodyContains string
+	}{
+		{
+			it:                   "registers an instance",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"tenant-b","endpoint":"` + endpoint + `","access_key_id":"key","secret_access_key":"secret","region":"us-east-1","use_ssl":false}`,
+			user:                 ops,
+			expectedStatusCode:   http.StatusCreated,
+			expectedBodyContains: `"name":"tenant-b"`,
+		},
+		{
+			it:                   "rejects registering an instance without admin role",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"other","endpoint":"` + endpoint + `","access_key_id":"key","secret_access_key":"secret","use_ssl":false}`,
+			user:                 platform,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "admin role required",
+		},
+		{
+			it:                   "rejects registering an instance as admin of matching instances",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"tenant-b","endpoint":"` + endpoint + `","access_key_id":"key","secret_access_key":"secret","use_ssl":false}`,
+			user:                 platform,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "admin role required on all instances",
+		},
+		{
+			it:                   "rejects unknown settings",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"tenant-b","colour":"red"}`,
+			user:                 platform,
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedBodyContains: "unknown field",
+		},
+		{
+			it:                   "rejects invalid settings",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"tenant-b","endpoint":"` + endpoint + `","signature_type":"V5"}`,
+			user:                 ops,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "signature_type",
+		},
+		{
+			it:                   "rejects reading files of the server",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"tenant-b","endpoint":"` + endpoint + `","access_key_id":"key","secret_access_key":"secret","ca_file":"/etc/shadow"}`,
+			user:                 ops,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "ca_file: must not be set for instances registered at runtime",
+		},
+		{
+			it:                   "rejects an existing name",
+			method:               http.MethodPost,
+			path:                 "/api/s3-instances",
+			body:                 `{"name":"tenant-a","endpoint":"` + endpoint + `","access_key_id":"key","secret_access_key":"secret","region":"us-east-1","use_ssl":false}`,
+			user:                 ops,
+			expectedStatusCode:   http.StatusConflict,
+			expectedBodyContains: "already exists",
+		},
+		{
+			it:                   "updates a registered instance",
+			method:               http.MethodPut,
+			path:                 "/api/s3-instances/tenant-a",
+			body:                 `{"endpoint":"` + endpoint + `","access_key_id":"key","region":"us-east-1","use_ssl":false}`,
+			user:                 platform,
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"name":"tenant-a"`,
+		},
+		{
+			it:                   "rejects changing a configured instance",
+			method:               http.MethodPut,
+			path:                 "/api/s3-instances/tenant-static",
+			body:                 `{"endpoint":"` + endpoint + `","access_key_id":"key","secret_access_key":"secret","region":"us-east-1","use_ssl":false}`,
+			user:                 platform,
+			expectedStatusCode:   http.StatusConflict,
+			expectedBodyContains: "cannot be changed at runtime",
+		},
+		{
+			it:                 "removes a registered instance",
+			method:             http.MethodDelete,
+			path:               "/api/s3-instances/tenant-a",
+			user:               platform,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			it:                   "rejects removing an instance without admin role",
+			method:               http.MethodDelete,
+			path:                 "/api/s3-instances/tenant-a",
+			user:                 viewer,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "admin role required",
+		},
+		{
+			it:                   "returns error for an unknown instance",
+			method:               http.MethodDelete,
+			path:                 "/api/s3-instances/missing",
+			user:                 platform,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedBodyContains: "Instance not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+				fakeS3InstanceConfig("tenant-static", reachable),
+			})
+			is.NoErr(err)
+			is.NoErr(manager.UseInstanceStore(s3manager.NewFileInstanceStore(filepath.Join(t.TempDir(), "instances.json"))))
+			_, err = manager.AddInstance(t.Context(), fakeS3InstanceConfig("tenant-a", reachable))
+			is.NoErr(err)
+			policy, err := s3manager.NewAccessPolicy([]s3manager.RoleBinding{
+				{Groups: []string{"ops"}, Role: "admin"},
+				{Groups: []string{"platform"}, Role: "admin", Instance: "tenant-*"},
+				{Users: []string{"bob"}, Role: "viewer"},
+			})
+			is.NoErr(err)
+			manager.SetAccessPolicy(policy)
+
+			r := mux.NewRouter()
+			r.Handle("/api/s3-instances", s3manager.HandleCreateS3Instance(manager)).Methods(http.MethodPost)
+			r.Handle("/api/s3-instances/{instance}", s3manager.HandleUpdateS3Instance(manager)).Methods(http.MethodPut)
+			r.Handle("/api/s3-instances/{instance}", s3manager.HandleDeleteS3Instance(manager)).Methods(http.MethodDelete)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req = req.WithContext(s3manager.ContextWithUser(req.Context(), tc.user))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+		})
+	}
+}
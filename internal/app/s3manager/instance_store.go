@@ -0,0 +1,39 @@
+package s3manager
+
+// InstanceStore persists the S3 instances registered at runtime.
+type InstanceStore interface {
+	// Load returns all stored instances.
+	Load() ([]S3InstanceConfig, error)
+	// Save replaces the stored instances with configs.
+	Save(configs []S3InstanceConfig) error
+}
+
+// FileInstanceStore is an InstanceStore that keeps the instances in a JSON
+// file. The file contains credentials and is only readable by its owner.
+type FileInstanceStore struct {
+	path string
+}
+
+// NewFileInstanceStore creates a FileInstanceStore backed by the file at path.
+// The file is created on the first Save.
+func NewFileInstanceStore(path string) *FileInstanceStore {
+	return &FileInstanceStore{path: path}
+}
+
+// Load implements InstanceStore.
+func (s *FileInstanceStore) Load() ([]S3InstanceConfig, error) {
+	var configs []S3InstanceConfig
+	if err := loadJSONFile(s.path, "instance store", &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// Save implements InstanceStore. The file is replaced atomically, so a failed
+// write never leaves a truncated store behind.
+func (s *FileInstanceStore) Save(configs []S3InstanceConfig) error {
+	if configs == nil {
+		configs = []S3InstanceConfig{}
+	}
+	return saveJSONFile(s.path, "instance store", configs)
+}
@@ -0,0 +1,61 @@
+package s3manager_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestFileInstanceStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("loads nothing if the file does not exist", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		store := s3manager.NewFileInstanceStore(filepath.Join(t.TempDir(), "instances.json"))
+		configs, err := store.Load()
+		is.NoErr(err)
+		is.Equal(0, len(configs))
+	})
+
+	t.Run("loads what was saved", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		path := filepath.Join(t.TempDir(), "instances.json")
+		store := s3manager.NewFileInstanceStore(path)
+		saved := []s3manager.S3InstanceConfig{{
+			Name:            "tenant-a",
+			Endpoint:        "minio.example.com:9000",
+			AccessKeyID:     "key",
+			SecretAccessKey: "secret",
+			UseSSL:          true,
+			SignatureType:   "V4",
+			DeniedBuckets:   []string{"*-secrets"},
+		}}
+		is.NoErr(store.Save(saved))
+
+		configs, err := store.Load()
+		is.NoErr(err)
+		is.Equal(saved, configs)
+
+		info, err := os.Stat(path)
+		is.NoErr(err)
+		is.Equal(os.FileMode(0o600), info.Mode().Perm()) // store is only readable by its owner
+	})
+
+	t.Run("returns error for a corrupt file", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		path := filepath.Join(t.TempDir(), "instances.json")
+		is.NoErr(os.WriteFile(path, []byte("{"), 0o600))
+
+		_, err := s3manager.NewFileInstanceStore(path).Load()
+		is.True(err != nil)
+	})
+}
@@ -0,0 +1,76 @@
+package s3manager
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// withJobs resolves the instance and authorizes the user like withInstance
+// and delegates to a handler that receives the instance and the jobs.
+func withJobs(manager *MultiS3Manager, required Role, fn func(*S3Instance, *jobRegistry) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		if !authorize(manager, w, r, current, mux.Vars(r)["bucketName"], required) {
+			return
+		}
+		fn(current, &manager.jobs)(w, r)
+	}
+}
+
+// HandleListJobsWithManager lists the jobs of a bucket that are running or
+// finished recently.
+func HandleListJobsWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withJobs(manager, RoleViewer, func(current *S3Instance, jobs *jobRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, jobs.list(current.Name, mux.Vars(r)["bucketName"]))
+		}
+	})
+}
+
+// HandleGetJobWithManager returns the progress of a job of a bucket.
+func HandleGetJobWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withJobs(manager, RoleViewer, func(current *S3Instance, jobs *jobRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			job, err := jobs.get(current.Name, mux.Vars(r)["bucketName"], mux.Vars(r)["jobId"])
+			if errors.Is(err, ErrJobNotFound) {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, job)
+		}
+	})
+}
+
+// HandleCancelJobWithManager cancels a job of a bucket. Objects the job
+// already worked on are not restored.
+func HandleCancelJobWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withJobs(manager, RoleViewer, func(current *S3Instance, jobs *jobRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			bucketName, id := mux.Vars(r)["bucketName"], mux.Vars(r)["jobId"]
+			job, err := jobs.get(current.Name, bucketName, id)
+			if errors.Is(err, ErrJobNotFound) {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
+			// Jobs that write to another bucket are canceled by the users
+			// who may write to it.
+			target := bucketName
+			if job.DestinationBucket != "" {
+				target = job.DestinationBucket
+			}
+			if !authorize(manager, w, r, current, target, RoleUploader) {
+				return
+			}
+			if err := jobs.cancel(current.Name, bucketName, id); err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
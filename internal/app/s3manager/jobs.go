@@ -0,0 +1,241 @@
+package s3manager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned for jobs that don't exist or belong to another
+// bucket.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobStatus is the state of a job.
+type JobStatus string
+
+// The states of a job. A job is running until it finished all objects, failed
+// on at least one of them or was canceled.
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+const (
+	// maxJobErrors is the number of failed objects a job reports in detail.
+	maxJobErrors = 100
+	// jobRetention is how long finished jobs are kept.
+	jobRetention = 24 * time.Hour
+)
+
+// Job is an operation on many objects of a bucket that runs in the
+// background. DestinationBucket is only set for jobs that write to another
+// bucket.
+type Job struct {
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	Instance          string     `json:"instance"`
+	Bucket            string     `json:"bucket"`
+	Source            string     `json:"source"`
+	DestinationBucket string     `json:"destination_bucket,omitempty"`
+	Destination       string     `json:"destination,omitempty"`
+	CreatedBy         string     `json:"created_by,omitempty"`
+	Status            JobStatus  `json:"status"`
+	Total             int        `json:"total"`
+	Done              int        `json:"done"`
+	Skipped           int        `json:"skipped"`
+	Failed            int        `json:"failed"`
+	Errors            []JobError `json:"errors,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	Started           time.Time  `json:"started"`
+	Finished          *time.Time `json:"finished,omitempty"`
+}
+
+// JobError is an object a job failed on.
+type JobError struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// jobRegistry holds the jobs of a MultiS3Manager in memory, so jobs don't
+// survive a restart. The zero value is ready to use.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs []*runningJob
+	// drainer runs the jobs, so they are waited for on shutdown.
+	drainer *Drainer
+}
+
+// runningJob is a job along with the function that cancels it. job is
+// guarded by the mutex of the registry.
+type runningJob struct {
+	job    Job
+	cancel context.CancelFunc
+}
+
+// jobProgress lets a job report its progress.
+type jobProgress struct {
+	registry *jobRegistry
+	job      *runningJob
+}
+
+// newJobID returns a random job ID.
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// start registers job and runs it in the background until run returns or the
+// job is canceled. The job fails if run returns an error or failed on an
+// object.
+func (r *jobRegistry) start(job Job, run func(ctx context.Context, progress *jobProgress) error) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	job.ID = id
+	job.Status = JobRunning
+	job.Started = time.Now().UTC()
+
+	r.mu.Lock()
+	drainer := r.drainer
+	ctx, cancel := context.WithCancel(drainer.Context())
+	running := &runningJob{job: job, cancel: cancel}
+	r.jobs = slices.DeleteFunc(r.jobs, func(j *runningJob) bool {
+		return j.job.Finished != nil && time.Since(*j.job.Finished) > jobRetention
+	})
+	r.jobs = append(r.jobs, running)
+	r.mu.Unlock()
+
+	drainer.Go(func() {
+		defer cancel()
+		err := run(ctx, &jobProgress{registry: r, job: running})
+		r.finish(running, ctx.Err(), err)
+	})
+	return job, nil
+}
+
+// finish records the outcome of job.
+func (r *jobRegistry) finish(job *runningJob, canceled, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	job.job.Finished = &now
+	switch {
+	case canceled != nil:
+		job.job.Status = JobCanceled
+	case err != nil:
+		job.job.Status = JobFailed
+		job.job.Error = err.Error()
+	case job.job.Failed > 0:
+		job.job.Status = JobFailed
+	default:
+		job.job.Status = JobSucceeded
+	}
+}
+
+// list returns the jobs of bucket on instance, oldest first.
+func (r *jobRegistry) list(instance, bucket string) []Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := []Job{}
+	for _, j := range r.jobs {
+		if j.job.Instance == instance && j.job.Bucket == bucket {
+			jobs = append(jobs, j.snapshot())
+		}
+	}
+	return jobs
+}
+
+// get returns the job with id of bucket on instance.
+func (r *jobRegistry) get(instance, bucket, id string) (Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j := r.find(instance, bucket, id)
+	if j == nil {
+		return Job{}, ErrJobNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// cancel stops the job with id of bucket on instance. Objects the job is
+// working on are finished first.
+func (r *jobRegistry) cancel(instance, bucket, id string) error {
+	r.mu.Lock()
+	j := r.find(instance, bucket, id)
+	r.mu.Unlock()
+
+	if j == nil {
+		return ErrJobNotFound
+	}
+	j.cancel()
+	return nil
+}
+
+// find returns the job with id of bucket on instance, or nil. r.mu must be
+// held.
+func (r *jobRegistry) find(instance, bucket, id string) *runningJob {
+	i := slices.IndexFunc(r.jobs, func(j *runningJob) bool {
+		return j.job.ID == id && j.job.Instance == instance && j.job.Bucket == bucket
+	})
+	if i < 0 {
+		return nil
+	}
+	return r.jobs[i]
+}
+
+// snapshot returns a copy of the job that is safe to use without holding the
+// mutex of the registry.
+func (j *runningJob) snapshot() Job {
+	job := j.job
+	job.Errors = slices.Clone(job.Errors)
+	return job
+}
+
+// add counts an object the job found to work on. Jobs stream their
+// listings, so the total grows while they run.
+func (p *jobProgress) add() {
+	p.registry.mu.Lock()
+	defer p.registry.mu.Unlock()
+
+	p.job.job.Total++
+}
+
+// done counts an object the job is finished with.
+func (p *jobProgress) done() {
+	p.registry.mu.Lock()
+	defer p.registry.mu.Unlock()
+
+	p.job.job.Done++
+}
+
+// skip counts an object the job left alone.
+func (p *jobProgress) skip() {
+	p.registry.mu.Lock()
+	defer p.registry.mu.Unlock()
+
+	p.job.job.Skipped++
+}
+
+// fail counts an object the job failed on.
+func (p *jobProgress) fail(key string, err error) {
+	p.registry.mu.Lock()
+	defer p.registry.mu.Unlock()
+
+	p.job.job.Failed++
+	if len(p.job.job.Errors) < maxJobErrors {
+		p.job.job.Errors = append(p.job.job.Errors, JobError{Key: key, Error: err.Error()})
+	}
+}
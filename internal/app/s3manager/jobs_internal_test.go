@@ -0,0 +1,117 @@
+package s3manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// waitForJob polls the job with id until it finished.
+func waitForJob(t *testing.T, jobs *jobRegistry, id string) Job {
+	t.Helper()
+	is := is.New(t)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := jobs.get("primary", "bucket", id)
+		is.NoErr(err)
+		if job.Status != JobRunning || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJobRegistry(t *testing.T) {
+	t.Parallel()
+
+	newJob := func() Job {
+		return Job{Type: "move", Instance: "primary", Bucket: "bucket", Source: "docs/"}
+	}
+
+	t.Run("reports failed objects", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var jobs jobRegistry
+		job, err := jobs.start(newJob(), func(_ context.Context, progress *jobProgress) error {
+			for range maxJobErrors + 2 {
+				progress.add()
+			}
+			progress.done()
+			for i := range maxJobErrors + 1 {
+				progress.fail(fmt.Sprintf("docs/%d", i), errors.New("access denied"))
+			}
+			return nil
+		})
+		is.NoErr(err)
+
+		job = waitForJob(t, &jobs, job.ID)
+		is.Equal(JobFailed, job.Status)
+		is.Equal(1, job.Done)
+		is.Equal(maxJobErrors+1, job.Failed)
+		is.Equal(maxJobErrors, len(job.Errors)) // errors are capped
+		is.Equal(JobError{Key: "docs/0", Error: "access denied"}, job.Errors[0])
+		is.Equal("", job.Error)
+	})
+
+	t.Run("fails if the job can't run", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var jobs jobRegistry
+		job, err := jobs.start(newJob(), func(context.Context, *jobProgress) error {
+			return errors.New("error listing objects")
+		})
+		is.NoErr(err)
+
+		job = waitForJob(t, &jobs, job.ID)
+		is.Equal(JobFailed, job.Status)
+		is.Equal("error listing objects", job.Error)
+	})
+
+	t.Run("cancels jobs", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var jobs jobRegistry
+		job, err := jobs.start(newJob(), func(ctx context.Context, _ *jobProgress) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		is.NoErr(err)
+
+		is.True(errors.Is(jobs.cancel("primary", "other", job.ID), ErrJobNotFound)) // other bucket
+		is.NoErr(jobs.cancel("primary", "bucket", job.ID))
+
+		job = waitForJob(t, &jobs, job.ID)
+		is.Equal(JobCanceled, job.Status)
+		is.True(job.Finished != nil)
+	})
+
+	t.Run("forgets old jobs", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var jobs jobRegistry
+		old, err := jobs.start(newJob(), func(context.Context, *jobProgress) error { return nil })
+		is.NoErr(err)
+		waitForJob(t, &jobs, old.ID)
+
+		jobs.mu.Lock()
+		finished := time.Now().Add(-jobRetention - time.Minute)
+		jobs.jobs[0].job.Finished = &finished
+		jobs.mu.Unlock()
+
+		current, err := jobs.start(newJob(), func(context.Context, *jobProgress) error { return nil })
+		is.NoErr(err)
+
+		listed := jobs.list("primary", "bucket")
+		is.Equal(1, len(listed))
+		is.Equal(current.ID, listed[0].ID)
+	})
+}
@@ -1,64 +1,137 @@
 package s3manager
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
 )
 
 // withInstance extracts the instance from the request, looks it up in the manager,
+// checks that the user holds at least the required role on the requested bucket
 // and delegates to a handler that receives the resolved S3 client.
-func withInstance(manager *MultiS3Manager, fn func(S3) http.HandlerFunc) http.HandlerFunc {
+func withInstance(manager *MultiS3Manager, required Role, fn func(S3) http.HandlerFunc) http.HandlerFunc {
+	return withInstanceFeatures(manager, required, func(s3 S3, _ Features) http.HandlerFunc { return fn(s3) })
+}
+
+// withInstanceFeatures is like withInstance, but also passes the features of
+// the resolved instance to the handler. Their SSE is that of the requested
+// bucket.
+func withInstanceFeatures(manager *MultiS3Manager, required Role, fn func(S3, Features) http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		instanceName := mux.Vars(r)["instance"]
-		current, err := manager.GetInstance(instanceName)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Instance not found: %s", err.Error()), http.StatusNotFound)
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		bucketName := mux.Vars(r)["bucketName"]
+		if !authorize(manager, w, r, current, bucketName, required) {
 			return
 		}
-		fn(current.Client)(w, r)
+		features := current.Features
+		features.SSE = features.SSEFor(bucketName)
+		fn(current.Client, features)(w, r)
+	}
+}
+
+// deleteDisabled responds to delete requests on instances that don't allow
+// deleting.
+func deleteDisabled(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, "Forbidden: deleting is disabled for this instance", http.StatusForbidden)
+}
+
+// resolveInstance looks up the instance named in the request. It responds with
+// 404 and returns false if there is no such instance.
+func resolveInstance(manager *MultiS3Manager, w http.ResponseWriter, r *http.Request) (*S3Instance, bool) {
+	instanceName := mux.Vars(r)["instance"]
+	current, err := manager.GetInstance(instanceName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Instance not found: %s", err.Error()), http.StatusNotFound)
+		return nil, false
+	}
+	return current, true
+}
+
+// authorize checks that bucket may be accessed on instance and that the user
+// holds at least the required role on it. It responds with 403 and returns
+// false if not.
+func authorize(manager *MultiS3Manager, w http.ResponseWriter, r *http.Request, instance *S3Instance, bucket string, required Role) bool {
+	if bucket != "" && !instance.Buckets.Allows(bucket) {
+		http.Error(w, fmt.Sprintf("Forbidden: access to bucket %s is not allowed", bucket), http.StatusForbidden)
+		return false
+	}
+	if manager.RoleFor(r, instance, bucket) < required {
+		http.Error(w, fmt.Sprintf("Forbidden: %s role required", required), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// peekBucketName reads the bucket name from a JSON request body without
+// consuming it.
+func peekBucketName(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading request body: %w", err)
 	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var bucket minio.BucketInfo
+	if err := json.Unmarshal(body, &bucket); err != nil {
+		return "", fmt.Errorf("error decoding body JSON: %w", err)
+	}
+	return bucket.Name, nil
 }
 
 // HandleBucketsViewWithManager renders all buckets on an HTML page using MultiS3Manager.
-func HandleBucketsViewWithManager(manager *MultiS3Manager, templates fs.FS, allowDelete bool, rootURL string, bucketName string) http.HandlerFunc {
+func HandleBucketsViewWithManager(manager *MultiS3Manager, templates fs.FS, rootURL string) http.HandlerFunc {
 	type pageData struct {
-		RootURL      string
-		Buckets      []any
-		AllowDelete  bool
-		CurrentS3    *S3Instance
-		S3Instances  []*S3Instance
-		HasError     bool
-		ErrorMessage string
+		RootURL           string
+		Buckets           []any
+		AllowDelete       bool
+		CanCreateBucket   bool
+		CanAddInstance    bool
+		CanRemoveInstance bool
+		CurrentS3         *S3Instance
+		S3Instances       []*S3Instance
+		HasError          bool
+		ErrorMessage      string
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		instanceName := vars["instance"]
-
-		current, err := manager.GetInstance(instanceName)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Instance not found: %s", err.Error()), http.StatusNotFound)
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		if !authorize(manager, w, r, current, "", RoleViewer) {
 			return
 		}
 
 		s3 := current.Client
-		instances := manager.GetAllInstances()
+		instances := manager.GetAccessibleInstances(r)
+
+		instanceAdmin := manager.InstanceRoleFor(r, current.Name) >= RoleAdmin
 
 		buckets, err := s3.ListBuckets(r.Context())
 
 		data := pageData{
-			RootURL:     rootURL,
-			AllowDelete: allowDelete,
-			CurrentS3:   current,
-			S3Instances: instances,
-			HasError:    false,
+			RootURL:           rootURL,
+			AllowDelete:       current.Features.AllowDelete,
+			CanCreateBucket:   manager.RoleFor(r, current, "") >= RoleAdmin,
+			CanAddInstance:    manager.GlobalRoleFor(r) >= RoleAdmin && manager.HasInstanceStore(),
+			CanRemoveInstance: instanceAdmin && manager.IsManaged(current),
+			CurrentS3:         current,
+			S3Instances:       instances,
+			HasError:          false,
 		}
 
 		if err != nil {
@@ -67,16 +140,13 @@ func HandleBucketsViewWithManager(manager *MultiS3Manager, templates fs.FS, allo
 			data.ErrorMessage = fmt.Sprintf("Unable to connect to S3 instance '%s'. Please check the credentials and try switching to another instance.", current.Name)
 			data.Buckets = make([]any, 0)
 		} else {
-			if bucketName != "" {
-				filtered := buckets[:0]
-				for _, b := range buckets {
-					if b.Name == bucketName {
-						filtered = append(filtered, b)
-						break
-					}
+			visible := buckets[:0]
+			for _, b := range buckets {
+				if current.Buckets.Allows(b.Name) && manager.RoleFor(r, current, b.Name) >= RoleViewer {
+					visible = append(visible, b)
 				}
-				buckets = filtered
 			}
+			buckets = visible
 			data.Buckets = make([]any, len(buckets))
 			for i, bucket := range buckets {
 				data.Buckets[i] = bucket
@@ -97,73 +167,132 @@ func HandleBucketsViewWithManager(manager *MultiS3Manager, templates fs.FS, allo
 }
 
 // HandleBucketViewWithManager shows the details page of a bucket using MultiS3Manager.
-func HandleBucketViewWithManager(manager *MultiS3Manager, templates fs.FS, allowDelete bool, listRecursive bool, rootURL string, showVersions bool, showMetadata bool) http.HandlerFunc {
+func HandleBucketViewWithManager(manager *MultiS3Manager, templates fs.FS, rootURL string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		instanceName := vars["instance"]
-
-		current, err := manager.GetInstance(instanceName)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Instance not found: %s", err.Error()), http.StatusNotFound)
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		bucketName := bucketNameFromViewPath(r.URL.Path)
+		if !authorize(manager, w, r, current, bucketName, RoleViewer) {
 			return
 		}
 
 		s3 := current.Client
-		instances := manager.GetAllInstances()
+		instances := manager.GetAccessibleInstances(r)
+		role := manager.RoleFor(r, current, bucketName)
 
 		// Create a modified handler that includes S3 instance data
-		handler := createBucketViewWithS3Data(s3, templates, allowDelete, listRecursive, rootURL, current, instances, showVersions, showMetadata)
+		features := current.Features
+		handler := createBucketViewWithS3Data(s3, templates, features.AllowDelete, features.ListRecursive, rootURL, current, instances, features.ShowVersions, features.ShowMetadata, role, manager.HasShareStore())
 		handler(w, r)
 	}
 }
 
 // HandleCreateBucketWithManager creates a new bucket using MultiS3Manager.
 func HandleCreateBucketWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleCreateBucket)
+	return func(w http.ResponseWriter, r *http.Request) {
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		// The bucket doesn't exist yet, so the name has to be taken from the body.
+		bucketName, err := peekBucketName(r)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		if !authorize(manager, w, r, current, bucketName, RoleAdmin) {
+			return
+		}
+		HandleCreateBucket(current.Client)(w, r)
+	}
 }
 
 // HandleDeleteBucketWithManager deletes a bucket using MultiS3Manager.
 func HandleDeleteBucketWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleDeleteBucket)
+	return withInstanceFeatures(manager, RoleAdmin, func(s3 S3, features Features) http.HandlerFunc {
+		if !features.AllowDelete {
+			return deleteDisabled
+		}
+		return HandleDeleteBucket(s3)
+	})
 }
 
 // HandleCreateObjectWithManager uploads a new object using MultiS3Manager.
-func HandleCreateObjectWithManager(manager *MultiS3Manager, sseInfo SSEType) http.HandlerFunc {
-	return withInstance(manager, func(s3 S3) http.HandlerFunc { return HandleCreateObject(s3, sseInfo) })
+func HandleCreateObjectWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleCreateObject(s3, features.SSE)
+	})
+}
+
+// HandleCreateFolderWithManager creates an empty folder using MultiS3Manager.
+func HandleCreateFolderWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleCreateFolder(s3, features.SSE)
+	})
 }
 
 // HandleGenerateURLWithManager generates a presigned URL using MultiS3Manager.
 func HandleGenerateURLWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleGenerateURL)
+	return withInstanceFeatures(manager, RoleViewer, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleGenerateURL(s3, features.SSE, features.MaxPresignExpiry, features.ShowVersions)
+	})
 }
 
 // HandleGetObjectWithManager downloads an object to the client using MultiS3Manager.
-func HandleGetObjectWithManager(manager *MultiS3Manager, forceDownload, showVersions bool) http.HandlerFunc {
-	return withInstance(manager, func(s3 S3) http.HandlerFunc { return HandleGetObject(s3, forceDownload, showVersions) })
+func HandleGetObjectWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleViewer, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleGetObject(s3, features.ForceDownload, features.ShowVersions, features.SSE)
+	})
 }
 
 // HandleDeleteObjectWithManager deletes an object using MultiS3Manager.
 func HandleDeleteObjectWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleDeleteObject)
+	return withInstanceFeatures(manager, RoleEditor, func(s3 S3, features Features) http.HandlerFunc {
+		if !features.AllowDelete {
+			return deleteDisabled
+		}
+		return HandleDeleteObject(s3)
+	})
 }
 
 // HandleCheckPublicAccessWithManager checks if an object is publicly accessible using MultiS3Manager.
 func HandleCheckPublicAccessWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleCheckPublicAccess)
+	return withInstance(manager, RoleViewer, HandleCheckPublicAccess)
 }
 
 // HandleGetObjectMetadataWithManager retrieves object metadata using MultiS3Manager.
 func HandleGetObjectMetadataWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleGetObjectMetadata)
+	return withInstanceFeatures(manager, RoleViewer, func(s3 S3, features Features) http.HandlerFunc {
+		if !features.ShowMetadata {
+			return http.NotFound
+		}
+		return HandleGetObjectMetadata(s3, features.ShowVersions, features.SSE)
+	})
+}
+
+// bucketViewPathRegex matches /{instance}/buckets/{bucketName}/{path}.
+var bucketViewPathRegex = regexp.MustCompile(`\/([^\/]+)\/buckets\/([^\/]*)\/?(.*)`)
+
+// bucketNameFromViewPath extracts the bucket name from a bucket view URL path.
+func bucketNameFromViewPath(urlPath string) string {
+	matches := bucketViewPathRegex.FindStringSubmatch(urlPath)
+	if len(matches) < 3 {
+		return ""
+	}
+	return matches[2]
 }
 
 // createBucketViewWithS3Data creates a bucket view handler that includes S3 instance data
-func createBucketViewWithS3Data(s3 S3, templates fs.FS, allowDelete bool, listRecursive bool, rootURL string, current *S3Instance, instances []*S3Instance, showVersions bool, showMetadata bool) http.HandlerFunc {
+func createBucketViewWithS3Data(s3 S3, templates fs.FS, allowDelete bool, listRecursive bool, rootURL string, current *S3Instance, instances []*S3Instance, showVersions bool, showMetadata bool, role Role, sharesEnabled bool) http.HandlerFunc {
 	type pageData struct {
 		RootURL             string
 		BucketName          string
 		Objects             []objectWithIcon
 		AllowDelete         bool
+		CanUpload           bool
+		CanManageBucket     bool
 		Paths               []string
 		CurrentPath         string
 		Endpoint            string
@@ -183,12 +312,12 @@ func createBucketViewWithS3Data(s3 S3, templates fs.FS, allowDelete bool, listRe
 		ShowVersions        bool
 		VersionsUnavailable bool
 		ShowMetadata        bool
+		MaxPresignExpiry    int
+		SharesEnabled       bool
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Updated regex to handle instance in the URL path
-		regex := regexp.MustCompile(`\/([^\/]+)\/buckets\/([^\/]*)\/?(.*)`)
-		matches := regex.FindStringSubmatch(r.URL.Path)
+		matches := bucketViewPathRegex.FindStringSubmatch(r.URL.Path)
 		if len(matches) < 3 {
 			handleHTTPError(w, fmt.Errorf("invalid URL path"))
 			return
@@ -285,7 +414,9 @@ func createBucketViewWithS3Data(s3 S3, templates fs.FS, allowDelete bool, listRe
 			RootURL:             rootURL,
 			BucketName:          bucketName,
 			Objects:             objs,
-			AllowDelete:         allowDelete,
+			AllowDelete:         allowDelete && role >= RoleEditor,
+			CanUpload:           role >= RoleUploader,
+			CanManageBucket:     role >= RoleAdmin,
 			Paths:               removeEmptyStrings(strings.Split(path, "/")),
 			CurrentPath:         path,
 			Endpoint:            s3.EndpointURL().String(),
@@ -305,6 +436,8 @@ func createBucketViewWithS3Data(s3 S3, templates fs.FS, allowDelete bool, listRe
 			ShowVersions:        versionsShown,
 			VersionsUnavailable: versionsUnavailable,
 			ShowMetadata:        showMetadata,
+			MaxPresignExpiry:    int(presignExpiryLimit(current.Features.MaxPresignExpiry) / time.Second),
+			SharesEnabled:       sharesEnabled,
 		}
 
 		funcMap := template.FuncMap{
@@ -341,20 +474,94 @@ func createBucketViewWithS3Data(s3 S3, templates fs.FS, allowDelete bool, listRe
 
 // HandleBulkDeleteObjectsWithManager deletes multiple objects using MultiS3Manager.
 func HandleBulkDeleteObjectsWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleBulkDeleteObjects)
+	return withInstanceFeatures(manager, RoleEditor, func(s3 S3, features Features) http.HandlerFunc {
+		if !features.AllowDelete {
+			return deleteDisabled
+		}
+		return HandleBulkDeleteObjects(s3)
+	})
 }
 
 // HandleGetBucketPolicyWithManager retrieves the policy for a bucket using MultiS3Manager.
 func HandleGetBucketPolicyWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleGetBucketPolicy)
+	return withInstance(manager, RoleAdmin, HandleGetBucketPolicy)
 }
 
 // HandlePutBucketPolicyWithManager sets the policy for a bucket using MultiS3Manager.
 func HandlePutBucketPolicyWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandlePutBucketPolicy)
+	return withInstance(manager, RoleAdmin, HandlePutBucketPolicy)
 }
 
 // HandleBulkDownloadObjectsWithManager downloads multiple objects as a ZIP using MultiS3Manager.
 func HandleBulkDownloadObjectsWithManager(manager *MultiS3Manager) http.HandlerFunc {
-	return withInstance(manager, HandleBulkDownloadObjects)
+	return withInstanceFeatures(manager, RoleViewer, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleBulkDownloadObjects(s3, features.SSE)
+	})
+}
+
+// HandleInitiateUploadWithManager starts a resumable upload using MultiS3Manager.
+func HandleInitiateUploadWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleInitiateUpload(s3, features.SSE)
+	})
+}
+
+// HandleListUploadPartsWithManager lists the stored parts of a resumable upload using MultiS3Manager.
+func HandleListUploadPartsWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstance(manager, RoleUploader, HandleListUploadParts)
+}
+
+// HandleUploadPartWithManager stores a part of a resumable upload using MultiS3Manager.
+func HandleUploadPartWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleUploadPart(s3, features.SSE)
+	})
+}
+
+// HandleCompleteUploadWithManager completes a resumable upload using MultiS3Manager.
+func HandleCompleteUploadWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleCompleteUpload(s3, features.SSE)
+	})
+}
+
+// HandleAbortUploadWithManager aborts a resumable upload using MultiS3Manager.
+// Aborting discards the uploaded parts of any user, so it is restricted like
+// aborting all incomplete uploads.
+func HandleAbortUploadWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleEditor, func(s3 S3, features Features) http.HandlerFunc {
+		if !features.AllowDelete {
+			return deleteDisabled
+		}
+		return HandleAbortUpload(s3)
+	})
+}
+
+// HandleListIncompleteUploadsWithManager lists the incomplete multipart uploads of a bucket using MultiS3Manager.
+func HandleListIncompleteUploadsWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstance(manager, RoleUploader, HandleListIncompleteUploads)
+}
+
+// HandleAbortIncompleteUploadsWithManager aborts old incomplete multipart uploads of a bucket using MultiS3Manager.
+func HandleAbortIncompleteUploadsWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleEditor, func(s3 S3, features Features) http.HandlerFunc {
+		if !features.AllowDelete {
+			return deleteDisabled
+		}
+		return HandleAbortIncompleteUploads(s3)
+	})
+}
+
+// HandleGenerateUploadURLWithManager generates a presigned upload URL using MultiS3Manager.
+func HandleGenerateUploadURLWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleGenerateUploadURL(s3, features.SSE, features.MaxPresignExpiry)
+	})
+}
+
+// HandleGenerateUploadPolicyWithManager generates a presigned POST policy using MultiS3Manager.
+func HandleGenerateUploadPolicyWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withInstanceFeatures(manager, RoleUploader, func(s3 S3, features Features) http.HandlerFunc {
+		return HandleGenerateUploadPolicy(s3, features.SSE, features.MaxPresignExpiry)
+	})
 }
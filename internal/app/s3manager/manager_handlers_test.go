@@ -17,6 +17,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/matryer/is"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
 )
 
 // newTestMultiS3Manager constructs a MultiS3Manager directly from S3Instance slices,
@@ -47,6 +48,9 @@ type stubS3 struct {
 	endpointURL        func() *url.URL
 	presignedGetObject func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error)
 	statObject         func(context.Context, string, string, minio.StatObjectOptions) (minio.ObjectInfo, error)
+	copyObject         func(context.Context, minio.CopyDestOptions, minio.CopySrcOptions) (minio.UploadInfo, error)
+	composeObject      func(context.Context, minio.CopyDestOptions, ...minio.CopySrcOptions) (minio.UploadInfo, error)
+	getObjectTagging   func(context.Context, string, string, minio.GetObjectTaggingOptions) (*tags.Tags, error)
 }
 
 func (s *stubS3) ListBuckets(ctx context.Context) ([]minio.BucketInfo, error) {
@@ -70,6 +74,9 @@ func (s *stubS3) SetBucketPolicy(ctx context.Context, bucket, policy string) err
 func (s *stubS3) RemoveObjects(ctx context.Context, bucket string, ch <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError {
 	return s.removeObjects(ctx, bucket, ch, opts)
 }
+func (s *stubS3) RemoveObjectsWithResult(_ context.Context, _ string, _ <-chan minio.ObjectInfo, _ minio.RemoveObjectsOptions) <-chan minio.RemoveObjectResult {
+	panic("RemoveObjectsWithResult not expected in this test")
+}
 func (s *stubS3) ListObjects(ctx context.Context, bucket string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
 	return s.listObjects(ctx, bucket, opts)
 }
@@ -94,6 +101,48 @@ func (s *stubS3) StatObject(ctx context.Context, bucket, object string, opts min
 	}
 	panic("StatObject not expected in this test")
 }
+func (s *stubS3) CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+	if s.copyObject != nil {
+		return s.copyObject(ctx, dst, src)
+	}
+	panic("CopyObject not expected in this test")
+}
+func (s *stubS3) ComposeObject(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error) {
+	if s.composeObject != nil {
+		return s.composeObject(ctx, dst, srcs...)
+	}
+	panic("ComposeObject not expected in this test")
+}
+func (s *stubS3) GetObjectTagging(ctx context.Context, bucket, object string, opts minio.GetObjectTaggingOptions) (*tags.Tags, error) {
+	if s.getObjectTagging != nil {
+		return s.getObjectTagging(ctx, bucket, object, opts)
+	}
+	panic("GetObjectTagging not expected in this test")
+}
+func (s *stubS3) PresignedPutObject(_ context.Context, _, _ string, _ time.Duration) (*url.URL, error) {
+	panic("PresignedPutObject not expected in this test")
+}
+func (s *stubS3) PresignedPostPolicy(_ context.Context, _ *minio.PostPolicy) (*url.URL, map[string]string, error) {
+	panic("PresignedPostPolicy not expected in this test")
+}
+func (s *stubS3) NewMultipartUpload(_ context.Context, _, _ string, _ minio.PutObjectOptions) (string, error) {
+	panic("NewMultipartUpload not expected in this test")
+}
+func (s *stubS3) PutObjectPart(_ context.Context, _, _, _ string, _ int, _ io.Reader, _ int64, _ minio.PutObjectPartOptions) (minio.ObjectPart, error) {
+	panic("PutObjectPart not expected in this test")
+}
+func (s *stubS3) ListObjectParts(_ context.Context, _, _, _ string, _, _ int) (minio.ListObjectPartsResult, error) {
+	panic("ListObjectParts not expected in this test")
+}
+func (s *stubS3) CompleteMultipartUpload(_ context.Context, _, _, _ string, _ []minio.CompletePart, _ minio.PutObjectOptions) (minio.UploadInfo, error) {
+	panic("CompleteMultipartUpload not expected in this test")
+}
+func (s *stubS3) AbortMultipartUpload(_ context.Context, _, _, _ string) error {
+	panic("AbortMultipartUpload not expected in this test")
+}
+func (s *stubS3) ListMultipartUploads(_ context.Context, _, _, _, _, _ string, _ int) (minio.ListMultipartUploadsResult, error) {
+	panic("ListMultipartUploads not expected in this test")
+}
 
 var errManagerTest = errors.New("manager test error")
 
@@ -149,7 +198,7 @@ func TestHandleBucketsViewWithManager(t *testing.T) {
 			})
 
 			r := mux.NewRouter()
-			r.Handle("/{instance}/buckets", HandleBucketsViewWithManager(manager, templates, true, "", "")).Methods(http.MethodGet)
+			r.Handle("/{instance}/buckets", HandleBucketsViewWithManager(manager, templates, "")).Methods(http.MethodGet)
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -272,7 +321,7 @@ func TestHandleDeleteBucketWithManager(t *testing.T) {
 
 			s3mock := &stubS3{removeBucket: tc.removeBucketFunc}
 			manager := newTestMultiS3Manager([]*S3Instance{
-				{ID: "1", Name: "primary", Client: s3mock},
+				{ID: "1", Name: "primary", Client: s3mock, Features: Features{AllowDelete: true}},
 			})
 
 			r := mux.NewRouter()
@@ -335,7 +384,7 @@ func TestHandleDeleteObjectWithManager(t *testing.T) {
 
 			s3mock := &stubS3{removeObject: tc.removeObjectFunc}
 			manager := newTestMultiS3Manager([]*S3Instance{
-				{ID: "1", Name: "primary", Client: s3mock},
+				{ID: "1", Name: "primary", Client: s3mock, Features: Features{AllowDelete: true}},
 			})
 
 			r := mux.NewRouter()
@@ -541,7 +590,7 @@ func TestHandleBulkDeleteObjectsWithManager(t *testing.T) {
 
 			s3mock := &stubS3{removeObjects: tc.removeObjectsFunc}
 			manager := newTestMultiS3Manager([]*S3Instance{
-				{ID: "1", Name: "primary", Client: s3mock},
+				{ID: "1", Name: "primary", Client: s3mock, Features: Features{AllowDelete: true}},
 			})
 
 			r := mux.NewRouter()
@@ -605,6 +654,7 @@ func TestHandleGenerateURLWithManager(t *testing.T) {
 		it                     string
 		instanceName           string
 		presignedGetObjectFunc func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error)
+		maxPresignExpiry       time.Duration
 		expectedStatusCode     int
 		expectedBodyContains   string
 	}{
@@ -617,6 +667,16 @@ func TestHandleGenerateURLWithManager(t *testing.T) {
 			expectedStatusCode:   http.StatusNotFound,
 			expectedBodyContains: "Instance not found",
 		},
+		{
+			it:           "enforces the instance's maximum expiry",
+			instanceName: "1",
+			presignedGetObjectFunc: func(context.Context, string, string, time.Duration, url.Values) (*url.URL, error) {
+				return presignedURL, nil
+			},
+			maxPresignExpiry:     time.Minute,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "invalid expiry value",
+		},
 		{
 			it:           "generates URL via valid instance",
 			instanceName: "1",
@@ -636,7 +696,7 @@ func TestHandleGenerateURLWithManager(t *testing.T) {
 			s3mock := &stubS3{}
 			s3mock.presignedGetObject = tc.presignedGetObjectFunc
 			manager := newTestMultiS3Manager([]*S3Instance{
-				{ID: "1", Name: "primary", Client: s3mock},
+				{ID: "1", Name: "primary", Client: s3mock, Features: Features{MaxPresignExpiry: tc.maxPresignExpiry}},
 			})
 
 			r := mux.NewRouter()
@@ -755,7 +815,7 @@ func TestHandleGetObjectMetadataWithManager(t *testing.T) {
 				},
 			}
 			manager := newTestMultiS3Manager([]*S3Instance{
-				{ID: "1", Name: "primary", Client: s3mock},
+				{ID: "1", Name: "primary", Client: s3mock, Features: Features{ShowMetadata: true}},
 			})
 
 			r := mux.NewRouter()
@@ -779,6 +839,65 @@ func TestHandleGetObjectMetadataWithManager(t *testing.T) {
 	}
 }
 
+func TestHandleGetObjectMetadataWithManagerBucketSSE(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it          string
+		bucketName  string
+		expectedSSE string
+	}{
+		{
+			it:          "sends the SSE-C key of a matching bucket",
+			bucketName:  "secure-bucket",
+			expectedSSE: "SSE-C",
+		},
+		{
+			it:          "sends no key for other buckets",
+			bucketName:  "test-bucket",
+			expectedSSE: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			var sse string
+			s3mock := &stubS3{
+				statObject: func(_ context.Context, _, _ string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+					if opts.ServerSideEncryption != nil {
+						sse = string(opts.ServerSideEncryption.Type())
+					}
+					return minio.ObjectInfo{Key: "test-object"}, nil
+				},
+			}
+			manager := newTestMultiS3Manager([]*S3Instance{
+				{ID: "1", Name: "primary", Client: s3mock, Features: Features{
+					ShowMetadata: true,
+					BucketSSE: []BucketSSE{
+						{Buckets: []string{"secure-*"}, SSE: SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"}},
+					},
+				}},
+			})
+
+			r := mux.NewRouter()
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/metadata", HandleGetObjectMetadataWithManager(manager))
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL + "/primary/api/buckets/" + tc.bucketName + "/objects/test-object/metadata")
+			is.NoErr(err)
+			is.NoErr(resp.Body.Close())
+
+			is.Equal(http.StatusOK, resp.StatusCode)
+			is.Equal(tc.expectedSSE, sse)
+		})
+	}
+}
+
 func TestHandleGetObjectWithManager(t *testing.T) {
 	t.Parallel()
 	is := is.New(t)
@@ -788,7 +907,7 @@ func TestHandleGetObjectWithManager(t *testing.T) {
 	})
 
 	r := mux.NewRouter()
-	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName}", HandleGetObjectWithManager(manager, true, false)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName}", HandleGetObjectWithManager(manager)).Methods(http.MethodGet)
 
 	ts := httptest.NewServer(r)
 	defer ts.Close()
@@ -815,7 +934,7 @@ func TestHandleCreateObjectWithManager(t *testing.T) {
 	})
 
 	r := mux.NewRouter()
-	r.Handle("/{instance}/api/buckets/{bucketName}/objects", HandleCreateObjectWithManager(manager, SSEType{})).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects", HandleCreateObjectWithManager(manager)).Methods(http.MethodPost)
 
 	ts := httptest.NewServer(r)
 	defer ts.Close()
@@ -1049,11 +1168,16 @@ func TestHandleBucketViewWithManager(t *testing.T) {
 			templates := os.DirFS(filepath.Join("..", "..", "..", "web", "template"))
 
 			manager := newTestMultiS3Manager([]*S3Instance{
-				{ID: "1", Name: "primary", Client: tc.client},
+				{ID: "1", Name: "primary", Client: tc.client, Features: Features{
+					AllowDelete:   true,
+					ListRecursive: true,
+					ShowVersions:  tc.showVersions,
+					ShowMetadata:  tc.showMetadata,
+				}},
 			})
 
 			r := mux.NewRouter()
-			r.PathPrefix("/{instance}/buckets/").Handler(HandleBucketViewWithManager(manager, templates, true, true, "", tc.showVersions, tc.showMetadata)).Methods(http.MethodGet)
+			r.PathPrefix("/{instance}/buckets/").Handler(HandleBucketViewWithManager(manager, templates, "")).Methods(http.MethodGet)
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -1077,3 +1201,483 @@ func TestHandleBucketViewWithManager(t *testing.T) {
 		})
 	}
 }
+
+// withTestUser injects user into every request so handlers see it as authenticated.
+func withTestUser(user *User, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), user)))
+	})
+}
+
+func TestManagerHandlersEnforceRoles(t *testing.T) {
+	t.Parallel()
+
+	templates := os.DirFS(filepath.Join("..", "..", "..", "web", "template"))
+
+	policy, err := NewAccessPolicy([]RoleBinding{
+		{Users: []string{"viewer"}, Role: "viewer", Instance: "primary"},
+		{Users: []string{"editor"}, Role: "editor", Instance: "primary", Bucket: "team-*"},
+		{Users: []string{"admin"}, Role: "admin", Instance: "primary", Bucket: "team-*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newClient := func() *stubS3 {
+		return &stubS3{
+			listBuckets: func(context.Context) ([]minio.BucketInfo, error) {
+				return []minio.BucketInfo{{Name: "team-bucket"}, {Name: "other-bucket"}}, nil
+			},
+			makeBucket:   func(context.Context, string, minio.MakeBucketOptions) error { return nil },
+			removeBucket: func(context.Context, string) error { return nil },
+			removeObject: func(context.Context, string, string, minio.RemoveObjectOptions) error { return nil },
+			listObjects: func(context.Context, string, minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+				ch := make(chan minio.ObjectInfo)
+				close(ch)
+				return ch
+			},
+			endpointURL: func() *url.URL { u, _ := url.Parse("http://localhost:9000"); return u },
+		}
+	}
+
+	cases := []struct {
+		it                   string
+		user                 string
+		method               string
+		path                 string
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains []string
+		unexpectedInBody     []string
+	}{
+		{
+			it:                   "forbids users without a role on the instance",
+			user:                 "nobody",
+			method:               http.MethodGet,
+			path:                 "/primary/buckets",
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: []string{"viewer role required"},
+		},
+		{
+			it:                   "lets viewers list buckets but not create them",
+			user:                 "viewer",
+			method:               http.MethodGet,
+			path:                 "/primary/buckets",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: []string{"team-bucket", "other-bucket"},
+			unexpectedInBody:     []string{"modal-create-bucket\">"},
+		},
+		{
+			it:                   "only lists buckets the user holds a role on",
+			user:                 "editor",
+			method:               http.MethodGet,
+			path:                 "/primary/buckets",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: []string{"team-bucket"},
+			unexpectedInBody:     []string{"other-bucket"},
+		},
+		{
+			it:                 "forbids viewing buckets outside the user's scope",
+			user:               "editor",
+			method:             http.MethodGet,
+			path:               "/primary/buckets/other-bucket/",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "hides upload and bucket management from viewers",
+			user:               "viewer",
+			method:             http.MethodGet,
+			path:               "/primary/buckets/team-bucket/",
+			expectedStatusCode: http.StatusOK,
+			unexpectedInBody:   []string{"upload-file-btn\"", "Edit Policy"},
+		},
+		{
+			it:                   "shows upload but not bucket management to editors",
+			user:                 "editor",
+			method:               http.MethodGet,
+			path:                 "/primary/buckets/team-bucket/",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: []string{"upload-file-btn\""},
+			unexpectedInBody:     []string{"Edit Policy"},
+		},
+		{
+			it:                 "forbids viewers to delete objects",
+			user:               "viewer",
+			method:             http.MethodDelete,
+			path:               "/primary/api/buckets/team-bucket/objects/file",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids viewers to start resumable uploads",
+			user:               "viewer",
+			method:             http.MethodPost,
+			path:               "/primary/api/buckets/team-bucket/uploads",
+			body:               `{"path":"disk.img"}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids viewers to abort incomplete uploads",
+			user:               "viewer",
+			method:             http.MethodDelete,
+			path:               "/primary/api/buckets/team-bucket/uploads?older_than=0",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "lets editors delete objects",
+			user:               "editor",
+			method:             http.MethodDelete,
+			path:               "/primary/api/buckets/team-bucket/objects/file",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			it:                 "forbids editors to delete buckets",
+			user:               "editor",
+			method:             http.MethodDelete,
+			path:               "/primary/api/buckets/team-bucket",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "lets admins create buckets matching their scope",
+			user:               "admin",
+			method:             http.MethodPost,
+			path:               "/primary/api/buckets",
+			body:               `{"name":"team-new"}`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			it:                 "forbids admins to create buckets outside their scope",
+			user:               "admin",
+			method:             http.MethodPost,
+			path:               "/primary/api/buckets",
+			body:               `{"name":"other-new"}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			manager := newTestMultiS3Manager([]*S3Instance{
+				{ID: "1", Name: "primary", Client: newClient(), Features: Features{AllowDelete: true}},
+			})
+			manager.SetAccessPolicy(policy)
+
+			r := mux.NewRouter()
+			r.Handle("/{instance}/buckets", HandleBucketsViewWithManager(manager, templates, "")).Methods(http.MethodGet)
+			r.PathPrefix("/{instance}/buckets/").Handler(HandleBucketViewWithManager(manager, templates, "")).Methods(http.MethodGet)
+			r.Handle("/{instance}/api/buckets", HandleCreateBucketWithManager(manager)).Methods(http.MethodPost)
+			r.Handle("/{instance}/api/buckets/{bucketName}", HandleDeleteBucketWithManager(manager)).Methods(http.MethodDelete)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", HandleDeleteObjectWithManager(manager)).Methods(http.MethodDelete)
+			r.Handle("/{instance}/api/buckets/{bucketName}/uploads", HandleInitiateUploadWithManager(manager)).Methods(http.MethodPost)
+			r.Handle("/{instance}/api/buckets/{bucketName}/uploads", HandleAbortIncompleteUploadsWithManager(manager)).Methods(http.MethodDelete)
+
+			ts := httptest.NewServer(withTestUser(&User{Name: tc.user}, r))
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			is.NoErr(err)
+			resp, err := http.DefaultClient.Do(req)
+			is.NoErr(err)
+			defer func() {
+				err = resp.Body.Close()
+				is.NoErr(err)
+			}()
+			body, err := io.ReadAll(resp.Body)
+			is.NoErr(err)
+
+			is.Equal(tc.expectedStatusCode, resp.StatusCode)
+			for _, expected := range tc.expectedBodyContains {
+				is.True(strings.Contains(string(body), expected))
+			}
+			for _, unexpected := range tc.unexpectedInBody {
+				is.True(!strings.Contains(string(body), unexpected))
+			}
+		})
+	}
+}
+
+func TestManagerHandlersEnforceBucketFilter(t *testing.T) {
+	t.Parallel()
+
+	templates := os.DirFS(filepath.Join("..", "..", "..", "web", "template"))
+
+	cases := []struct {
+		it                   string
+		method               string
+		path                 string
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains []string
+		unexpectedInBody     []string
+	}{
+		{
+			it:                   "only lists allowed buckets",
+			method:               http.MethodGet,
+			path:                 "/primary/buckets",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: []string{"team-bucket"},
+			unexpectedInBody:     []string{"other-bucket", "team-secret"},
+		},
+		{
+			it:                 "allows viewing an allowed bucket",
+			method:             http.MethodGet,
+			path:               "/primary/buckets/team-bucket/",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			it:                   "forbids viewing a bucket that is not allowed",
+			method:               http.MethodGet,
+			path:                 "/primary/buckets/other-bucket/",
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: []string{"access to bucket other-bucket is not allowed"},
+		},
+		{
+			it:                 "forbids viewing a denied bucket",
+			method:             http.MethodGet,
+			path:               "/primary/buckets/team-secret/",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids downloading from a bucket that is not allowed",
+			method:             http.MethodGet,
+			path:               "/primary/api/buckets/other-bucket/objects/file",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids deleting objects from a bucket that is not allowed",
+			method:             http.MethodDelete,
+			path:               "/primary/api/buckets/other-bucket/objects/file",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids bulk deleting from a bucket that is not allowed",
+			method:             http.MethodPost,
+			path:               "/primary/api/buckets/other-bucket/objects/bulk-delete",
+			body:               `{"keys":["file"]}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids reading the policy of a bucket that is not allowed",
+			method:             http.MethodGet,
+			path:               "/primary/api/buckets/other-bucket/policy",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids deleting a bucket that is not allowed",
+			method:             http.MethodDelete,
+			path:               "/primary/api/buckets/other-bucket",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "allows creating an allowed bucket",
+			method:             http.MethodPost,
+			path:               "/primary/api/buckets",
+			body:               `{"name":"team-new"}`,
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			it:                 "forbids creating a bucket that is not allowed",
+			method:             http.MethodPost,
+			path:               "/primary/api/buckets",
+			body:               `{"name":"other-new"}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3mock := &stubS3{
+				listBuckets: func(context.Context) ([]minio.BucketInfo, error) {
+					return []minio.BucketInfo{{Name: "team-bucket"}, {Name: "team-secret"}, {Name: "other-bucket"}}, nil
+				},
+				makeBucket:   func(context.Context, string, minio.MakeBucketOptions) error { return nil },
+				removeBucket: func(context.Context, string) error { return nil },
+				listObjects: func(context.Context, string, minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+					ch := make(chan minio.ObjectInfo)
+					close(ch)
+					return ch
+				},
+				endpointURL: func() *url.URL { u, _ := url.Parse("http://localhost:9000"); return u },
+			}
+			manager := newTestMultiS3Manager([]*S3Instance{
+				{
+					ID:       "1",
+					Name:     "primary",
+					Client:   s3mock,
+					Buckets:  BucketFilter{Allow: []string{"team-*"}, Deny: []string{"team-secret"}},
+					Features: Features{AllowDelete: true},
+				},
+			})
+
+			r := mux.NewRouter()
+			r.Handle("/{instance}/buckets", HandleBucketsViewWithManager(manager, templates, "")).Methods(http.MethodGet)
+			r.PathPrefix("/{instance}/buckets/").Handler(HandleBucketViewWithManager(manager, templates, "")).Methods(http.MethodGet)
+			r.Handle("/{instance}/api/buckets", HandleCreateBucketWithManager(manager)).Methods(http.MethodPost)
+			r.Handle("/{instance}/api/buckets/{bucketName}", HandleDeleteBucketWithManager(manager)).Methods(http.MethodDelete)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/bulk-delete", HandleBulkDeleteObjectsWithManager(manager)).Methods(http.MethodPost)
+			r.Handle("/{instance}/api/buckets/{bucketName}/policy", HandleGetBucketPolicyWithManager(manager)).Methods(http.MethodGet)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", HandleGetObjectWithManager(manager)).Methods(http.MethodGet)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", HandleDeleteObjectWithManager(manager)).Methods(http.MethodDelete)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			is.NoErr(err)
+			resp, err := http.DefaultClient.Do(req)
+			is.NoErr(err)
+			defer func() {
+				err = resp.Body.Close()
+				is.NoErr(err)
+			}()
+			body, err := io.ReadAll(resp.Body)
+			is.NoErr(err)
+
+			is.Equal(tc.expectedStatusCode, resp.StatusCode)
+			for _, expected := range tc.expectedBodyContains {
+				is.True(strings.Contains(string(body), expected))
+			}
+			for _, unexpected := range tc.unexpectedInBody {
+				is.True(!strings.Contains(string(body), unexpected))
+			}
+		})
+	}
+}
+
+func TestManagerHandlersEnforceFeatures(t *testing.T) {
+	t.Parallel()
+
+	templates := os.DirFS(filepath.Join("..", "..", "..", "web", "template"))
+
+	cases := []struct {
+		it                   string
+		method               string
+		path                 string
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains []string
+		unexpectedInBody     []string
+	}{
+		{
+			it:                   "forbids deleting objects on a read-only instance",
+			method:               http.MethodDelete,
+			path:                 "/prod/api/buckets/bucket/objects/file",
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: []string{"deleting is disabled for this instance"},
+		},
+		{
+			it:                 "allows deleting objects on a writable instance",
+			method:             http.MethodDelete,
+			path:               "/dev/api/buckets/bucket/objects/file",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			it:                 "forbids bulk deleting on a read-only instance",
+			method:             http.MethodPost,
+			path:               "/prod/api/buckets/bucket/objects/bulk-delete",
+			body:               `{"keys":["file"]}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids aborting an upload on a read-only instance",
+			method:             http.MethodDelete,
+			path:               "/prod/api/buckets/bucket/uploads/UPLOAD-ID?path=file",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "forbids deleting buckets on a read-only instance",
+			method:             http.MethodDelete,
+			path:               "/prod/api/buckets/bucket",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			it:                 "hides metadata on instances that don't show it",
+			method:             http.MethodGet,
+			path:               "/prod/api/buckets/bucket/objects/file/metadata",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			it:                 "shows metadata on instances that show it",
+			method:             http.MethodGet,
+			path:               "/dev/api/buckets/bucket/objects/file/metadata",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			it:                 "renders no delete controls for a read-only instance",
+			method:             http.MethodGet,
+			path:               "/prod/buckets/bucket/",
+			expectedStatusCode: http.StatusOK,
+			unexpectedInBody:   []string{"Delete Selected", `onclick="deleteObject(`},
+		},
+		{
+			it:                   "renders delete controls for a writable instance",
+			method:               http.MethodGet,
+			path:                 "/dev/buckets/bucket/",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: []string{"Delete Selected", `onclick="deleteObject(`},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			newClient := func() S3 {
+				return &stubS3{
+					removeBucket: func(context.Context, string) error { return nil },
+					removeObject: func(context.Context, string, string, minio.RemoveObjectOptions) error { return nil },
+					listObjects: func(context.Context, string, minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+						ch := make(chan minio.ObjectInfo, 1)
+						ch <- minio.ObjectInfo{Key: "file"}
+						close(ch)
+						return ch
+					},
+					statObject: func(context.Context, string, string, minio.StatObjectOptions) (minio.ObjectInfo, error) {
+						return minio.ObjectInfo{Key: "file"}, nil
+					},
+					endpointURL: func() *url.URL { u, _ := url.Parse("http://localhost:9000"); return u },
+				}
+			}
+			manager := newTestMultiS3Manager([]*S3Instance{
+				{ID: "1", Name: "prod", Client: newClient()},
+				{ID: "2", Name: "dev", Client: newClient(), Features: Features{AllowDelete: true, ShowMetadata: true}},
+			})
+
+			r := mux.NewRouter()
+			r.PathPrefix("/{instance}/buckets/").Handler(HandleBucketViewWithManager(manager, templates, "")).Methods(http.MethodGet)
+			r.Handle("/{instance}/api/buckets/{bucketName}", HandleDeleteBucketWithManager(manager)).Methods(http.MethodDelete)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/bulk-delete", HandleBulkDeleteObjectsWithManager(manager)).Methods(http.MethodPost)
+			r.Handle("/{instance}/api/buckets/{bucketName}/uploads/{uploadId}", HandleAbortUploadWithManager(manager)).Methods(http.MethodDelete)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/metadata", HandleGetObjectMetadataWithManager(manager)).Methods(http.MethodGet)
+			r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", HandleDeleteObjectWithManager(manager)).Methods(http.MethodDelete)
+
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, strings.NewReader(tc.body))
+			is.NoErr(err)
+			resp, err := http.DefaultClient.Do(req)
+			is.NoErr(err)
+			defer func() {
+				err = resp.Body.Close()
+				is.NoErr(err)
+			}()
+			body, err := io.ReadAll(resp.Body)
+			is.NoErr(err)
+
+			is.Equal(tc.expectedStatusCode, resp.StatusCode)
+			for _, expected := range tc.expectedBodyContains {
+				is.True(strings.Contains(string(body), expected))
+			}
+			for _, unexpected := range tc.unexpectedInBody {
+				is.True(!strings.Contains(string(body), unexpected))
+			}
+		})
+	}
+}
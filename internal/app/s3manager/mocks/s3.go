@@ -7,6 +7,7 @@ import (
 	"context"
 	"github.com/cloudlena/s3manager/internal/app/s3manager"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
 	"io"
 	"net/url"
 	"sync"
@@ -23,6 +24,18 @@ var _ s3manager.S3 = &S3Mock{}
 //
 //		// make and configure a mocked s3manager.S3
 //		mockedS3 := &S3Mock{
+//			AbortMultipartUploadFunc: func(ctx context.Context, bucket string, object string, uploadID string) error {
+//				panic("mock out the AbortMultipartUpload method")
+//			},
+//			CompleteMultipartUploadFunc: func(ctx context.Context, bucket string, object string, uploadID string, parts []minio.CompletePart, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+//				panic("mock out the CompleteMultipartUpload method")
+//			},
+//			ComposeObjectFunc: func(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error) {
+//				panic("mock out the ComposeObject method")
+//			},
+//			CopyObjectFunc: func(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+//				panic("mock out the CopyObject method")
+//			},
 //			EndpointURLFunc: func() *url.URL {
 //				panic("mock out the EndpointURL method")
 //			},
@@ -32,21 +45,42 @@ var _ s3manager.S3 = &S3Mock{}
 //			GetObjectFunc: func(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*minio.Object, error) {
 //				panic("mock out the GetObject method")
 //			},
+//			GetObjectTaggingFunc: func(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectTaggingOptions) (*tags.Tags, error) {
+//				panic("mock out the GetObjectTagging method")
+//			},
 //			ListBucketsFunc: func(ctx context.Context) ([]minio.BucketInfo, error) {
 //				panic("mock out the ListBuckets method")
 //			},
+//			ListMultipartUploadsFunc: func(ctx context.Context, bucket string, prefix string, keyMarker string, uploadIDMarker string, delimiter string, maxUploads int) (minio.ListMultipartUploadsResult, error) {
+//				panic("mock out the ListMultipartUploads method")
+//			},
+//			ListObjectPartsFunc: func(ctx context.Context, bucket string, object string, uploadID string, partNumberMarker int, maxParts int) (minio.ListObjectPartsResult, error) {
+//				panic("mock out the ListObjectParts method")
+//			},
 //			ListObjectsFunc: func(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
 //				panic("mock out the ListObjects method")
 //			},
 //			MakeBucketFunc: func(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error {
 //				panic("mock out the MakeBucket method")
 //			},
+//			NewMultipartUploadFunc: func(ctx context.Context, bucket string, object string, opts minio.PutObjectOptions) (string, error) {
+//				panic("mock out the NewMultipartUpload method")
+//			},
 //			PresignedGetObjectFunc: func(ctx context.Context, bucketName string, objectName string, expiry time.Duration, reqParams url.Values) (*url.URL, error) {
 //				panic("mock out the PresignedGetObject method")
 //			},
+//			PresignedPostPolicyFunc: func(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error) {
+//				panic("mock out the PresignedPostPolicy method")
+//			},
+//			PresignedPutObjectFunc: func(ctx context.Context, bucketName string, objectName string, expiry time.Duration) (*url.URL, error) {
+//				panic("mock out the PresignedPutObject method")
+//			},
 //			PutObjectFunc: func(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
 //				panic("mock out the PutObject method")
 //			},
+//			PutObjectPartFunc: func(ctx context.Context, bucket string, object string, uploadID string, partID int, data io.Reader, size int64, opts minio.PutObjectPartOptions) (minio.ObjectPart, error) {
+//				panic("mock out the PutObjectPart method")
+//			},
 //			RemoveBucketFunc: func(ctx context.Context, bucketName string) error {
 //				panic("mock out the RemoveBucket method")
 //			},
@@ -56,6 +90,9 @@ var _ s3manager.S3 = &S3Mock{}
 //			RemoveObjectsFunc: func(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError {
 //				panic("mock out the RemoveObjects method")
 //			},
+//			RemoveObjectsWithResultFunc: func(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectResult {
+//				panic("mock out the RemoveObjectsWithResult method")
+//			},
 //			SetBucketPolicyFunc: func(ctx context.Context, bucketName string, policy string) error {
 //				panic("mock out the SetBucketPolicy method")
 //			},
@@ -69,6 +106,18 @@ var _ s3manager.S3 = &S3Mock{}
 //
 //	}
 type S3Mock struct {
+	// AbortMultipartUploadFunc mocks the AbortMultipartUpload method.
+	AbortMultipartUploadFunc func(ctx context.Context, bucket string, object string, uploadID string) error
+
+	// CompleteMultipartUploadFunc mocks the CompleteMultipartUpload method.
+	CompleteMultipartUploadFunc func(ctx context.Context, bucket string, object string, uploadID string, parts []minio.CompletePart, opts minio.PutObjectOptions) (minio.UploadInfo, error)
+
+	// ComposeObjectFunc mocks the ComposeObject method.
+	ComposeObjectFunc func(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error)
+
+	// CopyObjectFunc mocks the CopyObject method.
+	CopyObjectFunc func(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error)
+
 	// EndpointURLFunc mocks the EndpointURL method.
 	EndpointURLFunc func() *url.URL
 
@@ -78,21 +127,42 @@ type S3Mock struct {
 	// GetObjectFunc mocks the GetObject method.
 	GetObjectFunc func(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*minio.Object, error)
 
+	// GetObjectTaggingFunc mocks the GetObjectTagging method.
+	GetObjectTaggingFunc func(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectTaggingOptions) (*tags.Tags, error)
+
 	// ListBucketsFunc mocks the ListBuckets method.
 	ListBucketsFunc func(ctx context.Context) ([]minio.BucketInfo, error)
 
+	// ListMultipartUploadsFunc mocks the ListMultipartUploads method.
+	ListMultipartUploadsFunc func(ctx context.Context, bucket string, prefix string, keyMarker string, uploadIDMarker string, delimiter string, maxUploads int) (minio.ListMultipartUploadsResult, error)
+
+	// ListObjectPartsFunc mocks the ListObjectParts method.
+	ListObjectPartsFunc func(ctx context.Context, bucket string, object string, uploadID string, partNumberMarker int, maxParts int) (minio.ListObjectPartsResult, error)
+
 	// ListObjectsFunc mocks the ListObjects method.
 	ListObjectsFunc func(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
 
 	// MakeBucketFunc mocks the MakeBucket method.
 	MakeBucketFunc func(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error
 
+	// NewMultipartUploadFunc mocks the NewMultipartUpload method.
+	NewMultipartUploadFunc func(ctx context.Context, bucket string, object string, opts minio.PutObjectOptions) (string, error)
+
 	// PresignedGetObjectFunc mocks the PresignedGetObject method.
 	PresignedGetObjectFunc func(ctx context.Context, bucketName string, objectName string, expiry time.Duration, reqParams url.Values) (*url.URL, error)
 
+	// PresignedPostPolicyFunc mocks the PresignedPostPolicy method.
+	PresignedPostPolicyFunc func(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error)
+
+	// PresignedPutObjectFunc mocks the PresignedPutObject method.
+	PresignedPutObjectFunc func(ctx context.Context, bucketName string, objectName string, expiry time.Duration) (*url.URL, error)
+
 	// PutObjectFunc mocks the PutObject method.
 	PutObjectFunc func(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error)
 
+	// PutObjectPartFunc mocks the PutObjectPart method.
+	PutObjectPartFunc func(ctx context.Context, bucket string, object string, uploadID string, partID int, data io.Reader, size int64, opts minio.PutObjectPartOptions) (minio.ObjectPart, error)
+
 	// RemoveBucketFunc mocks the RemoveBucket method.
 	RemoveBucketFunc func(ctx context.Context, bucketName string) error
 
@@ -102,6 +172,9 @@ type S3Mock struct {
 	// RemoveObjectsFunc mocks the RemoveObjects method.
 	RemoveObjectsFunc func(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError
 
+	// RemoveObjectsWithResultFunc mocks the RemoveObjectsWithResult method.
+	RemoveObjectsWithResultFunc func(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectResult
+
 	// SetBucketPolicyFunc mocks the SetBucketPolicy method.
 	SetBucketPolicyFunc func(ctx context.Context, bucketName string, policy string) error
 
@@ -110,6 +183,50 @@ type S3Mock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// AbortMultipartUpload holds details about calls to the AbortMultipartUpload method.
+		AbortMultipartUpload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Object is the object argument value.
+			Object string
+			// UploadID is the uploadID argument value.
+			UploadID string
+		}
+		// CompleteMultipartUpload holds details about calls to the CompleteMultipartUpload method.
+		CompleteMultipartUpload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Object is the object argument value.
+			Object string
+			// UploadID is the uploadID argument value.
+			UploadID string
+			// Parts is the parts argument value.
+			Parts []minio.CompletePart
+			// Opts is the opts argument value.
+			Opts minio.PutObjectOptions
+		}
+		// ComposeObject holds details about calls to the ComposeObject method.
+		ComposeObject []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dst is the dst argument value.
+			Dst minio.CopyDestOptions
+			// Srcs is the srcs argument value.
+			Srcs []minio.CopySrcOptions
+		}
+		// CopyObject holds details about calls to the CopyObject method.
+		CopyObject []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Dst is the dst argument value.
+			Dst minio.CopyDestOptions
+			// Src is the src argument value.
+			Src minio.CopySrcOptions
+		}
 		// EndpointURL holds details about calls to the EndpointURL method.
 		EndpointURL []struct {
 		}
@@ -131,11 +248,54 @@ type S3Mock struct {
 			// Opts is the opts argument value.
 			Opts minio.GetObjectOptions
 		}
+		// GetObjectTagging holds details about calls to the GetObjectTagging method.
+		GetObjectTagging []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BucketName is the bucketName argument value.
+			BucketName string
+			// ObjectName is the objectName argument value.
+			ObjectName string
+			// Opts is the opts argument value.
+			Opts minio.GetObjectTaggingOptions
+		}
 		// ListBuckets holds details about calls to the ListBuckets method.
 		ListBuckets []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// ListMultipartUploads holds details about calls to the ListMultipartUploads method.
+		ListMultipartUploads []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Prefix is the prefix argument value.
+			Prefix string
+			// KeyMarker is the keyMarker argument value.
+			KeyMarker string
+			// UploadIDMarker is the uploadIDMarker argument value.
+			UploadIDMarker string
+			// Delimiter is the delimiter argument value.
+			Delimiter string
+			// MaxUploads is the maxUploads argument value.
+			MaxUploads int
+		}
+		// ListObjectParts holds details about calls to the ListObjectParts method.
+		ListObjectParts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Object is the object argument value.
+			Object string
+			// UploadID is the uploadID argument value.
+			UploadID string
+			// PartNumberMarker is the partNumberMarker argument value.
+			PartNumberMarker int
+			// MaxParts is the maxParts argument value.
+			MaxParts int
+		}
 		// ListObjects holds details about calls to the ListObjects method.
 		ListObjects []struct {
 			// Ctx is the ctx argument value.
@@ -154,6 +314,17 @@ type S3Mock struct {
 			// Opts is the opts argument value.
 			Opts minio.MakeBucketOptions
 		}
+		// NewMultipartUpload holds details about calls to the NewMultipartUpload method.
+		NewMultipartUpload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Object is the object argument value.
+			Object string
+			// Opts is the opts argument value.
+			Opts minio.PutObjectOptions
+		}
 		// PresignedGetObject holds details about calls to the PresignedGetObject method.
 		PresignedGetObject []struct {
 			// Ctx is the ctx argument value.
@@ -167,6 +338,24 @@ type S3Mock struct {
 			// ReqParams is the reqParams argument value.
 			ReqParams url.Values
 		}
+		// PresignedPostPolicy holds details about calls to the PresignedPostPolicy method.
+		PresignedPostPolicy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Policy is the policy argument value.
+			Policy *minio.PostPolicy
+		}
+		// PresignedPutObject holds details about calls to the PresignedPutObject method.
+		PresignedPutObject []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BucketName is the bucketName argument value.
+			BucketName string
+			// ObjectName is the objectName argument value.
+			ObjectName string
+			// Expiry is the expiry argument value.
+			Expiry time.Duration
+		}
 		// PutObject holds details about calls to the PutObject method.
 		PutObject []struct {
 			// Ctx is the ctx argument value.
@@ -182,6 +371,25 @@ type S3Mock struct {
 			// Opts is the opts argument value.
 			Opts minio.PutObjectOptions
 		}
+		// PutObjectPart holds details about calls to the PutObjectPart method.
+		PutObjectPart []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Bucket is the bucket argument value.
+			Bucket string
+			// Object is the object argument value.
+			Object string
+			// UploadID is the uploadID argument value.
+			UploadID string
+			// PartID is the partID argument value.
+			PartID int
+			// Data is the data argument value.
+			Data io.Reader
+			// Size is the size argument value.
+			Size int64
+			// Opts is the opts argument value.
+			Opts minio.PutObjectPartOptions
+		}
 		// RemoveBucket holds details about calls to the RemoveBucket method.
 		RemoveBucket []struct {
 			// Ctx is the ctx argument value.
@@ -211,6 +419,17 @@ type S3Mock struct {
 			// Opts is the opts argument value.
 			Opts minio.RemoveObjectsOptions
 		}
+		// RemoveObjectsWithResult holds details about calls to the RemoveObjectsWithResult method.
+		RemoveObjectsWithResult []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BucketName is the bucketName argument value.
+			BucketName string
+			// ObjectsCh is the objectsCh argument value.
+			ObjectsCh <-chan minio.ObjectInfo
+			// Opts is the opts argument value.
+			Opts minio.RemoveObjectsOptions
+		}
 		// SetBucketPolicy holds details about calls to the SetBucketPolicy method.
 		SetBucketPolicy []struct {
 			// Ctx is the ctx argument value.
@@ -232,19 +451,207 @@ type S3Mock struct {
 			Opts minio.StatObjectOptions
 		}
 	}
-	lockEndpointURL        sync.RWMutex
-	lockGetBucketPolicy    sync.RWMutex
-	lockGetObject          sync.RWMutex
-	lockListBuckets        sync.RWMutex
-	lockListObjects        sync.RWMutex
-	lockMakeBucket         sync.RWMutex
-	lockPresignedGetObject sync.RWMutex
-	lockPutObject          sync.RWMutex
-	lockRemoveBucket       sync.RWMutex
-	lockRemoveObject       sync.RWMutex
-	lockRemoveObjects      sync.RWMutex
-	lockSetBucketPolicy    sync.RWMutex
-	lockStatObject         sync.RWMutex
+	lockAbortMultipartUpload    sync.RWMutex
+	lockCompleteMultipartUpload sync.RWMutex
+	lockComposeObject           sync.RWMutex
+	lockCopyObject              sync.RWMutex
+	lockEndpointURL             sync.RWMutex
+	lockGetBucketPolicy         sync.RWMutex
+	lockGetObject               sync.RWMutex
+	lockGetObjectTagging        sync.RWMutex
+	lockListBuckets             sync.RWMutex
+	lockListMultipartUploads    sync.RWMutex
+	lockListObjectParts         sync.RWMutex
+	lockListObjects             sync.RWMutex
+	lockMakeBucket              sync.RWMutex
+	lockNewMultipartUpload      sync.RWMutex
+	lockPresignedGetObject      sync.RWMutex
+	lockPresignedPostPolicy     sync.RWMutex
+	lockPresignedPutObject      sync.RWMutex
+	lockPutObject               sync.RWMutex
+	lockPutObjectPart           sync.RWMutex
+	lockRemoveBucket            sync.RWMutex
+	lockRemoveObject            sync.RWMutex
+	lockRemoveObjects           sync.RWMutex
+	lockRemoveObjectsWithResult sync.RWMutex
+	lockSetBucketPolicy         sync.RWMutex
+	lockStatObject              sync.RWMutex
+}
+
+// AbortMultipartUpload calls AbortMultipartUploadFunc.
+func (mock *S3Mock) AbortMultipartUpload(ctx context.Context, bucket string, object string, uploadID string) error {
+	if mock.AbortMultipartUploadFunc == nil {
+		panic("S3Mock.AbortMultipartUploadFunc: method is nil but S3.AbortMultipartUpload was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Bucket   string
+		Object   string
+		UploadID string
+	}{
+		Ctx:      ctx,
+		Bucket:   bucket,
+		Object:   object,
+		UploadID: uploadID,
+	}
+	mock.lockAbortMultipartUpload.Lock()
+	mock.calls.AbortMultipartUpload = append(mock.calls.AbortMultipartUpload, callInfo)
+	mock.lockAbortMultipartUpload.Unlock()
+	return mock.AbortMultipartUploadFunc(ctx, bucket, object, uploadID)
+}
+
+// AbortMultipartUploadCalls gets all the calls that were made to AbortMultipartUpload.
+// Check the length with:
+//
+//	len(mockedS3.AbortMultipartUploadCalls())
+func (mock *S3Mock) AbortMultipartUploadCalls() []struct {
+	Ctx      context.Context
+	Bucket   string
+	Object   string
+	UploadID string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Bucket   string
+		Object   string
+		UploadID string
+	}
+	mock.lockAbortMultipartUpload.RLock()
+	calls = mock.calls.AbortMultipartUpload
+	mock.lockAbortMultipartUpload.RUnlock()
+	return calls
+}
+
+// CompleteMultipartUpload calls CompleteMultipartUploadFunc.
+func (mock *S3Mock) CompleteMultipartUpload(ctx context.Context, bucket string, object string, uploadID string, parts []minio.CompletePart, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	if mock.CompleteMultipartUploadFunc == nil {
+		panic("S3Mock.CompleteMultipartUploadFunc: method is nil but S3.CompleteMultipartUpload was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Bucket   string
+		Object   string
+		UploadID string
+		Parts    []minio.CompletePart
+		Opts     minio.PutObjectOptions
+	}{
+		Ctx:      ctx,
+		Bucket:   bucket,
+		Object:   object,
+		UploadID: uploadID,
+		Parts:    parts,
+		Opts:     opts,
+	}
+	mock.lockCompleteMultipartUpload.Lock()
+	mock.calls.CompleteMultipartUpload = append(mock.calls.CompleteMultipartUpload, callInfo)
+	mock.lockCompleteMultipartUpload.Unlock()
+	return mock.CompleteMultipartUploadFunc(ctx, bucket, object, uploadID, parts, opts)
+}
+
+// CompleteMultipartUploadCalls gets all the calls that were made to CompleteMultipartUpload.
+// Check the length with:
+//
+//	len(mockedS3.CompleteMultipartUploadCalls())
+func (mock *S3Mock) CompleteMultipartUploadCalls() []struct {
+	Ctx      context.Context
+	Bucket   string
+	Object   string
+	UploadID string
+	Parts    []minio.CompletePart
+	Opts     minio.PutObjectOptions
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Bucket   string
+		Object   string
+		UploadID string
+		Parts    []minio.CompletePart
+		Opts     minio.PutObjectOptions
+	}
+	mock.lockCompleteMultipartUpload.RLock()
+	calls = mock.calls.CompleteMultipartUpload
+	mock.lockCompleteMultipartUpload.RUnlock()
+	return calls
+}
+
+// ComposeObject calls ComposeObjectFunc.
+func (mock *S3Mock) ComposeObject(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error) {
+	if mock.ComposeObjectFunc == nil {
+		panic("S3Mock.ComposeObjectFunc: method is nil but S3.ComposeObject was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Dst  minio.CopyDestOptions
+		Srcs []minio.CopySrcOptions
+	}{
+		Ctx:  ctx,
+		Dst:  dst,
+		Srcs: srcs,
+	}
+	mock.lockComposeObject.Lock()
+	mock.calls.ComposeObject = append(mock.calls.ComposeObject, callInfo)
+	mock.lockComposeObject.Unlock()
+	return mock.ComposeObjectFunc(ctx, dst, srcs...)
+}
+
+// ComposeObjectCalls gets all the calls that were made to ComposeObject.
+// Check the length with:
+//
+//	len(mockedS3.ComposeObjectCalls())
+func (mock *S3Mock) ComposeObjectCalls() []struct {
+	Ctx  context.Context
+	Dst  minio.CopyDestOptions
+	Srcs []minio.CopySrcOptions
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Dst  minio.CopyDestOptions
+		Srcs []minio.CopySrcOptions
+	}
+	mock.lockComposeObject.RLock()
+	calls = mock.calls.ComposeObject
+	mock.lockComposeObject.RUnlock()
+	return calls
+}
+
+// CopyObject calls CopyObjectFunc.
+func (mock *S3Mock) CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+	if mock.CopyObjectFunc == nil {
+		panic("S3Mock.CopyObjectFunc: method is nil but S3.CopyObject was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Dst minio.CopyDestOptions
+		Src minio.CopySrcOptions
+	}{
+		Ctx: ctx,
+		Dst: dst,
+		Src: src,
+	}
+	mock.lockCopyObject.Lock()
+	mock.calls.CopyObject = append(mock.calls.CopyObject, callInfo)
+	mock.lockCopyObject.Unlock()
+	return mock.CopyObjectFunc(ctx, dst, src)
+}
+
+// CopyObjectCalls gets all the calls that were made to CopyObject.
+// Check the length with:
+//
+//	len(mockedS3.CopyObjectCalls())
+func (mock *S3Mock) CopyObjectCalls() []struct {
+	Ctx context.Context
+	Dst minio.CopyDestOptions
+	Src minio.CopySrcOptions
+} {
+	var calls []struct {
+		Ctx context.Context
+		Dst minio.CopyDestOptions
+		Src minio.CopySrcOptions
+	}
+	mock.lockCopyObject.RLock()
+	calls = mock.calls.CopyObject
+	mock.lockCopyObject.RUnlock()
+	return calls
 }
 
 // EndpointURL calls EndpointURLFunc.
@@ -354,6 +761,50 @@ func (mock *S3Mock) GetObjectCalls() []struct {
 	return calls
 }
 
+// GetObjectTagging calls GetObjectTaggingFunc.
+func (mock *S3Mock) GetObjectTagging(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectTaggingOptions) (*tags.Tags, error) {
+	if mock.GetObjectTaggingFunc == nil {
+		panic("S3Mock.GetObjectTaggingFunc: method is nil but S3.GetObjectTagging was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		BucketName string
+		ObjectName string
+		Opts       minio.GetObjectTaggingOptions
+	}{
+		Ctx:        ctx,
+		BucketName: bucketName,
+		ObjectName: objectName,
+		Opts:       opts,
+	}
+	mock.lockGetObjectTagging.Lock()
+	mock.calls.GetObjectTagging = append(mock.calls.GetObjectTagging, callInfo)
+	mock.lockGetObjectTagging.Unlock()
+	return mock.GetObjectTaggingFunc(ctx, bucketName, objectName, opts)
+}
+
+// GetObjectTaggingCalls gets all the calls that were made to GetObjectTagging.
+// Check the length with:
+//
+//	len(mockedS3.GetObjectTaggingCalls())
+func (mock *S3Mock) GetObjectTaggingCalls() []struct {
+	Ctx        context.Context
+	BucketName string
+	ObjectName string
+	Opts       minio.GetObjectTaggingOptions
+} {
+	var calls []struct {
+		Ctx        context.Context
+		BucketName string
+		ObjectName string
+		Opts       minio.GetObjectTaggingOptions
+	}
+	mock.lockGetObjectTagging.RLock()
+	calls = mock.calls.GetObjectTagging
+	mock.lockGetObjectTagging.RUnlock()
+	return calls
+}
+
 // ListBuckets calls ListBucketsFunc.
 func (mock *S3Mock) ListBuckets(ctx context.Context) ([]minio.BucketInfo, error) {
 	if mock.ListBucketsFunc == nil {
@@ -386,6 +837,114 @@ func (mock *S3Mock) ListBucketsCalls() []struct {
 	return calls
 }
 
+// ListMultipartUploads calls ListMultipartUploadsFunc.
+func (mock *S3Mock) ListMultipartUploads(ctx context.Context, bucket string, prefix string, keyMarker string, uploadIDMarker string, delimiter string, maxUploads int) (minio.ListMultipartUploadsResult, error) {
+	if mock.ListMultipartUploadsFunc == nil {
+		panic("S3Mock.ListMultipartUploadsFunc: method is nil but S3.ListMultipartUploads was just called")
+	}
+	callInfo := struct {
+		Ctx            context.Context
+		Bucket         string
+		Prefix         string
+		KeyMarker      string
+		UploadIDMarker string
+		Delimiter      string
+		MaxUploads     int
+	}{
+		Ctx:            ctx,
+		Bucket:         bucket,
+		Prefix:         prefix,
+		KeyMarker:      keyMarker,
+		UploadIDMarker: uploadIDMarker,
+		Delimiter:      delimiter,
+		MaxUploads:     maxUploads,
+	}
+	mock.lockListMultipartUploads.Lock()
+	mock.calls.ListMultipartUploads = append(mock.calls.ListMultipartUploads, callInfo)
+	mock.lockListMultipartUploads.Unlock()
+	return mock.ListMultipartUploadsFunc(ctx, bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
+}
+
+// ListMultipartUploadsCalls gets all the calls that were made to ListMultipartUploads.
+// Check the length with:
+//
+//	len(mockedS3.ListMultipartUploadsCalls())
+func (mock *S3Mock) ListMultipartUploadsCalls() []struct {
+	Ctx            context.Context
+	Bucket         string
+	Prefix         string
+	KeyMarker      string
+	UploadIDMarker string
+	Delimiter      string
+	MaxUploads     int
+} {
+	var calls []struct {
+		Ctx            context.Context
+		Bucket         string
+		Prefix         string
+		KeyMarker      string
+		UploadIDMarker string
+		Delimiter      string
+		MaxUploads     int
+	}
+	mock.lockListMultipartUploads.RLock()
+	calls = mock.calls.ListMultipartUploads
+	mock.lockListMultipartUploads.RUnlock()
+	return calls
+}
+
+// ListObjectParts calls ListObjectPartsFunc.
+func (mock *S3Mock) ListObjectParts(ctx context.Context, bucket string, object string, uploadID string, partNumberMarker int, maxParts int) (minio.ListObjectPartsResult, error) {
+	if mock.ListObjectPartsFunc == nil {
+		panic("S3Mock.ListObjectPartsFunc: method is nil but S3.ListObjectParts was just called")
+	}
+	callInfo := struct {
+		Ctx              context.Context
+		Bucket           string
+		Object           string
+		UploadID         string
+		PartNumberMarker int
+		MaxParts         int
+	}{
+		Ctx:              ctx,
+		Bucket:           bucket,
+		Object:           object,
+		UploadID:         uploadID,
+		PartNumberMarker: partNumberMarker,
+		MaxParts:         maxParts,
+	}
+	mock.lockListObjectParts.Lock()
+	mock.calls.ListObjectParts = append(mock.calls.ListObjectParts, callInfo)
+	mock.lockListObjectParts.Unlock()
+	return mock.ListObjectPartsFunc(ctx, bucket, object, uploadID, partNumberMarker, maxParts)
+}
+
+// ListObjectPartsCalls gets all the calls that were made to ListObjectParts.
+// Check the length with:
+//
+//	len(mockedS3.ListObjectPartsCalls())
+func (mock *S3Mock) ListObjectPartsCalls() []struct {
+	Ctx              context.Context
+	Bucket           string
+	Object           string
+	UploadID         string
+	PartNumberMarker int
+	MaxParts         int
+} {
+	var calls []struct {
+		Ctx              context.Context
+		Bucket           string
+		Object           string
+		UploadID         string
+		PartNumberMarker int
+		MaxParts         int
+	}
+	mock.lockListObjectParts.RLock()
+	calls = mock.calls.ListObjectParts
+	mock.lockListObjectParts.RUnlock()
+	return calls
+}
+
 // ListObjects calls ListObjectsFunc.
 func (mock *S3Mock) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
 	if mock.ListObjectsFunc == nil {
@@ -466,6 +1025,50 @@ func (mock *S3Mock) MakeBucketCalls() []struct {
 	return calls
 }
 
+// NewMultipartUpload calls NewMultipartUploadFunc.
+func (mock *S3Mock) NewMultipartUpload(ctx context.Context, bucket string, object string, opts minio.PutObjectOptions) (string, error) {
+	if mock.NewMultipartUploadFunc == nil {
+		panic("S3Mock.NewMultipartUploadFunc: method is nil but S3.NewMultipartUpload was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Bucket string
+		Object string
+		Opts   minio.PutObjectOptions
+	}{
+		Ctx:    ctx,
+		Bucket: bucket,
+		Object: object,
+		Opts:   opts,
+	}
+	mock.lockNewMultipartUpload.Lock()
+	mock.calls.NewMultipartUpload = append(mock.calls.NewMultipartUpload, callInfo)
+	mock.lockNewMultipartUpload.Unlock()
+	return mock.NewMultipartUploadFunc(ctx, bucket, object, opts)
+}
+
+// NewMultipartUploadCalls gets all the calls that were made to NewMultipartUpload.
+// Check the length with:
+//
+//	len(mockedS3.NewMultipartUploadCalls())
+func (mock *S3Mock) NewMultipartUploadCalls() []struct {
+	Ctx    context.Context
+	Bucket string
+	Object string
+	Opts   minio.PutObjectOptions
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Bucket string
+		Object string
+		Opts   minio.PutObjectOptions
+	}
+	mock.lockNewMultipartUpload.RLock()
+	calls = mock.calls.NewMultipartUpload
+	mock.lockNewMultipartUpload.RUnlock()
+	return calls
+}
+
 // PresignedGetObject calls PresignedGetObjectFunc.
 func (mock *S3Mock) PresignedGetObject(ctx context.Context, bucketName string, objectName string, expiry time.Duration, reqParams url.Values) (*url.URL, error) {
 	if mock.PresignedGetObjectFunc == nil {
@@ -514,6 +1117,86 @@ func (mock *S3Mock) PresignedGetObjectCalls() []struct {
 	return calls
 }
 
+// PresignedPostPolicy calls PresignedPostPolicyFunc.
+func (mock *S3Mock) PresignedPostPolicy(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error) {
+	if mock.PresignedPostPolicyFunc == nil {
+		panic("S3Mock.PresignedPostPolicyFunc: method is nil but S3.PresignedPostPolicy was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Policy *minio.PostPolicy
+	}{
+		Ctx:    ctx,
+		Policy: policy,
+	}
+	mock.lockPresignedPostPolicy.Lock()
+	mock.calls.PresignedPostPolicy = append(mock.calls.PresignedPostPolicy, callInfo)
+	mock.lockPresignedPostPolicy.Unlock()
+	return mock.PresignedPostPolicyFunc(ctx, policy)
+}
+
+// PresignedPostPolicyCalls gets all the calls that were made to PresignedPostPolicy.
+// Check the length with:
+//
+//	len(mockedS3.PresignedPostPolicyCalls())
+func (mock *S3Mock) PresignedPostPolicyCalls() []struct {
+	Ctx    context.Context
+	Policy *minio.PostPolicy
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Policy *minio.PostPolicy
+	}
+	mock.lockPresignedPostPolicy.RLock()
+	calls = mock.calls.PresignedPostPolicy
+	mock.lockPresignedPostPolicy.RUnlock()
+	return calls
+}
+
+// PresignedPutObject calls PresignedPutObjectFunc.
+func (mock *S3Mock) PresignedPutObject(ctx context.Context, bucketName string, objectName string, expiry time.Duration) (*url.URL, error) {
+	if mock.PresignedPutObjectFunc == nil {
+		panic("S3Mock.PresignedPutObjectFunc: method is nil but S3.PresignedPutObject was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		BucketName string
+		ObjectName string
+		Expiry     time.Duration
+	}{
+		Ctx:        ctx,
+		BucketName: bucketName,
+		ObjectName: objectName,
+		Expiry:     expiry,
+	}
+	mock.lockPresignedPutObject.Lock()
+	mock.calls.PresignedPutObject = append(mock.calls.PresignedPutObject, callInfo)
+	mock.lockPresignedPutObject.Unlock()
+	return mock.PresignedPutObjectFunc(ctx, bucketName, objectName, expiry)
+}
+
+// PresignedPutObjectCalls gets all the calls that were made to PresignedPutObject.
+// Check the length with:
+//
+//	len(mockedS3.PresignedPutObjectCalls())
+func (mock *S3Mock) PresignedPutObjectCalls() []struct {
+	Ctx        context.Context
+	BucketName string
+	ObjectName string
+	Expiry     time.Duration
+} {
+	var calls []struct {
+		Ctx        context.Context
+		BucketName string
+		ObjectName string
+		Expiry     time.Duration
+	}
+	mock.lockPresignedPutObject.RLock()
+	calls = mock.calls.PresignedPutObject
+	mock.lockPresignedPutObject.RUnlock()
+	return calls
+}
+
 // PutObject calls PutObjectFunc.
 func (mock *S3Mock) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
 	if mock.PutObjectFunc == nil {
@@ -566,6 +1249,66 @@ func (mock *S3Mock) PutObjectCalls() []struct {
 	return calls
 }
 
+// PutObjectPart calls PutObjectPartFunc.
+func (mock *S3Mock) PutObjectPart(ctx context.Context, bucket string, object string, uploadID string, partID int, data io.Reader, size int64, opts minio.PutObjectPartOptions) (minio.ObjectPart, error) {
+	if mock.PutObjectPartFunc == nil {
+		panic("S3Mock.PutObjectPartFunc: method is nil but S3.PutObjectPart was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Bucket   string
+		Object   string
+		UploadID string
+		PartID   int
+		Data     io.Reader
+		Size     int64
+		Opts     minio.PutObjectPartOptions
+	}{
+		Ctx:      ctx,
+		Bucket:   bucket,
+		Object:   object,
+		UploadID: uploadID,
+		PartID:   partID,
+		Data:     data,
+		Size:     size,
+		Opts:     opts,
+	}
+	mock.lockPutObjectPart.Lock()
+	mock.calls.PutObjectPart = append(mock.calls.PutObjectPart, callInfo)
+	mock.lockPutObjectPart.Unlock()
+	return mock.PutObjectPartFunc(ctx, bucket, object, uploadID, partID, data, size, opts)
+}
+
+// PutObjectPartCalls gets all the calls that were made to PutObjectPart.
+// Check the length with:
+//
+//	len(mockedS3.PutObjectPartCalls())
+func (mock *S3Mock) PutObjectPartCalls() []struct {
+	Ctx      context.Context
+	Bucket   string
+	Object   string
+	UploadID string
+	PartID   int
+	Data     io.Reader
+	Size     int64
+	Opts     minio.PutObjectPartOptions
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Bucket   string
+		Object   string
+		UploadID string
+		PartID   int
+		Data     io.Reader
+		Size     int64
+		Opts     minio.PutObjectPartOptions
+	}
+	mock.lockPutObjectPart.RLock()
+	calls = mock.calls.PutObjectPart
+	mock.lockPutObjectPart.RUnlock()
+	return calls
+}
+
 // RemoveBucket calls RemoveBucketFunc.
 func (mock *S3Mock) RemoveBucket(ctx context.Context, bucketName string) error {
 	if mock.RemoveBucketFunc == nil {
@@ -690,6 +1433,50 @@ func (mock *S3Mock) RemoveObjectsCalls() []struct {
 	return calls
 }
 
+// RemoveObjectsWithResult calls RemoveObjectsWithResultFunc.
+func (mock *S3Mock) RemoveObjectsWithResult(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectResult {
+	if mock.RemoveObjectsWithResultFunc == nil {
+		panic("S3Mock.RemoveObjectsWithResultFunc: method is nil but S3.RemoveObjectsWithResult was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		BucketName string
+		ObjectsCh  <-chan minio.ObjectInfo
+		Opts       minio.RemoveObjectsOptions
+	}{
+		Ctx:        ctx,
+		BucketName: bucketName,
+		ObjectsCh:  objectsCh,
+		Opts:       opts,
+	}
+	mock.lockRemoveObjectsWithResult.Lock()
+	mock.calls.RemoveObjectsWithResult = append(mock.calls.RemoveObjectsWithResult, callInfo)
+	mock.lockRemoveObjectsWithResult.Unlock()
+	return mock.RemoveObjectsWithResultFunc(ctx, bucketName, objectsCh, opts)
+}
+
+// RemoveObjectsWithResultCalls gets all the calls that were made to RemoveObjectsWithResult.
+// Check the length with:
+//
+//	len(mockedS3.RemoveObjectsWithResultCalls())
+func (mock *S3Mock) RemoveObjectsWithResultCalls() []struct {
+	Ctx        context.Context
+	BucketName string
+	ObjectsCh  <-chan minio.ObjectInfo
+	Opts       minio.RemoveObjectsOptions
+} {
+	var calls []struct {
+		Ctx        context.Context
+		BucketName string
+		ObjectsCh  <-chan minio.ObjectInfo
+		Opts       minio.RemoveObjectsOptions
+	}
+	mock.lockRemoveObjectsWithResult.RLock()
+	calls = mock.calls.RemoveObjectsWithResult
+	mock.lockRemoveObjectsWithResult.RUnlock()
+	return calls
+}
+
 // SetBucketPolicy calls SetBucketPolicyFunc.
 func (mock *S3Mock) SetBucketPolicy(ctx context.Context, bucketName string, policy string) error {
 	if mock.SetBucketPolicyFunc == nil {
@@ -0,0 +1,155 @@
+package s3manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// HandleMoveObjectWithManager renames or moves an object or folder within a
+// bucket. The JSON body takes the source and destination keys. Objects are
+// moved right away, while folders, whose keys end with a slash, are moved
+// with every object below them by a job.
+func HandleMoveObjectWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	type request struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+	}
+
+	return withJobs(manager, RoleEditor, func(current *S3Instance, jobs *jobRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !current.Features.AllowDelete {
+				deleteDisabled(w, r)
+				return
+			}
+			bucketName := mux.Vars(r)["bucketName"]
+			sseInfo := current.Features.SSEFor(bucketName)
+
+			var req request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+				return
+			}
+			folder := strings.HasSuffix(req.Source, "/")
+			switch {
+			case req.Source == "" || req.Destination == "":
+				http.Error(w, "source and destination must not be empty", http.StatusBadRequest)
+				return
+			case req.Source == req.Destination:
+				http.Error(w, "source and destination must differ", http.StatusBadRequest)
+				return
+			case folder && !strings.HasSuffix(req.Destination, "/"):
+				http.Error(w, "destination of a folder must end with a slash", http.StatusBadRequest)
+				return
+			case !folder && strings.HasSuffix(req.Destination, "/"):
+				http.Error(w, "destination of an object must not end with a slash", http.StatusBadRequest)
+				return
+			case folder && strings.HasPrefix(req.Destination, req.Source):
+				http.Error(w, "destination must not be inside the source folder", http.StatusBadRequest)
+				return
+			}
+
+			exists, err := destinationExists(r.Context(), current.Client, bucketName, req.Destination)
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			if exists {
+				http.Error(w, fmt.Sprintf("destination %s already exists", req.Destination), http.StatusConflict)
+				return
+			}
+
+			if !folder {
+				if err := moveObject(r.Context(), current.Client, sseInfo, bucketName, req.Source, req.Destination, -1); err != nil {
+					handleHTTPError(w, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			job := Job{
+				Type:        "move",
+				Instance:    current.Name,
+				Bucket:      bucketName,
+				Source:      req.Source,
+				Destination: req.Destination,
+			}
+			if user, ok := UserFromContext(r.Context()); ok {
+				job.CreatedBy = user.Name
+			}
+			s3 := current.Client
+			job, err = jobs.start(job, func(ctx context.Context, progress *jobProgress) error {
+				return moveFolder(ctx, s3, sseInfo, bucketName, req.Source, req.Destination, progress)
+			})
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusAccepted, job)
+		}
+	})
+}
+
+// destinationExists reports whether there is an object with key or, if key
+// ends with a slash, below it. Listing works regardless of the encryption of
+// the object.
+func destinationExists(ctx context.Context, s3 S3, bucketName, key string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Keys are listed in lexicographic order, so an object with key itself
+	// comes first.
+	for object := range s3.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: key, Recursive: true, MaxKeys: 1}) {
+		if object.Err != nil {
+			return false, fmt.Errorf("error listing objects: %w", object.Err)
+		}
+		return strings.HasSuffix(key, "/") || object.Key == key, nil
+	}
+	return false, nil
+}
+
+// moveObject copies an object of size bytes to destination and removes the
+// source. A negative size is looked up.
+func moveObject(ctx context.Context, s3 S3, sseInfo SSEType, bucketName, source, destination string, size int64) error {
+	err := copyObject(ctx, s3,
+		objectLocation{Bucket: bucketName, Key: source, SSE: sseInfo},
+		objectLocation{Bucket: bucketName, Key: destination, SSE: sseInfo},
+		size,
+	)
+	if err != nil {
+		return err
+	}
+	if err := s3.RemoveObject(ctx, bucketName, source, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("error removing object: %w", err)
+	}
+	return nil
+}
+
+// moveFolder moves every object below the source prefix to the destination
+// prefix. The listing is streamed, so folders of any size are moved without
+// holding their keys in memory. Objects that fail to move are reported and
+// skipped.
+func moveFolder(ctx context.Context, s3 S3, sseInfo SSEType, bucketName, source, destination string, progress *jobProgress) error {
+	for object := range s3.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: source, Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("error listing objects: %w", object.Err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		progress.add()
+		target := destination + strings.TrimPrefix(object.Key, source)
+		if err := moveObject(ctx, s3, sseInfo, bucketName, object.Key, target, object.Size); err != nil {
+			progress.fail(object.Key, err)
+			continue
+		}
+		progress.done()
+	}
+	return nil
+}
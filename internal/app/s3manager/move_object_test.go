@@ -0,0 +1,468 @@
+package s3manager_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+)
+
+// fakeContent is the content of every object in fakeBuckets.
+const fakeContent = "content"
+
+// fakeBuckets are in-memory buckets that can be listed and whose objects,
+// which all hold fakeContent, can be stat'ed, downloaded, uploaded, copied
+// and removed. Objects listed in stale ("bucket/key") were modified before
+// the others and report the ETag of other content, those in multipart report
+// the ETag of a multipart upload, those in corrupted are served with
+// different content and those in locked can't be removed by a multi-object
+// delete.
+type fakeBuckets struct {
+	mu        sync.Mutex
+	objects   map[string][]string
+	stale     []string
+	multipart []string
+	corrupted []string
+	locked    []string
+	copies    []http.Header
+	puts      []http.Header
+}
+
+// keys returns the keys in bucket.
+func (b *fakeBuckets) keys(bucket string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.objects[bucket])
+}
+
+func (b *fakeBuckets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	keys, ok := b.objects[bucket]
+	if !ok {
+		writeS3Error(w, "NoSuchBucket", "The specified bucket does not exist.")
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		type contents struct {
+			Key          string
+			Size         int64
+			LastModified string
+			ETag         string
+		}
+		type listBucketResult struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			KeyCount    int
+			MaxKeys     int
+			IsTruncated bool
+			Contents    []contents
+		}
+		result := listBucketResult{Name: bucket, Prefix: r.URL.Query().Get("prefix"), MaxKeys: 1000}
+		for _, k := range keys {
+			if strings.HasPrefix(k, result.Prefix) {
+				modified, etag := "2026-01-02T15:04:05.000Z", fakeETag()
+				if slices.Contains(b.stale, bucket+"/"+k) {
+					modified, etag = "2025-01-02T15:04:05.000Z", fakeStaleETag
+				}
+				if slices.Contains(b.multipart, bucket+"/"+k) {
+					etag = fakeMultipartETag
+				}
+				result.Contents = append(result.Contents, contents{Key: k, Size: int64(len(fakeContent)), LastModified: modified, ETag: etag})
+			}
+		}
+		result.KeyCount = len(result.Contents)
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodHead:
+		if !slices.Contains(keys, key) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeFakeHeaders(w)
+		if slices.Contains(b.stale, bucket+"/"+key) {
+			w.Header().Set("Last-Modified", "Thu, 02 Jan 2025 15:04:05 GMT")
+			w.Header().Set("ETag", fakeStaleETag)
+		}
+		if slices.Contains(b.multipart, bucket+"/"+key) {
+			w.Header().Set("ETag", fakeMultipartETag)
+		}
+	case r.Method == http.MethodGet && r.URL.Query().Has("tagging"):
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<Tagging><TagSet><Tag><Key>project</Key><Value>launch</Value></Tag></TagSet></Tagging>`))
+	case r.Method == http.MethodGet:
+		if !slices.Contains(keys, key) {
+			writeS3Error(w, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		writeFakeHeaders(w)
+		if slices.Contains(b.corrupted, bucket+"/"+key) {
+			_, _ = w.Write([]byte(strings.ToUpper(fakeContent)))
+			return
+		}
+		_, _ = w.Write([]byte(fakeContent))
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		sourceBucket, sourceKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+		if !slices.Contains(b.objects[sourceBucket], sourceKey) {
+			writeS3Error(w, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		b.copies = append(b.copies, r.Header.Clone())
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+			slices.Sort(keys)
+			b.objects[bucket] = keys
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<CopyObjectResult><LastModified>2026-01-02T15:04:05.000Z</LastModified><ETag>"etag"</ETag></CopyObjectResult>`))
+	case r.Method == http.MethodPut:
+		_, _ = io.Copy(io.Discard, r.Body)
+		b.puts = append(b.puts, r.Header.Clone())
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+			slices.Sort(keys)
+			b.objects[bucket] = keys
+		}
+		w.Header().Set("ETag", fakeETag())
+	case r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+		var req struct {
+			Objects []struct{ Key string } `xml:"Object"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		type deleted struct{ Key string }
+		type deleteError struct{ Key, Code, Message string }
+		type deleteResult struct {
+			XMLName xml.Name      `xml:"DeleteResult"`
+			Deleted []deleted     `xml:"Deleted"`
+			Errors  []deleteError `xml:"Error"`
+		}
+		var result deleteResult
+		for _, object := range req.Objects {
+			if slices.Contains(b.locked, bucket+"/"+object.Key) {
+				result.Errors = append(result.Errors, deleteError{Key: object.Key, Code: "AccessDenied", Message: "Access Denied."})
+				continue
+			}
+			b.objects[bucket] = slices.DeleteFunc(b.objects[bucket], func(k string) bool { return k == object.Key })
+			result.Deleted = append(result.Deleted, deleted{Key: object.Key})
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodDelete:
+		b.objects[bucket] = slices.DeleteFunc(keys, func(k string) bool { return k == key })
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// fakeStaleETag and fakeMultipartETag are the ETags of objects of
+// fakeBuckets that don't hold fakeContent or were uploaded in parts.
+const (
+	fakeStaleETag     = `"36f34fd8319cf30f8e132ef294c616af"`
+	fakeMultipartETag = `"9a0364b9e99bb480dd25e1f0284c8555-2"`
+)
+
+// fakeETag returns the ETag of fakeContent.
+func fakeETag() string {
+	sum := md5.Sum([]byte(fakeContent))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// writeFakeHeaders writes the headers of an object of fakeBuckets.
+func writeFakeHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(fakeContent)))
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Last-Modified", "Fri, 02 Jan 2026 15:04:05 GMT")
+	w.Header().Set("ETag", fakeETag())
+	w.Header().Set("X-Amz-Meta-Camera", "front")
+}
+
+func writeS3Error(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write([]byte(`<Error><Code>` + code + `</Code><Message>` + message + `</Message></Error>`))
+}
+
+// newMoveRouter serves the move and job API of manager.
+func newMoveRouter(manager *s3manager.MultiS3Manager) *mux.Router {
+	r := mux.NewRouter()
+	r.Handle("/{instance}/api/buckets/{bucketName}/move", s3manager.HandleMoveObjectWithManager(manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs", s3manager.HandleListJobsWithManager(manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs/{jobId}", s3manager.HandleGetJobWithManager(manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs/{jobId}", s3manager.HandleCancelJobWithManager(manager)).Methods(http.MethodDelete)
+	return r
+}
+
+func TestHandleMoveObjectWithManager(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		keys                 []string
+		config               func(*s3manager.S3InstanceConfig)
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains string
+		expectedKeys         []string
+	}{
+		{
+			it:                 "renames an object",
+			keys:               []string{"docs/draft.txt", "docs/other.txt"},
+			body:               `{"source": "docs/draft.txt", "destination": "docs/final.txt"}`,
+			expectedStatusCode: http.StatusNoContent,
+			expectedKeys:       []string{"docs/final.txt", "docs/other.txt"},
+		},
+		{
+			it:                 "moves an object to another folder",
+			keys:               []string{"docs/draft.txt"},
+			body:               `{"source": "docs/draft.txt", "destination": "archive/2026/draft.txt"}`,
+			expectedStatusCode: http.StatusNoContent,
+			expectedKeys:       []string{"archive/2026/draft.txt"},
+		},
+		{
+			it:                   "refuses to overwrite an object",
+			keys:                 []string{"docs/draft.txt", "docs/final.txt"},
+			body:                 `{"source": "docs/draft.txt", "destination": "docs/final.txt"}`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedBodyContains: "destination docs/final.txt already exists",
+			expectedKeys:         []string{"docs/draft.txt", "docs/final.txt"},
+		},
+		{
+			it:                 "doesn't mistake longer keys for the destination",
+			keys:               []string{"docs/draft.txt", "docs/final.txt.bak"},
+			body:               `{"source": "docs/draft.txt", "destination": "docs/final.txt"}`,
+			expectedStatusCode: http.StatusNoContent,
+			expectedKeys:       []string{"docs/final.txt", "docs/final.txt.bak"},
+		},
+		{
+			it:                   "refuses to move a folder into an existing one",
+			keys:                 []string{"archive/old.txt", "docs/draft.txt"},
+			body:                 `{"source": "docs/", "destination": "archive/"}`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedBodyContains: "destination archive/ already exists",
+			expectedKeys:         []string{"archive/old.txt", "docs/draft.txt"},
+		},
+		{
+			it:                   "returns 404 for a missing object",
+			keys:                 []string{"docs/draft.txt"},
+			body:                 `{"source": "docs/missing.txt", "destination": "docs/final.txt"}`,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedBodyContains: "The specified key does not exist",
+			expectedKeys:         []string{"docs/draft.txt"},
+		},
+		{
+			it:                   "requires a source and destination",
+			body:                 `{"source": "docs/draft.txt"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "source and destination must not be empty",
+		},
+		{
+			it:                   "requires a different destination",
+			body:                 `{"source": "docs/draft.txt", "destination": "docs/draft.txt"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "source and destination must differ",
+		},
+		{
+			it:                   "requires folders to be moved to folders",
+			body:                 `{"source": "docs/", "destination": "archive"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination of a folder must end with a slash",
+		},
+		{
+			it:                   "requires objects to be moved to objects",
+			body:                 `{"source": "docs/draft.txt", "destination": "archive/"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination of an object must not end with a slash",
+		},
+		{
+			it:                   "refuses to move a folder into itself",
+			body:                 `{"source": "docs/", "destination": "docs/old/"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination must not be inside the source folder",
+		},
+		{
+			it:                   "requires deleting to be allowed",
+			keys:                 []string{"docs/draft.txt"},
+			config:               func(config *s3manager.S3InstanceConfig) { config.AllowDelete = false },
+			body:                 `{"source": "docs/draft.txt", "destination": "docs/final.txt"}`,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "deleting is disabled",
+			expectedKeys:         []string{"docs/draft.txt"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			buckets := &fakeBuckets{objects: map[string][]string{"bucket": tc.keys}}
+			ts := httptest.NewServer(buckets)
+			t.Cleanup(ts.Close)
+
+			config := fakeS3InstanceConfig("primary", ts)
+			config.AllowDelete = true
+			if tc.config != nil {
+				tc.config(&config)
+			}
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+			is.NoErr(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/move", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			newMoveRouter(manager).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)                             // status code
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains)) // body
+			is.Equal(tc.expectedKeys, buckets.keys("bucket"))                    // keys
+		})
+	}
+}
+
+func TestHandleMoveObjectWithManagerKeepsObject(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it              string
+		bucketSSE       []s3manager.BucketSSEConfig
+		expectedHeaders map[string]string
+	}{
+		{
+			it: "copies metadata, tags and content type",
+			expectedHeaders: map[string]string{
+				"X-Amz-Copy-Source":        "bucket/docs/draft.txt",
+				"X-Amz-Metadata-Directive": "",
+				"X-Amz-Tagging-Directive":  "",
+				"Content-Type":             "",
+			},
+		},
+		{
+			it: "reads and writes objects encrypted with SSE-C with the key",
+			bucketSSE: []s3manager.BucketSSEConfig{
+				{Buckets: []string{"bucket"}, SseType: "SSE-C", SseKey: "32byteslongsecretkeymustprovided"},
+			},
+			expectedHeaders: map[string]string{
+				"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Algorithm": "AES256",
+				"X-Amz-Server-Side-Encryption-Customer-Algorithm":             "AES256",
+			},
+		},
+		{
+			it: "encrypts the copy with KMS",
+			bucketSSE: []s3manager.BucketSSEConfig{
+				{Buckets: []string{"bucket"}, SseType: "KMS", SseKey: "KEY-ID"},
+			},
+			expectedHeaders: map[string]string{
+				"X-Amz-Server-Side-Encryption":                                "aws:kms",
+				"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id":                 "KEY-ID",
+				"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Algorithm": "",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			buckets := &fakeBuckets{objects: map[string][]string{"bucket": {"docs/draft.txt"}}}
+			ts := httptest.NewServer(buckets)
+			t.Cleanup(ts.Close)
+
+			config := fakeS3InstanceConfig("primary", ts)
+			config.AllowDelete = true
+			config.BucketSSE = tc.bucketSSE
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+			is.NoErr(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/move", strings.NewReader(`{"source": "docs/draft.txt", "destination": "docs/final.txt"}`))
+			rr := httptest.NewRecorder()
+			newMoveRouter(manager).ServeHTTP(rr, req)
+
+			is.Equal(http.StatusNoContent, rr.Code)
+			is.Equal(1, len(buckets.copies))
+			for name, expected := range tc.expectedHeaders {
+				is.Equal(expected, buckets.copies[0].Get(name)) // copy header
+			}
+		})
+	}
+}
+
+func TestHandleMoveObjectWithManagerFolder(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	buckets := &fakeBuckets{objects: map[string][]string{"bucket": {"docs/", "docs/a.txt", "docs/sub/b.txt", "other.txt"}}}
+	ts := httptest.NewServer(buckets)
+	t.Cleanup(ts.Close)
+
+	config := fakeS3InstanceConfig("primary", ts)
+	config.AllowDelete = true
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+	is.NoErr(err)
+	r := newMoveRouter(manager)
+
+	req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/move", strings.NewReader(`{"source": "docs/", "destination": "archive/docs/"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	is.Equal(http.StatusAccepted, rr.Code)
+
+	var job s3manager.Job
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+	is.Equal("move", job.Type)
+	is.Equal("docs/", job.Source)
+	is.Equal("archive/docs/", job.Destination)
+	is.Equal(s3manager.JobRunning, job.Status)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == s3manager.JobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/primary/api/buckets/bucket/jobs/"+job.ID, nil))
+		is.Equal(http.StatusOK, rr.Code)
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&job))
+	}
+
+	is.Equal(s3manager.JobSucceeded, job.Status) // job finished
+	is.Equal(3, job.Total)
+	is.Equal(3, job.Done)
+	is.Equal(0, job.Failed)
+	is.True(job.Finished != nil)
+	is.Equal([]string{"archive/docs/", "archive/docs/a.txt", "archive/docs/sub/b.txt", "other.txt"}, buckets.keys("bucket"))
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/primary/api/buckets/bucket/jobs", nil))
+	var jobs []s3manager.Job
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&jobs))
+	is.Equal(1, len(jobs))
+	is.Equal(job.ID, jobs[0].ID)
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/primary/api/buckets/other/jobs/"+job.ID, nil))
+	is.Equal(http.StatusNotFound, rr.Code) // jobs belong to their bucket
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/bucket/jobs/unknown", nil))
+	is.Equal(http.StatusNotFound, rr.Code)
+}
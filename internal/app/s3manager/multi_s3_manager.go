@@ -1,42 +1,123 @@
 package s3manager
 
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 // S3Instance represents a configured S3 instance
 type S3Instance struct {
-	ID     string
-	Name   string
-	Client S3
+	ID       string
+	Name     string
+	Client   S3
+	Buckets  BucketFilter
+	Features Features
+	config   S3InstanceConfig
+	health   atomic.Pointer[InstanceHealth]
+}
+
+// Features controls which functionality is offered for an S3 instance.
+type Features struct {
+	AllowDelete   bool
+	ForceDownload bool
+	ListRecursive bool
+	ShowVersions  bool
+	ShowMetadata  bool
+	SSE           SSEType
+	// BucketSSE overrides SSE for some buckets. Use SSEFor to get the
+	// encryption of a bucket.
+	BucketSSE []BucketSSE
+	// MaxPresignExpiry is the longest time presigned URLs can be valid for.
+	// Zero allows the seven days S3 supports.
+	MaxPresignExpiry time.Duration
 }
 
 // MultiS3Manager manages multiple S3 instances
 type MultiS3Manager struct {
 	instances     map[string]*S3Instance
 	instanceOrder []string
+	nextID        int
+	access        *AccessPolicy
 	mu            sync.RWMutex
+
+	// static are the instances from the configuration, stored the ones
+	// registered at runtime and persisted to store. updateMu serializes all
+	// changes to the instances.
+	static   []S3InstanceConfig
+	stored   []S3InstanceConfig
+	store    InstanceStore
+	updateMu sync.Mutex
+
+	// shares are the share links, if a share store is used.
+	shares atomic.Pointer[shareRegistry]
+	// jobs are the jobs that are running or finished recently.
+	jobs jobRegistry
+	// transfers are the transfers between instances.
+	transfers transferRegistry
 }
 
+// instanceProbeTimeout is how long instances registered at runtime may take
+// to list their buckets.
+const instanceProbeTimeout = 10 * time.Second
+
+// Errors returned when changing instances at runtime.
+var (
+	ErrNoInstanceStore     = errors.New("no instance store configured")
+	ErrInstanceNotFound    = errors.New("S3 instance not found")
+	ErrInstanceExists      = errors.New("S3 instance already exists")
+	ErrInstanceNotManaged  = errors.New("S3 instance is defined in the configuration and cannot be changed at runtime")
+	ErrInstanceUnreachable = errors.New("S3 instance is not reachable")
+)
+
 // S3InstanceConfig holds configuration for a single S3 instance
 type S3InstanceConfig struct {
-	Name                string
-	Endpoint            string
-	UseIam              bool
-	IamEndpoint         string
-	AccessKeyID         string
-	SecretAccessKey     string
-	Region              string
-	UseSSL              bool
-	SkipSSLVerification bool
-	SignatureType       string
+	Name                 string            `mapstructure:"name" json:"name"`
+	Endpoint             string            `mapstructure:"endpoint" json:"endpoint"`
+	UseIam               bool              `mapstructure:"use_iam" json:"use_iam"`
+	IamEndpoint          string            `mapstructure:"iam_endpoint" json:"iam_endpoint"`
+	AccessKeyID          string            `mapstructure:"access_key_id" json:"access_key_id"`
+	SecretAccessKey      string            `mapstructure:"secret_access_key" json:"secret_access_key"`
+	CredentialsProvider  string            `mapstructure:"credentials_provider" json:"credentials_provider"`
+	CredentialsFile      string            `mapstructure:"credentials_file" json:"credentials_file"`
+	CredentialsProfile   string            `mapstructure:"credentials_profile" json:"credentials_profile"`
+	STSEndpoint          string            `mapstructure:"sts_endpoint" json:"sts_endpoint"`
+	RoleARN              string            `mapstructure:"role_arn" json:"role_arn"`
+	RoleSessionName      string            `mapstructure:"role_session_name" json:"role_session_name"`
+	WebIdentityTokenFile string            `mapstructure:"web_identity_token_file" json:"web_identity_token_file"`
+	Region               string            `mapstructure:"region" json:"region"`
+	UseSSL               bool              `mapstructure:"use_ssl" json:"use_ssl"`
+	SkipSSLVerification  bool              `mapstructure:"skip_ssl_verification" json:"skip_ssl_verification"`
+	CAFile               string            `mapstructure:"ca_file" json:"ca_file"`
+	ClientCertFile       string            `mapstructure:"client_cert_file" json:"client_cert_file"`
+	ClientKeyFile        string            `mapstructure:"client_key_file" json:"client_key_file"`
+	TLSMinVersion        string            `mapstructure:"tls_min_version" json:"tls_min_version"`
+	ProxyURL             string            `mapstructure:"proxy_url" json:"proxy_url"`
+	MaxIdleConns         int               `mapstructure:"max_idle_conns" json:"max_idle_conns"`
+	MaxIdleConnsPerHost  int               `mapstructure:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+	IdleConnTimeout      int               `mapstructure:"idle_conn_timeout" json:"idle_conn_timeout"`
+	SignatureType        string            `mapstructure:"signature_type" json:"signature_type"`
+	AllowedBuckets       []string          `mapstructure:"allowed_buckets" json:"allowed_buckets"`
+	DeniedBuckets        []string          `mapstructure:"denied_buckets" json:"denied_buckets"`
+	AllowDelete          bool              `mapstructure:"allow_delete" json:"allow_delete"`
+	ForceDownload        bool              `mapstructure:"force_download" json:"force_download"`
+	ListRecursive        bool              `mapstructure:"list_recursive" json:"list_recursive"`
+	ShowVersions         bool              `mapstructure:"show_versions" json:"show_versions"`
+	ShowMetadata         bool              `mapstructure:"show_metadata" json:"show_metadata"`
+	SseType              string            `mapstructure:"sse_type" json:"sse_type"`
+	SseKey               string            `mapstructure:"sse_key" json:"sse_key"`
+	MaxPresignExpiry     int               `mapstructure:"max_presign_expiry" json:"max_presign_expiry"`
+	BucketSSE            []BucketSSEConfig `mapstructure:"bucket_sse" json:"bucket_sse"`
 }
 
 // NewMultiS3Manager creates a new MultiS3Manager with the given configurations
@@ -46,65 +127,378 @@ func NewMultiS3Manager(configs []S3InstanceConfig) (*MultiS3Manager, error) {
 		instanceOrder: make([]string, 0, len(configs)),
 	}
 
-	for i, config := range configs {
-		instanceID := fmt.Sprintf("%d", i+1)
+	if err := manager.Reload(configs); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Initialized MultiS3Manager with %d instances", len(manager.instances))
+	return manager, nil
+}
 
-		// Set up S3 client options
-		opts := &minio.Options{
-			Secure: config.UseSSL,
-		}
+// newS3Instance creates an S3Instance with its own client from config.
+func newS3Instance(id string, config S3InstanceConfig) (*S3Instance, error) {
+	// Set up S3 client options
+	opts := &minio.Options{
+		Secure: config.UseSSL,
+	}
 
-		if config.UseIam {
-			opts.Creds = credentials.NewIAM(config.IamEndpoint)
-		} else {
-			var signatureType credentials.SignatureType
-
-			switch config.SignatureType {
-			case "V2":
-				signatureType = credentials.SignatureV2
-			case "V4":
-				signatureType = credentials.SignatureV4
-			case "V4Streaming":
-				signatureType = credentials.SignatureV4Streaming
-			case "Anonymous":
-				signatureType = credentials.SignatureAnonymous
-			default:
-				return nil, fmt.Errorf("invalid SIGNATURE_TYPE: %s", config.SignatureType)
-			}
+	creds, err := newCredentials(config)
+	if err != nil {
+		return nil, err
+	}
+	opts.Creds = creds
+
+	if config.Region != "" {
+		opts.Region = config.Region
+	}
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating transport for instance %s: %w", config.Name, err)
+	}
+	opts.Transport = transport
+
+	// Create S3 client
+	s3Client, err := minio.New(config.Endpoint, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating s3 client for instance %s: %w", config.Name, err)
+	}
+
+	buckets, err := NewBucketFilter(config.AllowedBuckets, config.DeniedBuckets)
+	if err != nil {
+		return nil, fmt.Errorf("error creating bucket filter for instance %s: %w", config.Name, err)
+	}
+
+	return &S3Instance{
+		ID:      id,
+		Name:    config.Name,
+		Client:  newMinioS3(s3Client),
+		Buckets: buckets,
+		Features: Features{
+			AllowDelete:      config.AllowDelete,
+			ForceDownload:    config.ForceDownload,
+			ListRecursive:    config.ListRecursive,
+			ShowVersions:     config.ShowVersions,
+			ShowMetadata:     config.ShowMetadata,
+			SSE:              SSEType{Type: config.SseType, Key: config.SseKey},
+			BucketSSE:        newBucketSSE(config.BucketSSE),
+			MaxPresignExpiry: time.Duration(config.MaxPresignExpiry) * time.Second,
+		},
+		config: config,
+	}, nil
+}
+
+// Reload replaces the instances from the configuration with the given ones.
+// Instances whose configuration did not change are kept as they are, and
+// instances keep their ID as long as their name stays the same. All new
+// clients are created before any instance is swapped, so a failing
+// configuration leaves the manager untouched. Requests that already resolved
+// an instance keep using its previous client until they complete. Instances
+// registered at runtime are kept.
+func (m *MultiS3Manager) Reload(configs []S3InstanceConfig) error {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	if err := m.apply(slices.Concat(configs, m.stored)); err != nil {
+		return err
+	}
+	m.static = configs
+	return nil
+}
+
+// apply swaps the managed instances for the given configurations. The caller
+// must hold updateMu.
+func (m *MultiS3Manager) apply(configs []S3InstanceConfig) error {
+	if len(configs) == 0 {
+		return fmt.Errorf("no S3 instances configured")
+	}
+
+	m.mu.RLock()
+	existing := make(map[string]*S3Instance, len(m.instances))
+	for _, instance := range m.instances {
+		existing[instance.Name] = instance
+	}
+	nextID := m.nextID
+	m.mu.RUnlock()
 
-			opts.Creds = credentials.NewStatic(config.AccessKeyID, config.SecretAccessKey, "", signatureType)
+	instances := make(map[string]*S3Instance, len(configs))
+	instanceOrder := make([]string, 0, len(configs))
+	seen := make(map[string]bool, len(configs))
+	var added, updated, unchanged int
+	for _, config := range configs {
+		if seen[config.Name] {
+			return fmt.Errorf("duplicate S3 instance name: %s", config.Name)
 		}
+		seen[config.Name] = true
 
-		if config.Region != "" {
-			opts.Region = config.Region
+		previous, exists := existing[config.Name]
+		if exists && reflect.DeepEqual(previous.config, config) {
+			instances[previous.ID] = previous
+			instanceOrder = append(instanceOrder, previous.ID)
+			unchanged++
+			continue
 		}
 
-		if config.UseSSL && config.SkipSSLVerification {
-			opts.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}} //nolint:gosec
+		var id string
+		if exists {
+			id = previous.ID
+			updated++
+		} else {
+			nextID++
+			id = strconv.Itoa(nextID)
+			added++
 		}
 
-		// Create S3 client
-		s3Client, err := minio.New(config.Endpoint, opts)
+		instance, err := newS3Instance(id, config)
 		if err != nil {
-			return nil, fmt.Errorf("error creating s3 client for instance %s: %w", config.Name, err)
+			return err
 		}
+		instances[id] = instance
+		instanceOrder = append(instanceOrder, id)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		instance := &S3Instance{
-			ID:     instanceID,
-			Name:   config.Name,
-			Client: s3Client,
+	removed := len(m.instances) - unchanged - updated
+	m.instances = instances
+	m.instanceOrder = instanceOrder
+	m.nextID = nextID
+
+	if unchanged+updated+removed > 0 {
+		log.Printf("Reloaded S3 instances: %d added, %d updated, %d removed, %d unchanged", added, updated, removed, unchanged)
+	}
+	return nil
+}
+
+// ConfigSource loads the current S3 instance configurations.
+type ConfigSource func() ([]S3InstanceConfig, error)
+
+// Watch reloads the instances from source every time a value is received on
+// trigger, until ctx is done. Failed reloads are logged and keep the current
+// instances.
+func (m *MultiS3Manager) Watch(ctx context.Context, source ConfigSource, trigger <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			configs, err := source()
+			if err == nil {
+				err = m.Reload(configs)
+			}
+			if err != nil {
+				log.Printf("error reloading S3 instances, keeping current configuration: %v", err)
+			}
 		}
+	}
+}
+
+// UseInstanceStore loads the instances registered at runtime from store and
+// persists all future registrations to it.
+func (m *MultiS3Manager) UseInstanceStore(store InstanceStore) error {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
 
-		manager.instances[instanceID] = instance
-		manager.instanceOrder = append(manager.instanceOrder, instanceID)
+	stored, err := store.Load()
+	if err != nil {
+		return err
 	}
+	if err := m.apply(slices.Concat(m.static, stored)); err != nil {
+		return fmt.Errorf("error loading stored instances: %w", err)
+	}
+	m.stored = stored
+	m.store = store
+	return nil
+}
+
+// HasInstanceStore reports whether instances can be registered at runtime.
+func (m *MultiS3Manager) HasInstanceStore() bool {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	return m.store != nil
+}
+
+// IsManaged reports whether instance was registered at runtime and can
+// therefore be changed or removed at runtime.
+func (m *MultiS3Manager) IsManaged(instance *S3Instance) bool {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
 
-	if len(manager.instances) == 0 {
-		return nil, fmt.Errorf("no S3 instances configured")
+	return m.storedIndex(instance.Name) >= 0
+}
+
+// AddInstance registers a new instance after checking that it can be reached
+// with the given credentials.
+func (m *MultiS3Manager) AddInstance(ctx context.Context, config S3InstanceConfig) (*S3Instance, error) {
+	check := func() error {
+		if m.store == nil {
+			return ErrNoInstanceStore
+		}
+		if slices.ContainsFunc(slices.Concat(m.static, m.stored), func(c S3InstanceConfig) bool { return c.Name == config.Name }) {
+			return fmt.Errorf("%w: %s", ErrInstanceExists, config.Name)
+		}
+		return nil
 	}
 
-	log.Printf("Initialized MultiS3Manager with %d instances", len(manager.instances))
-	return manager, nil
+	m.updateMu.Lock()
+	err := check()
+	m.updateMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := probeInstance(ctx, config); err != nil {
+		return nil, err
+	}
+
+	// The instances may have changed while the new one was probed.
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+	if err := check(); err != nil {
+		return nil, err
+	}
+	return m.storeInstances(append(slices.Clone(m.stored), config), config)
+}
+
+// UpdateInstance replaces the configuration of an instance registered at
+// runtime after checking that it can be reached with the new settings. The
+// name of an instance cannot be changed. If no secret access key is given,
+// the current one is kept as long as the access key ID stays the same.
+func (m *MultiS3Manager) UpdateInstance(ctx context.Context, identifier string, config S3InstanceConfig) (*S3Instance, error) {
+	m.updateMu.Lock()
+	i, err := m.managedIndex(identifier)
+	var current S3InstanceConfig
+	if err == nil {
+		current = m.stored[i]
+	}
+	m.updateMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Name == "" {
+		config.Name = current.Name
+	}
+	if config.Name != current.Name {
+		return nil, &ConfigError{Field: "name", Err: errors.New("cannot be changed")}
+	}
+	if config.SecretAccessKey == "" && config.AccessKeyID == current.AccessKeyID {
+		config.SecretAccessKey = current.SecretAccessKey
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := probeInstance(ctx, config); err != nil {
+		return nil, err
+	}
+
+	// The instance may have been removed while its new settings were
+	// probed.
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+	i, err = m.managedIndex(current.Name)
+	if err != nil {
+		return nil, err
+	}
+	stored := slices.Clone(m.stored)
+	stored[i] = config
+	return m.storeInstances(stored, config)
+}
+
+// RemoveInstance unregisters an instance registered at runtime.
+func (m *MultiS3Manager) RemoveInstance(identifier string) error {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	i, err := m.managedIndex(identifier)
+	if err != nil {
+		return err
+	}
+
+	stored := slices.Delete(slices.Clone(m.stored), i, i+1)
+	if err := m.store.Save(stored); err != nil {
+		return err
+	}
+	if err := m.apply(slices.Concat(m.static, stored)); err != nil {
+		return err
+	}
+	m.stored = stored
+	return nil
+}
+
+// probeInstance checks that config may be registered at runtime and that the
+// instance can be reached with it within instanceProbeTimeout. It must be
+// called without holding updateMu, so a slow endpoint doesn't block others.
+func probeInstance(ctx context.Context, config S3InstanceConfig) error {
+	// Other providers and the file settings read files or the environment of
+	// the server, which users registering instances must not have access to.
+	if provider := config.credentialsProvider(); provider != CredentialsStatic && provider != CredentialsAssumeRole {
+		return &ConfigError{Field: "credentials_provider", Err: fmt.Errorf("must be %s or %s for instances registered at runtime, got %q", CredentialsStatic, CredentialsAssumeRole, provider)}
+	}
+	files := []struct{ field, path string }{
+		{"ca_file", config.CAFile},
+		{"client_cert_file", config.ClientCertFile},
+		{"client_key_file", config.ClientKeyFile},
+		{"credentials_file", config.CredentialsFile},
+		{"web_identity_token_file", config.WebIdentityTokenFile},
+	}
+	for _, file := range files {
+		if file.path != "" {
+			return &ConfigError{Field: file.field, Err: errors.New("must not be set for instances registered at runtime")}
+		}
+	}
+
+	probe, err := newS3Instance("", config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, instanceProbeTimeout)
+	defer cancel()
+	if _, err := probe.Client.ListBuckets(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrInstanceUnreachable, err)
+	}
+	return nil
+}
+
+// storeInstances persists stored and applies it. It returns the instance
+// created for changed. The caller must hold updateMu.
+func (m *MultiS3Manager) storeInstances(stored []S3InstanceConfig, changed S3InstanceConfig) (*S3Instance, error) {
+	if err := m.store.Save(stored); err != nil {
+		return nil, err
+	}
+	if err := m.apply(slices.Concat(m.static, stored)); err != nil {
+		return nil, err
+	}
+	m.stored = stored
+
+	return m.GetInstance(changed.Name)
+}
+
+// managedIndex returns the position of the identified instance in m.stored.
+// The caller must hold updateMu.
+func (m *MultiS3Manager) managedIndex(identifier string) (int, error) {
+	if m.store == nil {
+		return -1, ErrNoInstanceStore
+	}
+	instance, err := m.GetInstance(identifier)
+	if err != nil {
+		return -1, fmt.Errorf("%w: %s", ErrInstanceNotFound, identifier)
+	}
+	i := m.storedIndex(instance.Name)
+	if i < 0 {
+		return -1, fmt.Errorf("%w: %s", ErrInstanceNotManaged, instance.Name)
+	}
+	return i, nil
+}
+
+// storedIndex returns the position of the named instance in m.stored, or -1.
+// The caller must hold updateMu.
+func (m *MultiS3Manager) storedIndex(name string) int {
+	return slices.IndexFunc(m.stored, func(c S3InstanceConfig) bool { return c.Name == name })
 }
 
 // GetInstance returns an S3 instance by its ID or Name
@@ -138,3 +532,59 @@ func (m *MultiS3Manager) GetAllInstances() []*S3Instance {
 	}
 	return instances
 }
+
+// SetAccessPolicy restricts what users may do on the managed instances. A nil
+// policy grants everyone full access.
+func (m *MultiS3Manager) SetAccessPolicy(policy *AccessPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.access = policy
+}
+
+// RoleFor returns the role the user authenticated for r holds on bucket in
+// instance. If bucket is empty, the highest role on any bucket of the instance
+// is returned.
+func (m *MultiS3Manager) RoleFor(r *http.Request, instance *S3Instance, bucket string) Role {
+	m.mu.RLock()
+	access := m.access
+	m.mu.RUnlock()
+
+	user, _ := UserFromContext(r.Context())
+	return access.RoleFor(user, instance.Name, bucket)
+}
+
+// InstanceRoleFor returns the role the user authenticated for r holds on every
+// bucket of the named instance.
+func (m *MultiS3Manager) InstanceRoleFor(r *http.Request, name string) Role {
+	m.mu.RLock()
+	access := m.access
+	m.mu.RUnlock()
+
+	user, _ := UserFromContext(r.Context())
+	return access.InstanceRoleFor(user, name)
+}
+
+// GlobalRoleFor returns the role the user authenticated for r holds on every
+// bucket of every instance.
+func (m *MultiS3Manager) GlobalRoleFor(r *http.Request) Role {
+	m.mu.RLock()
+	access := m.access
+	m.mu.RUnlock()
+
+	user, _ := UserFromContext(r.Context())
+	return access.GlobalRoleFor(user)
+}
+
+// GetAccessibleInstances returns all S3 instances the user authenticated for r
+// holds a role on.
+func (m *MultiS3Manager) GetAccessibleInstances(r *http.Request) []*S3Instance {
+	all := m.GetAllInstances()
+	instances := make([]*S3Instance, 0, len(all))
+	for _, instance := range all {
+		if m.RoleFor(r, instance, "") > RoleNone {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
@@ -1,7 +1,15 @@
 package s3manager_test
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cloudlena/s3manager/internal/app/s3manager"
 	"github.com/matryer/is"
@@ -103,6 +111,34 @@ func TestNewMultiS3Manager(t *testing.T) {
 				},
 			},
 		},
+		{
+			it: "creates manager with bucket allow and deny lists",
+			configs: []s3manager.S3InstanceConfig{
+				{
+					Name:            "test",
+					Endpoint:        "localhost:9000",
+					AccessKeyID:     "key",
+					SecretAccessKey: "secret",
+					SignatureType:   "V4",
+					AllowedBuckets:  []string{"team-*"},
+					DeniedBuckets:   []string{"team-secret"},
+				},
+			},
+		},
+		{
+			it: "returns error for an invalid bucket pattern",
+			configs: []s3manager.S3InstanceConfig{
+				{
+					Name:            "test",
+					Endpoint:        "localhost:9000",
+					AccessKeyID:     "key",
+					SecretAccessKey: "secret",
+					SignatureType:   "V4",
+					AllowedBuckets:  []string{"[team"},
+				},
+			},
+			expectError: true,
+		},
 		{
 			it:          "returns error for empty configs",
 			configs:     []s3manager.S3InstanceConfig{},
@@ -221,3 +257,359 @@ func TestMultiS3ManagerGetAllInstances(t *testing.T) {
 	is.Equal("first", instances[0].Name)
 	is.Equal("second", instances[1].Name)
 }
+
+func TestMultiS3ManagerInstanceFeatures(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		{
+			Name:          "prod",
+			Endpoint:      "localhost:9000",
+			SignatureType: "Anonymous",
+			ForceDownload: true,
+		},
+		{
+			Name:          "dev",
+			Endpoint:      "localhost:9001",
+			SignatureType: "Anonymous",
+			AllowDelete:   true,
+			ListRecursive: true,
+			ShowVersions:  true,
+			ShowMetadata:  true,
+			SseType:       "SSE",
+		},
+	})
+	is.NoErr(err)
+
+	prod, err := manager.GetInstance("prod")
+	is.NoErr(err)
+	is.Equal(s3manager.Features{ForceDownload: true}, prod.Features)
+
+	dev, err := manager.GetInstance("dev")
+	is.NoErr(err)
+	is.Equal(s3manager.Features{
+		AllowDelete:   true,
+		ListRecursive: true,
+		ShowVersions:  true,
+		ShowMetadata:  true,
+		SSE:           s3manager.SSEType{Type: "SSE"},
+	}, dev.Features)
+}
+
+func TestFeaturesSSEFor(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	features := s3manager.Features{
+		SSE: s3manager.SSEType{Type: "SSE"},
+		BucketSSE: []s3manager.BucketSSE{
+			{Buckets: []string{"secure-*"}, SSE: s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"}},
+			{Buckets: []string{"secure-logs", "plain"}, SSE: s3manager.SSEType{}},
+		},
+	}
+
+	is.Equal(s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"}, features.SSEFor("secure-data")) // first match
+	is.Equal(s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"}, features.SSEFor("secure-logs")) // earlier entries win
+	is.Equal(s3manager.SSEType{}, features.SSEFor("plain"))                                                             // override without encryption
+	is.Equal(s3manager.SSEType{Type: "SSE"}, features.SSEFor("other"))                                                  // instance default
+}
+
+func TestMultiS3ManagerReload(t *testing.T) {
+	t.Parallel()
+
+	first := s3manager.S3InstanceConfig{
+		Name:            "first",
+		Endpoint:        "localhost:9000",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		SignatureType:   "V4",
+	}
+	second := s3manager.S3InstanceConfig{
+		Name:            "second",
+		Endpoint:        "localhost:9001",
+		AccessKeyID:     "key2",
+		SecretAccessKey: "secret2",
+		SignatureType:   "V4",
+	}
+	third := s3manager.S3InstanceConfig{
+		Name:          "third",
+		Endpoint:      "localhost:9002",
+		SignatureType: "Anonymous",
+	}
+
+	t.Run("adds, removes and updates instances", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{first, second})
+		is.NoErr(err)
+		unchanged, err := manager.GetInstance("first")
+		is.NoErr(err)
+		rotated, err := manager.GetInstance("second")
+		is.NoErr(err)
+
+		recredentialed := second
+		recredentialed.SecretAccessKey = "rotated"
+		err = manager.Reload([]s3manager.S3InstanceConfig{recredentialed, first, third})
+		is.NoErr(err)
+
+		instances := manager.GetAllInstances()
+		is.Equal(3, len(instances))
+		is.Equal("second", instances[0].Name)
+		is.Equal("first", instances[1].Name)
+		is.Equal("third", instances[2].Name)
+
+		// Unchanged instances are kept as they are.
+		is.True(instances[1] == unchanged)
+		// Updated instances keep their ID but get a new client.
+		is.Equal(rotated.ID, instances[0].ID)
+		is.True(instances[0] != rotated)
+		// New instances never reuse an ID.
+		is.Equal("3", instances[2].ID)
+
+		err = manager.Reload([]s3manager.S3InstanceConfig{third})
+		is.NoErr(err)
+		_, err = manager.GetInstance("first")
+		is.True(err != nil)
+		is.Equal(1, len(manager.GetAllInstances()))
+	})
+
+	t.Run("keeps the current instances if the configuration is invalid", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{first})
+		is.NoErr(err)
+
+		invalid := second
+		invalid.SignatureType = "INVALID"
+		is.True(manager.Reload([]s3manager.S3InstanceConfig{third, invalid}) != nil)
+		is.True(manager.Reload([]s3manager.S3InstanceConfig{third, third}) != nil)
+		is.True(manager.Reload(nil) != nil)
+
+		instances := manager.GetAllInstances()
+		is.Equal(1, len(instances))
+		is.Equal("first", instances[0].Name)
+	})
+}
+
+func TestMultiS3ManagerWatch(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	configs := []s3manager.S3InstanceConfig{{
+		Name:          "first",
+		Endpoint:      "localhost:9000",
+		SignatureType: "Anonymous",
+	}}
+	manager, err := s3manager.NewMultiS3Manager(configs)
+	is.NoErr(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trigger := make(chan struct{})
+	done := make(chan struct{})
+	loaded := make(chan struct{}, 2)
+	calls := 0
+	source := func() ([]s3manager.S3InstanceConfig, error) {
+		defer func() { loaded <- struct{}{} }()
+		calls++
+		if calls > 1 {
+			return nil, errors.New("config source unavailable")
+		}
+		return append(configs, s3manager.S3InstanceConfig{
+			Name:          "second",
+			Endpoint:      "localhost:9001",
+			SignatureType: "Anonymous",
+		}), nil
+	}
+	go func() {
+		manager.Watch(ctx, source, trigger)
+		close(done)
+	}()
+
+	trigger <- struct{}{}
+	trigger <- struct{}{}
+	<-loaded
+	<-loaded
+	cancel()
+	<-done
+
+	instances := manager.GetAllInstances()
+	is.Equal(2, len(instances))
+	is.Equal("second", instances[1].Name)
+}
+
+// newFakeS3Server starts a server that answers ListBuckets with an empty list,
+// or with AccessDenied if deny is set.
+func newFakeS3Server(t *testing.T, deny bool) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(fakeS3Handler(deny))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// fakeS3Handler answers every request with an empty bucket list or, if deny
+// is set, with an access denied error.
+func fakeS3Handler(deny bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		if deny {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<ListAllMyBucketsResult><Owner><ID>owner</ID></Owner><Buckets></Buckets></ListAllMyBucketsResult>`))
+	}
+}
+
+// fakeS3InstanceConfig returns the configuration of an instance served by ts.
+func fakeS3InstanceConfig(name string, ts *httptest.Server) s3manager.S3InstanceConfig {
+	return s3manager.S3InstanceConfig{
+		Name:            name,
+		Endpoint:        strings.TrimPrefix(ts.URL, "http://"),
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Region:          "us-east-1",
+		SignatureType:   "V4",
+	}
+}
+
+func TestMultiS3ManagerInstanceStore(t *testing.T) {
+	t.Parallel()
+
+	static := s3manager.S3InstanceConfig{
+		Name:          "static",
+		Endpoint:      "localhost:9000",
+		SignatureType: "Anonymous",
+	}
+	reachable := newFakeS3Server(t, false)
+	denied := newFakeS3Server(t, true)
+
+	t.Run("cannot change instances without a store", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{static})
+		is.NoErr(err)
+
+		_, err = manager.AddInstance(context.Background(), fakeS3InstanceConfig("tenant", reachable))
+		is.True(errors.Is(err, s3manager.ErrNoInstanceStore))
+	})
+
+	t.Run("adds, updates and removes stored instances", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		path := filepath.Join(t.TempDir(), "instances.json")
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{static})
+		is.NoErr(err)
+		is.NoErr(manager.UseInstanceStore(s3manager.NewFileInstanceStore(path)))
+
+		instance, err := manager.AddInstance(context.Background(), fakeS3InstanceConfig("tenant", reachable))
+		is.NoErr(err)
+		is.Equal("tenant", instance.Name)
+		is.True(manager.IsManaged(instance))
+
+		// Stored instances are loaded again and survive configuration reloads.
+		restarted, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{static})
+		is.NoErr(err)
+		is.NoErr(restarted.UseInstanceStore(s3manager.NewFileInstanceStore(path)))
+		is.NoErr(restarted.Reload([]s3manager.S3InstanceConfig{static}))
+		_, err = restarted.GetInstance("tenant")
+		is.NoErr(err)
+
+		// The secret is kept if it is not given again.
+		update := fakeS3InstanceConfig("", reachable)
+		update.SecretAccessKey = ""
+		update.Region = "eu-central-1"
+		updated, err := manager.UpdateInstance(context.Background(), instance.ID, update)
+		is.NoErr(err)
+		is.Equal(instance.ID, updated.ID)
+		configs, err := s3manager.NewFileInstanceStore(path).Load()
+		is.NoErr(err)
+		is.Equal("eu-central-1", configs[0].Region)
+		is.Equal("secret", configs[0].SecretAccessKey)
+
+		is.NoErr(manager.RemoveInstance("tenant"))
+		_, err = manager.GetInstance("tenant")
+		is.True(err != nil)
+		configs, err = s3manager.NewFileInstanceStore(path).Load()
+		is.NoErr(err)
+		is.Equal(0, len(configs))
+	})
+
+	t.Run("does not block other callers while probing an instance", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		probed, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			once.Do(func() { close(probed) })
+			<-release
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(slow.Close)
+		t.Cleanup(func() { close(release) })
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{static})
+		is.NoErr(err)
+		is.NoErr(manager.UseInstanceStore(s3manager.NewFileInstanceStore(filepath.Join(t.TempDir(), "instances.json"))))
+
+		go func() { _, _ = manager.AddInstance(context.Background(), fakeS3InstanceConfig("tenant", slow)) }()
+		<-probed
+
+		checked := make(chan bool)
+		go func() { checked <- manager.HasInstanceStore() }()
+		select {
+		case ok := <-checked:
+			is.True(ok)
+		case <-time.After(5 * time.Second):
+			t.Fatal("HasInstanceStore blocked by a probe")
+		}
+	})
+
+	t.Run("rejects invalid changes", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{static})
+		is.NoErr(err)
+		is.NoErr(manager.UseInstanceStore(s3manager.NewFileInstanceStore(filepath.Join(t.TempDir(), "instances.json"))))
+
+		_, err = manager.AddInstance(context.Background(), fakeS3InstanceConfig("tenant", denied))
+		is.True(errors.Is(err, s3manager.ErrInstanceUnreachable))
+
+		_, err = manager.AddInstance(context.Background(), fakeS3InstanceConfig("static", reachable))
+		is.True(errors.Is(err, s3manager.ErrInstanceExists))
+
+		var configErr *s3manager.ConfigError
+		_, err = manager.AddInstance(context.Background(), fakeS3InstanceConfig("", reachable))
+		is.True(errors.As(err, &configErr))
+
+		_, err = manager.AddInstance(context.Background(), fakeS3InstanceConfig("s", reachable))
+		is.True(errors.As(err, &configErr)) // the name is taken by share links
+		is.Equal("name", configErr.Field)
+
+		webIdentity := fakeS3InstanceConfig("tenant", reachable)
+		webIdentity.CredentialsProvider, webIdentity.WebIdentityTokenFile = s3manager.CredentialsWebIdentity, "/etc/passwd"
+		_, err = manager.AddInstance(context.Background(), webIdentity)
+		is.True(errors.As(err, &configErr)) // providers reading server files are not allowed at runtime
+		is.Equal("credentials_provider", configErr.Field)
+
+		_, err = manager.UpdateInstance(context.Background(), "static", fakeS3InstanceConfig("static", reachable))
+		is.True(errors.Is(err, s3manager.ErrInstanceNotManaged))
+		is.True(errors.Is(manager.RemoveInstance("static"), s3manager.ErrInstanceNotManaged))
+		is.True(errors.Is(manager.RemoveInstance("missing"), s3manager.ErrInstanceNotFound))
+
+		_, err = manager.AddInstance(context.Background(), fakeS3InstanceConfig("tenant", reachable))
+		is.NoErr(err)
+		_, err = manager.UpdateInstance(context.Background(), "tenant", fakeS3InstanceConfig("renamed", reachable))
+		is.True(errors.As(err, &configErr))
+
+		is.Equal(2, len(manager.GetAllInstances()))
+	})
+}
@@ -0,0 +1,419 @@
+package s3manager
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Paths handled by the OIDCAuthenticator itself.
+const (
+	OIDCCallbackPath = "/auth/callback"
+	OIDCLogoutPath   = "/auth/logout"
+)
+
+const (
+	sessionCookieName = "s3manager_session"
+	stateCookieName   = "s3manager_oidc_state"
+	stateTTL          = 10 * time.Minute
+	// maxCookieSize is the size of the largest cookie browsers are
+	// guaranteed to store.
+	maxCookieSize = 4096
+)
+
+// OIDCConfig holds the configuration for OpenID Connect authentication.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the externally reachable URL of OIDCCallbackPath.
+	RedirectURL string
+	Scopes      []string
+	// GroupsClaim is the userinfo claim that lists the user's groups.
+	GroupsClaim string
+	// Groups limits the groups stored in sessions to the listed ones, e.g.
+	// those role bindings refer to, if it is not nil. Other groups only
+	// make session cookies larger.
+	Groups        []string
+	SessionSecret []byte
+	SessionTTL    time.Duration
+	// RootURL is prepended to redirects within the application.
+	RootURL string
+}
+
+// OIDCAuthenticator authenticates users with the OpenID Connect
+// authorization code flow and keeps them logged in with a signed session cookie.
+type OIDCAuthenticator struct {
+	config                OIDCConfig
+	authorizationEndpoint string
+	tokenEndpoint         string
+	userinfoEndpoint      string
+	sessions              *sessionCodec
+	client                *http.Client
+}
+
+// oidcState is stored in a cookie for the duration of a login. Verifier is
+// the PKCE code verifier and Nonce is expected in the ID token.
+type oidcState struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"returnTo"`
+}
+
+// NewOIDCAuthenticator creates an OIDCAuthenticator, discovering the
+// provider's endpoints from its issuer URL.
+func NewOIDCAuthenticator(ctx context.Context, config OIDCConfig, client *http.Client) (*OIDCAuthenticator, error) {
+	if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("issuer URL, client ID and redirect URL are required")
+	}
+	if len(config.SessionSecret) < 32 {
+		return nil, fmt.Errorf("session secret must be at least 32 bytes long")
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = 8 * time.Hour
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	discoveryURL := strings.TrimSuffix(config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := getJSON(ctx, client, discoveryURL, "", &discovery); err != nil {
+		return nil, fmt.Errorf("error discovering OIDC provider: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(config.IssuerURL, "/") {
+		return nil, fmt.Errorf("OIDC provider reported issuer %s, expected %s", discovery.Issuer, config.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider does not advertise authorization, token and userinfo endpoints")
+	}
+
+	return &OIDCAuthenticator{
+		config:                config,
+		authorizationEndpoint: discovery.AuthorizationEndpoint,
+		tokenEndpoint:         discovery.TokenEndpoint,
+		userinfoEndpoint:      discovery.UserinfoEndpoint,
+		sessions:              newSessionCodec(config.SessionSecret),
+		client:                client,
+	}, nil
+}
+
+// Middleware implements Authenticator.
+func (a *OIDCAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case OIDCCallbackPath:
+			a.handleCallback(w, r)
+			return
+		case OIDCLogoutPath:
+			// Logging out by GET would let other sites log users out.
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+				return
+			}
+			a.handleLogout(w, r)
+			return
+		}
+
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			var user User
+			// Sessions are only created for users with a name.
+			if err := a.sessions.decode(purposeSession, cookie.Value, &user); err == nil && user.Name != "" {
+				next.ServeHTTP(w, r.WithContext(ContextWithUser(r.Context(), &user)))
+				return
+			}
+		}
+
+		// Only send browsers navigating to a page to the provider; API
+		// clients get a plain 401 instead of an HTML login page.
+		if r.Method != http.MethodGet || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		a.redirectToProvider(w, r)
+	})
+}
+
+// redirectToProvider starts the authorization code flow with PKCE (RFC 7636)
+// and a nonce.
+func (a *OIDCAuthenticator) redirectToProvider(w http.ResponseWriter, r *http.Request) {
+	state := oidcState{ReturnTo: a.config.RootURL + r.URL.RequestURI()}
+	for _, value := range []*string{&state.State, &state.Verifier, &state.Nonce} {
+		var err error
+		if *value, err = randomString(32); err != nil {
+			handleHTTPError(w, fmt.Errorf("error generating OIDC state: %w", err))
+			return
+		}
+	}
+	value, err := a.sessions.encode(purposeOIDCState, state, stateTTL)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	a.setCookie(w, r, stateCookieName, value, stateTTL)
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.config.ClientID},
+		"redirect_uri":          {a.config.RedirectURL},
+		"scope":                 {strings.Join(a.config.Scopes, " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, a.authorizationEndpoint+"?"+params.Encode(), http.StatusFound)
+}
+
+// handleCallback completes the authorization code flow and creates a session.
+func (a *OIDCAuthenticator) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		http.Error(w, fmt.Sprintf("login failed: %s", errCode), http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		http.Error(w, "login failed: missing state", http.StatusBadRequest)
+		return
+	}
+	var state oidcState
+	if err := a.sessions.decode(purposeOIDCState, cookie.Value, &state); err != nil || state.State != r.URL.Query().Get("state") {
+		http.Error(w, "login failed: invalid state", http.StatusBadRequest)
+		return
+	}
+	a.setCookie(w, r, stateCookieName, "", -1)
+
+	accessToken, err := a.exchangeCode(r.Context(), r.URL.Query().Get("code"), state)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "login failed: unable to exchange authorization code", http.StatusUnauthorized)
+		return
+	}
+	user, err := a.fetchUser(r.Context(), accessToken)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "login failed: unable to fetch user info", http.StatusUnauthorized)
+		return
+	}
+
+	if a.config.Groups != nil {
+		user.Groups = slices.DeleteFunc(user.Groups, func(group string) bool {
+			return !slices.Contains(a.config.Groups, group)
+		})
+	}
+	value, err := a.sessions.encode(purposeSession, user, a.config.SessionTTL)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	// Browsers silently drop larger cookies, which would make the login
+	// loop.
+	if len(sessionCookieName)+len(value) > maxCookieSize {
+		log.Printf("session of %s with %d groups exceeds the cookie size limit of %d bytes", user.Name, len(user.Groups), maxCookieSize)
+		http.Error(w, "login failed: too many groups to store in a session", http.StatusInternalServerError)
+		return
+	}
+	a.setCookie(w, r, sessionCookieName, value, a.config.SessionTTL)
+
+	returnTo := state.ReturnTo
+	// Only allow relative redirects to prevent open redirects.
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+		returnTo = a.config.RootURL + "/"
+	}
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+// handleLogout removes the session cookie.
+func (a *OIDCAuthenticator) handleLogout(w http.ResponseWriter, r *http.Request) {
+	a.setCookie(w, r, sessionCookieName, "", -1)
+	http.Redirect(w, r, a.config.RootURL+"/", http.StatusFound)
+}
+
+// exchangeCode trades an authorization code for an access token. The ID
+// token issued along with it must carry the nonce of state.
+func (a *OIDCAuthenticator) exchangeCode(ctx context.Context, code string, state oidcState) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {a.config.RedirectURL},
+		"code_verifier": {state.Verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token response did not contain an access token")
+	}
+	if err := a.verifyIDToken(token.IDToken, state.Nonce); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// verifyIDToken checks that idToken was issued by the provider to this
+// client for the login with nonce. Its signature isn't checked, as it was
+// received from the token endpoint directly, which OpenID Connect Core 1.0
+// section 3.1.3.7 allows.
+func (a *OIDCAuthenticator) verifyIDToken(idToken, nonce string) error {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return errors.New("token response did not contain an ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("error decoding ID token: %w", err)
+	}
+	var claims struct {
+		Issuer   string          `json:"iss"`
+		Audience json.RawMessage `json:"aud"`
+		Nonce    string          `json:"nonce"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("error decoding ID token: %w", err)
+	}
+
+	// The audience is either a single client ID or a list of them.
+	var audience []string
+	if err := json.Unmarshal(claims.Audience, &audience); err != nil {
+		audience = []string{""}
+		if err := json.Unmarshal(claims.Audience, &audience[0]); err != nil {
+			return fmt.Errorf("error decoding ID token audience: %w", err)
+		}
+	}
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(a.config.IssuerURL, "/"):
+		return fmt.Errorf("ID token was issued by %s, expected %s", claims.Issuer, a.config.IssuerURL)
+	case !slices.Contains(audience, a.config.ClientID):
+		return fmt.Errorf("ID token was not issued to client %s", a.config.ClientID)
+	case claims.Nonce != nonce:
+		return errors.New("ID token does not carry the nonce of the login")
+	}
+	return nil
+}
+
+// fetchUser looks up the user that owns accessToken at the userinfo endpoint.
+func (a *OIDCAuthenticator) fetchUser(ctx context.Context, accessToken string) (*User, error) {
+	var claims map[string]any
+	if err := getJSON(ctx, a.client, a.userinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("error fetching user info: %w", err)
+	}
+
+	user := &User{}
+	for _, claim := range []string{"preferred_username", "email", "sub"} {
+		if name, ok := claims[claim].(string); ok && name != "" {
+			user.Name = name
+			break
+		}
+	}
+	if user.Name == "" {
+		return nil, errors.New("user info does not identify the user")
+	}
+
+	if groups, ok := claims[a.config.GroupsClaim].([]any); ok {
+		for _, group := range groups {
+			if g, ok := group.(string); ok {
+				user.Groups = append(user.Groups, g)
+			}
+		}
+	}
+	return user, nil
+}
+
+func (a *OIDCAuthenticator) setCookie(w http.ResponseWriter, r *http.Request, name, value string, ttl time.Duration) {
+	path := a.config.RootURL
+	if path == "" {
+		path = "/"
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(a.config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// getJSON fetches url and decodes its JSON body into dst, authenticating
+// with bearerToken if it is set.
+func getJSON(ctx context.Context, client *http.Client, url, bearerToken string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// randomString returns a URL-safe random string built from n random bytes.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,420 @@
+package s3manager_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+// newFakeOIDCProvider starts a minimal OpenID Connect provider. Its
+// authorization endpoint issues a code for the given PKCE challenge and
+// nonce, which its token endpoint exchanges for tokens identifying alice and
+// her groups.
+func newFakeOIDCProvider(t *testing.T, groups ...string) *httptest.Server {
+	t.Helper()
+
+	if groups == nil {
+		groups = []string{"data-team"}
+	}
+	type login struct{ challenge, nonce string }
+	var (
+		mu     sync.Mutex
+		logins = map[string]login{}
+	)
+
+	mux := http.NewServeMux()
+	var ts *httptest.Server
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 ts.URL,
+			"authorization_endpoint": ts.URL + "/authorize",
+			"token_endpoint":         ts.URL + "/token",
+			"userinfo_endpoint":      ts.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("code_challenge_method") != "S256" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		code := fmt.Sprintf("CODE-%d", len(logins))
+		logins[code] = login{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+		mu.Unlock()
+		http.Redirect(w, r, query.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {query.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		login, ok := logins[r.FormValue("code")]
+		mu.Unlock()
+		verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		clientID, clientSecret, _ := r.BasicAuth()
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != login.challenge || clientID != "client" || clientSecret != "client-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		claims, _ := json.Marshal(map[string]any{"iss": ts.URL, "aud": "client", "sub": "1234", "nonce": login.nonce})
+		idToken := "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "ACCESS-TOKEN", "token_type": "Bearer", "id_token": idToken})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ACCESS-TOKEN" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"sub":                "1234",
+			"preferred_username": "alice",
+			"groups":             groups,
+		})
+	})
+	ts = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// authorize sends a browser that was redirected to the provider of
+// location back to the callback, optionally changing the authorization
+// request first. It returns the callback URL.
+func authorize(t *testing.T, location string, change func(url.Values)) string {
+	t.Helper()
+
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != nil {
+		query := u.Query()
+		change(query)
+		u.RawQuery = query.Encode()
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback.RequestURI()
+}
+
+// newTestOIDCAuthenticator creates an OIDCAuthenticator for the provider at
+// issuerURL that keeps only groups in sessions, if any are given.
+func newTestOIDCAuthenticator(t *testing.T, issuerURL string, groups ...string) *s3manager.OIDCAuthenticator {
+	t.Helper()
+
+	auth, err := s3manager.NewOIDCAuthenticator(context.Background(), s3manager.OIDCConfig{
+		IssuerURL:     issuerURL,
+		ClientID:      "client",
+		ClientSecret:  "client-secret",
+		RedirectURL:   "http://s3manager.example.com/auth/callback",
+		Groups:        groups,
+		SessionSecret: []byte(strings.Repeat("s", 32)),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func TestNewOIDCAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	provider := newFakeOIDCProvider(t)
+
+	cases := []struct {
+		it          string
+		config      s3manager.OIDCConfig
+		expectError bool
+	}{
+		{
+			it: "discovers the provider endpoints",
+			config: s3manager.OIDCConfig{
+				IssuerURL:     provider.URL,
+				ClientID:      "client",
+				RedirectURL:   "http://s3manager.example.com/auth/callback",
+				SessionSecret: []byte(strings.Repeat("s", 32)),
+			},
+		},
+		{
+			it: "returns error for a short session secret",
+			config: s3manager.OIDCConfig{
+				IssuerURL:     provider.URL,
+				ClientID:      "client",
+				RedirectURL:   "http://s3manager.example.com/auth/callback",
+				SessionSecret: []byte("short"),
+			},
+			expectError: true,
+		},
+		{
+			it: "returns error if the issuer does not match",
+			config: s3manager.OIDCConfig{
+				IssuerURL:     provider.URL + "/other",
+				ClientID:      "client",
+				RedirectURL:   "http://s3manager.example.com/auth/callback",
+				SessionSecret: []byte(strings.Repeat("s", 32)),
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			auth, err := s3manager.NewOIDCAuthenticator(context.Background(), tc.config, nil)
+			if tc.expectError {
+				is.True(err != nil)
+				is.True(auth == nil)
+			} else {
+				is.NoErr(err)
+				is.True(auth != nil)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticatorMiddleware(t *testing.T) {
+	t.Parallel()
+
+	provider := newFakeOIDCProvider(t)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s3manager.UserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		_, _ = io.WriteString(w, user.Name+" "+strings.Join(user.Groups, ","))
+	})
+
+	t.Run("rejects unauthenticated API requests", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+		req := httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/b", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("rejects a callback with a mismatching state", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+
+		req := httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(http.StatusFound, rr.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/auth/callback?code=CODE&state=forged", nil)
+		for _, c := range rr.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(http.StatusBadRequest, rr.Code)
+		is.True(strings.Contains(rr.Body.String(), "invalid state"))
+	})
+
+	t.Run("rejects a state cookie as session", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+
+		req := httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(http.StatusFound, rr.Code)
+
+		var state *http.Cookie
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == "s3manager_oidc_state" {
+				state = c
+			}
+		}
+		is.True(state != nil)
+
+		req = httptest.NewRequest(http.MethodGet, "/primary/api/buckets", nil)
+		req.AddCookie(&http.Cookie{Name: "s3manager_session", Value: state.Value})
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		is.Equal(http.StatusUnauthorized, rr.Code) // state is signed for another purpose
+	})
+
+	t.Run("logs a browser in through the provider", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+
+		// Unauthenticated page views are redirected to the provider.
+		req := httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(http.StatusFound, rr.Code)
+		location, err := url.Parse(rr.Header().Get("Location"))
+		is.NoErr(err)
+		is.Equal(provider.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+		is.Equal("client", location.Query().Get("client_id"))
+		is.True(location.Query().Get("state") != "")
+		is.True(location.Query().Get("nonce") != "")
+		is.Equal("S256", location.Query().Get("code_challenge_method"))
+
+		// The provider redirects back to the callback with a code.
+		req = httptest.NewRequest(http.MethodGet, authorize(t, location.String(), nil), nil)
+		for _, c := range rr.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(http.StatusFound, rr.Code)
+		is.Equal("/primary/buckets", rr.Header().Get("Location"))
+
+		var session *http.Cookie
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == "s3manager_session" {
+				session = c
+			}
+		}
+		is.True(session != nil)
+
+		// The session cookie identifies the user on subsequent requests.
+		req = httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		req.AddCookie(session)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(http.StatusOK, rr.Code)
+		is.Equal("alice data-team", rr.Body.String())
+
+		// A tampered session cookie is not accepted.
+		req = httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		req.AddCookie(&http.Cookie{Name: session.Name, Value: "x" + session.Value})
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal(http.StatusUnauthorized, rr.Code)
+	})
+	// login redirects a browser to the provider of auth, changing the
+	// authorization request with change, and returns the response to the
+	// callback.
+	login := func(t *testing.T, handler http.Handler, change func(url.Values)) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		req = httptest.NewRequest(http.MethodGet, authorize(t, rr.Header().Get("Location"), change), nil)
+		for _, c := range rr.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		callback := httptest.NewRecorder()
+		handler.ServeHTTP(callback, req)
+		return callback
+	}
+
+	t.Run("rejects a login with another code challenge", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+		rr := login(t, handler, func(query url.Values) {
+			query.Set("code_challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+		})
+
+		is.Equal(http.StatusUnauthorized, rr.Code) // an intercepted code is useless without the verifier
+		is.True(strings.Contains(rr.Body.String(), "unable to exchange authorization code"))
+	})
+
+	t.Run("rejects an ID token with another nonce", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+		rr := login(t, handler, func(query url.Values) { query.Set("nonce", "replayed") })
+
+		is.Equal(http.StatusUnauthorized, rr.Code)
+		is.True(strings.Contains(rr.Body.String(), "unable to exchange authorization code"))
+	})
+
+	t.Run("keeps only the listed groups in the session", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		provider := newFakeOIDCProvider(t, "data-team", "everyone", "building-7")
+		handler := newTestOIDCAuthenticator(t, provider.URL, "data-team", "admins").Middleware(next)
+		rr := login(t, handler, nil)
+		is.Equal(http.StatusFound, rr.Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/primary/buckets", nil)
+		for _, c := range rr.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		is.Equal("alice data-team", rr.Body.String())
+	})
+
+	t.Run("fails a login whose session does not fit in a cookie", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		groups := make([]string, 200)
+		for i := range groups {
+			groups[i] = fmt.Sprintf("department-%03d-members", i)
+		}
+		provider := newFakeOIDCProvider(t, groups...)
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+		rr := login(t, handler, nil)
+
+		is.Equal(http.StatusInternalServerError, rr.Code)
+		is.True(strings.Contains(rr.Body.String(), "too many groups"))
+	})
+
+	t.Run("logs out by POST only", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		handler := newTestOIDCAuthenticator(t, provider.URL).Middleware(next)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/logout", nil))
+		is.Equal(http.StatusMethodNotAllowed, rr.Code) // links on other sites can't log users out
+		is.Equal(0, len(rr.Result().Cookies()))
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/logout", nil))
+		is.Equal(http.StatusFound, rr.Code)
+		is.Equal(1, len(rr.Result().Cookies()))
+		is.Equal(-1, rr.Result().Cookies()[0].MaxAge) // session cookie is removed
+	})
+}
@@ -0,0 +1,176 @@
+package s3manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// PresignedPost is a presigned POST policy. Uploading a file means sending a
+// multipart form with the form data and the file as last field, named "file",
+// to the URL.
+type PresignedPost struct {
+	URL      string            `json:"url"`
+	FormData map[string]string `json:"form_data"`
+}
+
+// HandleGenerateUploadURL generates a presigned URL valid for at most
+// maxExpiry an object can be uploaded to with a PUT request. A PUT link can't
+// make clients send encryption headers, so it is refused for buckets that are
+// encrypted by s3manager.
+func HandleGenerateUploadURL(s3 S3, sseInfo SSEType, maxExpiry time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucketName"]
+		objectName := mux.Vars(r)["objectName"]
+
+		if !presigningAllowed(w, sseInfo) {
+			return
+		}
+		if sseInfo.Type != "" {
+			http.Error(w, fmt.Sprintf("upload links are not supported for objects encrypted with %s, use an upload policy instead", sseInfo.Type), http.StatusBadRequest)
+			return
+		}
+		expiry, err := parseExpiry(r.URL.Query().Get("expiry"), maxExpiry)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		url, err := s3.PresignedPutObject(r.Context(), bucketName, objectName, expiry)
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error generating url: %w", err))
+			return
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(map[string]string{"url": url.String()})
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error encoding JSON: %w", err))
+			return
+		}
+	}
+}
+
+// HandleGenerateUploadPolicy generates a presigned POST policy that lets
+// browsers upload objects below a key prefix directly to S3, optionally
+// restricted in size and content type. The policy is valid for at most
+// maxExpiry.
+func HandleGenerateUploadPolicy(s3 S3, sseInfo SSEType, maxExpiry time.Duration) http.HandlerFunc {
+	type request struct {
+		KeyPrefix         string `json:"key_prefix"`
+		Expiry            int64  `json:"expiry"`
+		MinSize           int64  `json:"min_size"`
+		MaxSize           int64  `json:"max_size"`
+		ContentType       string `json:"content_type"`
+		ContentTypePrefix string `json:"content_type_prefix"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucketName"]
+
+		if !presigningAllowed(w, sseInfo) {
+			return
+		}
+
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+			return
+		}
+		expiry, err := validateExpiry(req.Expiry, maxExpiry)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		if req.ContentType != "" && req.ContentTypePrefix != "" {
+			http.Error(w, "content_type and content_type_prefix cannot be combined", http.StatusBadRequest)
+			return
+		}
+		if req.MinSize < 0 || req.MaxSize < 0 || req.MaxSize > 0 && req.MinSize > req.MaxSize {
+			http.Error(w, "min_size and max_size must be a valid range", http.StatusBadRequest)
+			return
+		}
+
+		policy := minio.NewPostPolicy()
+		err = setPostPolicyConditions(policy, bucketName, req.KeyPrefix, time.Now().UTC().Add(expiry))
+		if err == nil && req.ContentType != "" {
+			err = policy.SetContentType(req.ContentType)
+		}
+		if err == nil && req.ContentTypePrefix != "" {
+			err = policy.SetContentTypeStartsWith(req.ContentTypePrefix)
+		}
+		if err == nil && req.MaxSize > 0 {
+			err = policy.SetContentLengthRange(req.MinSize, req.MaxSize)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := setPostPolicyEncryption(policy, sseInfo); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		url, formData, err := s3.PresignedPostPolicy(r.Context(), policy)
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error generating policy: %w", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(PresignedPost{URL: url.String(), FormData: formData})
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error encoding JSON: %w", err))
+			return
+		}
+	}
+}
+
+// setPostPolicyConditions restricts policy to keys below keyPrefix in bucket
+// until expiry. The key field of the form data is set to keyPrefix, so
+// clients have to append the object name, or ${filename} to use the name of
+// the uploaded file.
+func setPostPolicyConditions(policy *minio.PostPolicy, bucketName, keyPrefix string, expiry time.Time) error {
+	if err := policy.SetBucket(bucketName); err != nil {
+		return err
+	}
+	if err := policy.SetExpires(expiry); err != nil {
+		return err
+	}
+	if strings.HasPrefix(keyPrefix, "/") {
+		return fmt.Errorf("key_prefix must not start with a slash")
+	}
+	// An empty prefix allows uploading any key.
+	if err := policy.SetKeyStartsWith(keyPrefix); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setPostPolicyEncryption makes policy require the encryption headers of
+// sseInfo, so S3 accepts them in the form data and clients can't leave them
+// out.
+func setPostPolicyEncryption(policy *minio.PostPolicy, sseInfo SSEType) error {
+	sse, err := serverSideEncryption(sseInfo)
+	if err != nil || sse == nil {
+		return err
+	}
+	headers := http.Header{}
+	sse.Marshal(headers)
+	for name := range headers {
+		// SetUserData adds the x-amz- prefix again.
+		field := strings.TrimPrefix(strings.ToLower(name), "x-amz-")
+		if err := policy.SetUserData(field, headers.Get(name)); err != nil {
+			return fmt.Errorf("error setting encryption of policy: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package s3manager_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/cloudlena/s3manager/internal/app/s3manager/mocks"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func TestHandleGenerateUploadURL(t *testing.T) {
+	t.Parallel()
+
+	presignedURL, _ := url.Parse("https://s3.example.com/bucket/incoming/report.pdf?X-Amz-Signature=abc123")
+
+	cases := []struct {
+		it                   string
+		expiry               string
+		sseInfo              s3manager.SSEType
+		expectedStatusCode   int
+		expectedBodyContains string
+	}{
+		{
+			it:                   "generates a presigned upload URL",
+			expiry:               "3600",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: "https://s3.example.com/bucket/incoming/report.pdf?X-Amz-Signature=abc123",
+		},
+		{
+			it:                   "returns error when expiry exceeds 7 days",
+			expiry:               "604801",
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "invalid expiry value",
+		},
+		{
+			it:                   "refuses instances encrypting with a customer provided key",
+			expiry:               "3600",
+			sseInfo:              s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "not supported for objects encrypted with SSE-C",
+		},
+		{
+			it:                   "refuses instances encrypting with KMS",
+			expiry:               "3600",
+			sseInfo:              s3manager.SSEType{Type: "KMS", Key: "key-id"},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "upload links are not supported for objects encrypted with KMS",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3 := &mocks.S3Mock{
+				PresignedPutObjectFunc: func(_ context.Context, bucketName, objectName string, expiry time.Duration) (*url.URL, error) {
+					is.Equal("bucket", bucketName)
+					is.Equal("incoming/report.pdf", objectName)
+					is.Equal(time.Hour, expiry)
+					return presignedURL, nil
+				},
+			}
+
+			r := mux.NewRouter()
+			r.Handle("/buckets/{bucketName}/objects/{objectName:.*}/upload-url", s3manager.HandleGenerateUploadURL(s3, tc.sseInfo, 0)).Methods(http.MethodGet)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/bucket/objects/incoming/report.pdf/upload-url?expiry="+tc.expiry, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+		})
+	}
+}
+
+func TestHandleGenerateUploadPolicy(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		body                 string
+		sseInfo              s3manager.SSEType
+		expectedStatusCode   int
+		expectedConditions   []string
+		expectedFormData     map[string]string
+		expectedBodyContains string
+	}{
+		{
+			it:                 "generates a policy for a key prefix",
+			body:               `{"key_prefix": "incoming/partner/", "expiry": 3600}`,
+			expectedStatusCode: http.StatusOK,
+			expectedConditions: []string{`["starts-with","$key","incoming/partner/"]`, `["eq","$bucket","bucket"]`},
+			expectedFormData:   map[string]string{"key": "incoming/partner/", "bucket": "bucket"},
+		},
+		{
+			it:                 "restricts size and content type",
+			body:               `{"key_prefix": "images/", "expiry": 3600, "min_size": 1, "max_size": 10485760, "content_type_prefix": "image/"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedConditions: []string{`["content-length-range", 1, 10485760]`, `["starts-with","$Content-Type","image/"]`},
+			expectedFormData:   map[string]string{"key": "images/"},
+		},
+		{
+			it:                 "stores objects with the instance's encryption",
+			body:               `{"key_prefix": "images/", "expiry": 3600}`,
+			sseInfo:            s3manager.SSEType{Type: "SSE"},
+			expectedStatusCode: http.StatusOK,
+			expectedConditions: []string{`["eq","$x-amz-server-side-encryption","AES256"]`},
+			expectedFormData:   map[string]string{"x-amz-server-side-encryption": "AES256"},
+		},
+		{
+			it:                 "requires the instance's KMS key",
+			body:               `{"key_prefix": "images/", "expiry": 3600}`,
+			sseInfo:            s3manager.SSEType{Type: "KMS", Key: "key-id"},
+			expectedStatusCode: http.StatusOK,
+			expectedConditions: []string{`["eq","$x-amz-server-side-encryption","aws:kms"]`, `["eq","$x-amz-server-side-encryption-aws-kms-key-id","key-id"]`},
+			expectedFormData:   map[string]string{"x-amz-server-side-encryption": "aws:kms", "x-amz-server-side-encryption-aws-kms-key-id": "key-id"},
+		},
+		{
+			it:                   "rejects an invalid size range",
+			body:                 `{"expiry": 3600, "min_size": 100, "max_size": 10}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "min_size and max_size must be a valid range",
+		},
+		{
+			it:                   "rejects combining content type conditions",
+			body:                 `{"expiry": 3600, "content_type": "image/png", "content_type_prefix": "image/"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "content_type and content_type_prefix cannot be combined",
+		},
+		{
+			it:                   "returns error when expiry exceeds the instance's maximum",
+			body:                 `{"key_prefix": "images/", "expiry": 3601}`,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "invalid expiry value",
+		},
+		{
+			it:                   "returns error when expiry is missing",
+			body:                 `{"key_prefix": "images/"}`,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "invalid expiry value",
+		},
+		{
+			it:                   "refuses instances encrypting with a customer provided key",
+			body:                 `{"key_prefix": "images/", "expiry": 3600}`,
+			sseInfo:              s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "not supported for objects encrypted with SSE-C",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			// Policies are signed locally, so a real client can be used.
+			client, err := minio.New("s3.example.com", &minio.Options{
+				Creds:  credentials.NewStaticV4("key", "secret", ""),
+				Secure: true,
+				Region: "us-east-1",
+			})
+			is.NoErr(err)
+			s3 := &mocks.S3Mock{PresignedPostPolicyFunc: client.PresignedPostPolicy}
+
+			r := mux.NewRouter()
+			r.Handle("/buckets/{bucketName}/upload-policy", s3manager.HandleGenerateUploadPolicy(s3, tc.sseInfo, time.Hour)).Methods(http.MethodPost)
+
+			req := httptest.NewRequest(http.MethodPost, "/buckets/bucket/upload-policy", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+			if tc.expectedStatusCode == http.StatusOK {
+				var post s3manager.PresignedPost
+				is.NoErr(json.NewDecoder(rr.Body).Decode(&post))
+				is.Equal("https://s3.example.com/bucket/", post.URL)
+				for k, v := range tc.expectedFormData {
+					is.Equal(v, post.FormData[k])
+				}
+				policy, err := base64.StdEncoding.DecodeString(post.FormData["policy"])
+				is.NoErr(err)
+				for _, condition := range tc.expectedConditions {
+					is.True(strings.Contains(string(policy), condition))
+				}
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
 )
 
 //go:generate moq -out mocks/s3.go -pkg mocks . S3
@@ -15,21 +16,69 @@ import (
 type S3 interface {
 	GetObject(ctx context.Context, bucketName, objectName string, opts minio.GetObjectOptions) (*minio.Object, error)
 	StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+	CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error)
+	ComposeObject(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error)
+	GetObjectTagging(ctx context.Context, bucketName, objectName string, opts minio.GetObjectTaggingOptions) (*tags.Tags, error)
 	ListBuckets(ctx context.Context) ([]minio.BucketInfo, error)
 	ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
 	MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error
 	PresignedGetObject(ctx context.Context, bucketName, objectName string, expiry time.Duration, reqParams url.Values) (*url.URL, error)
+	PresignedPutObject(ctx context.Context, bucketName, objectName string, expiry time.Duration) (*url.URL, error)
+	PresignedPostPolicy(ctx context.Context, policy *minio.PostPolicy) (*url.URL, map[string]string, error)
 	PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error)
 	RemoveBucket(ctx context.Context, bucketName string) error
 	RemoveObject(ctx context.Context, bucketName, objectName string, opts minio.RemoveObjectOptions) error
 	GetBucketPolicy(ctx context.Context, bucketName string) (string, error)
 	SetBucketPolicy(ctx context.Context, bucketName string, policy string) error
 	RemoveObjects(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError
+	RemoveObjectsWithResult(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectResult
+	NewMultipartUpload(ctx context.Context, bucket, object string, opts minio.PutObjectOptions) (string, error)
+	PutObjectPart(ctx context.Context, bucket, object, uploadID string, partID int, data io.Reader, size int64, opts minio.PutObjectPartOptions) (minio.ObjectPart, error)
+	ListObjectParts(ctx context.Context, bucket, object, uploadID string, partNumberMarker, maxParts int) (minio.ListObjectPartsResult, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, object, uploadID string, parts []minio.CompletePart, opts minio.PutObjectOptions) (minio.UploadInfo, error)
+	AbortMultipartUpload(ctx context.Context, bucket, object, uploadID string) error
+	ListMultipartUploads(ctx context.Context, bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (minio.ListMultipartUploadsResult, error)
 	EndpointURL() *url.URL
 }
 
-// SSEType describes a type of server side encryption.
-type SSEType struct {
-	Type string
-	Key  string
+// minioS3 implements S3 with minio-go. The low-level multipart upload API is
+// only offered by minio.Core.
+type minioS3 struct {
+	*minio.Client
+	core minio.Core
+}
+
+// newMinioS3 wraps client so that it implements S3.
+func newMinioS3(client *minio.Client) *minioS3 {
+	return &minioS3{Client: client, core: minio.Core{Client: client}}
+}
+
+// NewMultipartUpload implements S3.
+func (c *minioS3) NewMultipartUpload(ctx context.Context, bucket, object string, opts minio.PutObjectOptions) (string, error) {
+	return c.core.NewMultipartUpload(ctx, bucket, object, opts)
+}
+
+// PutObjectPart implements S3.
+func (c *minioS3) PutObjectPart(ctx context.Context, bucket, object, uploadID string, partID int, data io.Reader, size int64, opts minio.PutObjectPartOptions) (minio.ObjectPart, error) {
+	return c.core.PutObjectPart(ctx, bucket, object, uploadID, partID, data, size, opts)
+}
+
+// ListObjectParts implements S3.
+func (c *minioS3) ListObjectParts(ctx context.Context, bucket, object, uploadID string, partNumberMarker, maxParts int) (minio.ListObjectPartsResult, error) {
+	return c.core.ListObjectParts(ctx, bucket, object, uploadID, partNumberMarker, maxParts)
+}
+
+// CompleteMultipartUpload implements S3.
+func (c *minioS3) CompleteMultipartUpload(ctx context.Context, bucket, object, uploadID string, parts []minio.CompletePart, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	return c.core.CompleteMultipartUpload(ctx, bucket, object, uploadID, parts, opts)
+}
+
+// AbortMultipartUpload implements S3.
+func (c *minioS3) AbortMultipartUpload(ctx context.Context, bucket, object, uploadID string) error {
+	return c.core.AbortMultipartUpload(ctx, bucket, object, uploadID)
+}
+
+// ListMultipartUploads implements S3.
+func (c *minioS3) ListMultipartUploads(ctx context.Context, bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (minio.ListMultipartUploadsResult, error) {
+	return c.core.ListMultipartUploads(ctx, bucket, prefix, keyMarker, uploadIDMarker, delimiter, maxUploads)
 }
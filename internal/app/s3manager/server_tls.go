@@ -0,0 +1,162 @@
+package s3manager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// certificateCheckInterval is how often a CertificateReloader checks its
+// files for changes.
+const certificateCheckInterval = time.Minute
+
+// CertificateReloader serves a TLS certificate from files and reloads it when
+// they change, so renewed certificates are used without a restart.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	certificate atomic.Pointer[tls.Certificate]
+	// mu serializes reloads and guards modTimes.
+	mu       sync.Mutex
+	modTimes [2]time.Time
+}
+
+// NewCertificateReloader loads the certificate and key from the given PEM
+// files.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It can be used as
+// tls.Config.GetCertificate and doesn't touch the files, which Run checks in
+// the background.
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate.Load(), nil
+}
+
+// Run checks the files for changes every minute until ctx is done. If they
+// changed but cannot be loaded, the error is logged and the current
+// certificate is kept.
+func (r *CertificateReloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(certificateCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				log.Printf("error reloading TLS certificate, keeping the current one: %v", err)
+			}
+		}
+	}
+}
+
+// Reload loads the certificate if its files changed since it was last
+// loaded.
+func (r *CertificateReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var modTimes [2]time.Time
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("error reading TLS certificate: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	current := r.certificate.Load()
+	if current != nil && modTimes == r.modTimes {
+		return nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %w", err)
+	}
+	if current != nil {
+		log.Printf("Reloaded TLS certificate from %s", r.certFile)
+	}
+	r.certificate.Store(&certificate)
+	r.modTimes = modTimes
+	return nil
+}
+
+// SelfSignedCertificate creates a certificate for hosts that is only valid for
+// a year and signed by itself. It is meant for development, as browsers warn
+// about it.
+func SelfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error generating key: %w", err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error generating serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"S3 Manager"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error creating certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// HandleHTTPSRedirect redirects every request to the same URL on the HTTPS
+// listener at port. Requests without a host can't be redirected.
+func HandleHTTPSRedirect(port string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Trim(r.Host, "[]")
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		switch {
+		case host == "":
+			http.Error(w, "missing Host header", http.StatusBadRequest)
+			return
+		case port != "443":
+			host = net.JoinHostPort(host, port)
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	}
+}
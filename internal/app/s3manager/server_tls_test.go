@@ -0,0 +1,154 @@
+package s3manager_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	// writeCertificate writes a new certificate for host, dated at modTime.
+	writeCertificate := func(host string, modTime time.Time) {
+		certificate, err := s3manager.SelfSignedCertificate(host)
+		is.NoErr(err)
+		key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+		is.NoErr(err)
+		is.NoErr(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}), 0o600))
+		is.NoErr(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600))
+		is.NoErr(os.Chtimes(certFile, modTime, modTime))
+		is.NoErr(os.Chtimes(keyFile, modTime, modTime))
+	}
+	// servedHost returns the host the current certificate is valid for.
+	servedHost := func(reloader *s3manager.CertificateReloader) string {
+		certificate, err := reloader.GetCertificate(nil)
+		is.NoErr(err)
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		is.NoErr(err)
+		return leaf.DNSNames[0]
+	}
+
+	_, err := s3manager.NewCertificateReloader(certFile, keyFile)
+	is.True(err != nil) // files don't exist yet
+
+	start := time.Now().Add(-time.Hour)
+	writeCertificate("first.example.com", start)
+	reloader, err := s3manager.NewCertificateReloader(certFile, keyFile)
+	is.NoErr(err)
+	is.Equal("first.example.com", servedHost(reloader))
+
+	writeCertificate("second.example.com", start.Add(time.Minute))
+	is.Equal("first.example.com", servedHost(reloader)) // handshakes don't check the files
+	is.NoErr(reloader.Reload())
+	is.Equal("second.example.com", servedHost(reloader))
+
+	is.NoErr(os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	is.NoErr(os.Chtimes(certFile, start.Add(2*time.Minute), start.Add(2*time.Minute)))
+	is.True(reloader.Reload() != nil)
+	is.Equal("second.example.com", servedHost(reloader)) // keeps the current certificate
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	certificate, err := s3manager.SelfSignedCertificate("localhost", "127.0.0.1")
+	is.NoErr(err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	is.NoErr(err)
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12},
+		ForceAttemptHTTP2: true,
+	}}
+
+	resp, err := client.Get(ts.URL)
+	is.NoErr(err)
+	defer func() { _ = resp.Body.Close() }()
+	is.Equal(http.StatusNoContent, resp.StatusCode)
+	is.Equal(2, resp.ProtoMajor) // served with HTTP/2
+}
+
+func TestHandleHTTPSRedirect(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                 string
+		port               string
+		url                string
+		host               string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			it:                 "redirects to the HTTPS port",
+			port:               "8443",
+			url:                "http://s3manager.example.com:8080/prod/buckets?q=1",
+			expectedStatusCode: http.StatusPermanentRedirect,
+			expectedLocation:   "https://s3manager.example.com:8443/prod/buckets?q=1",
+		},
+		{
+			it:                 "omits the default HTTPS port",
+			port:               "443",
+			url:                "http://s3manager.example.com/",
+			expectedStatusCode: http.StatusPermanentRedirect,
+			expectedLocation:   "https://s3manager.example.com/",
+		},
+		{
+			it:                 "keeps IPv6 hosts intact",
+			port:               "443",
+			url:                "http://[::1]:8080/healthz",
+			expectedStatusCode: http.StatusPermanentRedirect,
+			expectedLocation:   "https://[::1]/healthz",
+		},
+		{
+			it:                 "rejects requests without a host",
+			port:               "8443",
+			url:                "http://s3manager.example.com/",
+			host:               ":8080",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.host != "" {
+				req.Host = tc.host
+			}
+			rr := httptest.NewRecorder()
+			s3manager.HandleHTTPSRedirect(tc.port).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.Equal(tc.expectedLocation, rr.Header().Get("Location"))
+		})
+	}
+}
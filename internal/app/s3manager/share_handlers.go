@@ -0,0 +1,316 @@
+package s3manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ShareLink describes a share link to the users who manage it.
+type ShareLink struct {
+	Token             string    `json:"token"`
+	Key               string    `json:"key"`
+	VersionID         string    `json:"version_id,omitempty"`
+	CreatedBy         string    `json:"created_by,omitempty"`
+	Created           time.Time `json:"created"`
+	Expires           time.Time `json:"expires"`
+	PasswordProtected bool      `json:"password_protected"`
+	MaxDownloads      int       `json:"max_downloads,omitempty"`
+	Downloads         int       `json:"downloads"`
+}
+
+func newShareLink(share Share) ShareLink {
+	return ShareLink{
+		Token:             share.Token,
+		Key:               share.Key,
+		VersionID:         share.VersionID,
+		CreatedBy:         share.CreatedBy,
+		Created:           share.Created,
+		Expires:           share.Expires,
+		PasswordProtected: share.PasswordHash != "",
+		MaxDownloads:      share.MaxDownloads,
+		Downloads:         share.Downloads,
+	}
+}
+
+// withShares resolves the instance and authorizes the user like withInstance
+// and delegates to a handler that receives the instance and the share links.
+// It responds with 404 if no share store is used.
+func withShares(manager *MultiS3Manager, required Role, fn func(*S3Instance, *shareRegistry) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shares := manager.shares.Load()
+		if shares == nil {
+			http.Error(w, "share links are not enabled", http.StatusNotFound)
+			return
+		}
+		current, ok := resolveInstance(manager, w, r)
+		if !ok {
+			return
+		}
+		if !authorize(manager, w, r, current, mux.Vars(r)["bucketName"], required) {
+			return
+		}
+		fn(current, shares)(w, r)
+	}
+}
+
+// HandleCreateShareWithManager creates a share link to an object. The JSON
+// body takes the key, optionally a version_id, the expiry in seconds and
+// optionally a password and max_downloads. The expiry is limited like the one
+// of presigned URLs.
+func HandleCreateShareWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	type request struct {
+		Key          string `json:"key"`
+		VersionID    string `json:"version_id"`
+		Expiry       int64  `json:"expiry"`
+		Password     string `json:"password"`
+		MaxDownloads int    `json:"max_downloads"`
+	}
+
+	return withShares(manager, RoleViewer, func(current *S3Instance, shares *shareRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			bucketName := mux.Vars(r)["bucketName"]
+			maxExpiry := presignExpiryLimit(current.Features.MaxPresignExpiry)
+
+			var req request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+				return
+			}
+			switch {
+			case req.Key == "":
+				http.Error(w, "key must not be empty", http.StatusBadRequest)
+				return
+			case req.Expiry < 1:
+				http.Error(w, "expiry must be a positive number of seconds", http.StatusBadRequest)
+				return
+			case req.Expiry > int64(maxExpiry/time.Second):
+				http.Error(w, fmt.Sprintf("expiry must not exceed %d seconds", int64(maxExpiry/time.Second)), http.StatusBadRequest)
+				return
+			case req.MaxDownloads < 0:
+				http.Error(w, "max_downloads must not be negative", http.StatusBadRequest)
+				return
+			case req.VersionID != "" && !current.Features.ShowVersions:
+				http.Error(w, "version_id requires versions to be shown for the instance", http.StatusBadRequest)
+				return
+			}
+
+			sse, err := readEncryption(current.Features.SSEFor(bucketName))
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			_, err = current.Client.StatObject(r.Context(), bucketName, req.Key, minio.StatObjectOptions{VersionID: req.VersionID, ServerSideEncryption: sse})
+			if err != nil {
+				handleHTTPError(w, fmt.Errorf("error getting object: %w", err))
+				return
+			}
+
+			token, err := newShareToken()
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+			now := time.Now().UTC()
+			share := Share{
+				Token:        token,
+				Instance:     current.Name,
+				Bucket:       bucketName,
+				Key:          req.Key,
+				VersionID:    req.VersionID,
+				Created:      now,
+				Expires:      now.Add(time.Duration(req.Expiry) * time.Second),
+				MaxDownloads: req.MaxDownloads,
+			}
+			if user, ok := UserFromContext(r.Context()); ok {
+				share.CreatedBy = user.Name
+			}
+			if req.Password != "" {
+				hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+				if err != nil {
+					handleHTTPError(w, fmt.Errorf("error hashing password: %w", err))
+					return
+				}
+				share.PasswordHash = string(hash)
+			}
+
+			if err := shares.add(share); err != nil {
+				handleHTTPError(w, fmt.Errorf("error storing share: %w", err))
+				return
+			}
+			writeJSON(w, http.StatusCreated, newShareLink(share))
+		}
+	})
+}
+
+// HandleListSharesWithManager lists the active share links of a bucket.
+func HandleListSharesWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withShares(manager, RoleViewer, func(current *S3Instance, shares *shareRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			active := shares.list(current.Name, mux.Vars(r)["bucketName"], time.Now())
+			links := make([]ShareLink, 0, len(active))
+			for _, share := range active {
+				links = append(links, newShareLink(share))
+			}
+			writeJSON(w, http.StatusOK, links)
+		}
+	})
+}
+
+// HandleRevokeShareWithManager revokes a share link of a bucket. Viewers and
+// uploaders may only revoke the links they created, editors any link.
+func HandleRevokeShareWithManager(manager *MultiS3Manager) http.HandlerFunc {
+	return withShares(manager, RoleViewer, func(current *S3Instance, shares *shareRegistry) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			bucketName, token := mux.Vars(r)["bucketName"], mux.Vars(r)["token"]
+			now := time.Now()
+
+			share, ok := shares.get(token, now)
+			if !ok || share.Instance != current.Name || share.Bucket != bucketName {
+				http.Error(w, "Share not found", http.StatusNotFound)
+				return
+			}
+			if manager.RoleFor(r, current, bucketName) < RoleEditor {
+				user, ok := UserFromContext(r.Context())
+				if !ok || user.Name == "" || user.Name != share.CreatedBy {
+					http.Error(w, fmt.Sprintf("Forbidden: %s role required to revoke shares of other users", RoleEditor), http.StatusForbidden)
+					return
+				}
+			}
+
+			err := shares.revoke(current.Name, bucketName, token, now)
+			if errors.Is(err, ErrShareNotFound) {
+				http.Error(w, "Share not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				handleHTTPError(w, fmt.Errorf("error revoking share: %w", err))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+// HandleShare serves a share link to anybody who knows its token. Password
+// protected shares show a page asking for the password, which is then posted
+// back to download the object. It must be served without authentication.
+func HandleShare(manager *MultiS3Manager, templates fs.FS, rootURL string) http.HandlerFunc {
+	type pageData struct {
+		RootURL     string
+		CurrentS3   *S3Instance
+		S3Instances []*S3Instance
+		FileName    string
+		Error       string
+	}
+
+	renderPasswordPage := func(w http.ResponseWriter, code int, share Share, message string) {
+		t, err := template.ParseFS(templates, "layout.html.tmpl", "share.html.tmpl")
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error parsing template files: %w", err))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(code)
+		err = t.ExecuteTemplate(w, "layout", pageData{RootURL: rootURL, FileName: path.Base(share.Key), Error: message})
+		if err != nil {
+			log.Println(fmt.Errorf("error executing template: %w", err))
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := mux.Vars(r)["token"]
+		shares := manager.shares.Load()
+		if shares == nil {
+			http.NotFound(w, r)
+			return
+		}
+		share, ok := shares.get(token, time.Now())
+		if !ok {
+			http.Error(w, "Share not found", http.StatusNotFound)
+			return
+		}
+
+		if share.PasswordHash != "" {
+			if r.Method != http.MethodPost {
+				renderPasswordPage(w, http.StatusOK, share, "")
+				return
+			}
+			if bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(r.PostFormValue("password"))) != nil {
+				renderPasswordPage(w, http.StatusUnauthorized, share, "Wrong password")
+				return
+			}
+		}
+
+		// The instance may have been removed or stopped allowing the bucket
+		// since the share was created.
+		instance, err := manager.GetInstance(share.Instance)
+		if err != nil || !instance.Buckets.Allows(share.Bucket) {
+			http.Error(w, "Share not found", http.StatusNotFound)
+			return
+		}
+
+		sse, err := readEncryption(instance.Features.SSEFor(share.Bucket))
+		var object *minio.Object
+		if err == nil {
+			object, err = instance.Client.GetObject(r.Context(), share.Bucket, share.Key, minio.GetObjectOptions{VersionID: share.VersionID, ServerSideEncryption: sse})
+		}
+		var info minio.ObjectInfo
+		if err == nil {
+			info, err = object.Stat()
+		}
+		if err != nil {
+			// Errors may contain the S3 endpoint, which shares must not expose.
+			if code := minio.ToErrorResponse(err).Code; code == "NoSuchKey" || code == "NoSuchVersion" {
+				http.Error(w, "Share not found", http.StatusNotFound)
+				return
+			}
+			log.Println(fmt.Errorf("error getting shared object: %w", err))
+			http.Error(w, "error getting object", http.StatusBadGateway)
+			return
+		}
+		defer func() { _ = object.Close() }()
+
+		err = shares.claimDownload(token, time.Now())
+		if errors.Is(err, ErrShareNotFound) {
+			http.Error(w, "Share not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println(fmt.Errorf("error counting share download: %w", err))
+			http.Error(w, "error counting download", http.StatusInternalServerError)
+			return
+		}
+
+		contentType := info.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(share.Key)})
+		if disposition == "" {
+			disposition = "attachment"
+		}
+		w.Header().Set("Content-Disposition", disposition)
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if _, err := io.Copy(w, object); err != nil {
+			log.Println(fmt.Errorf("error copying shared object to response writer: %w", err))
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+)
+
+// newFakeObjectServer serves the object bucket/docs/report.pdf and answers
+// every other key with NoSuchKey.
+func newFakeObjectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/docs/report.pdf" {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Length", "7")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2026 15:04:05 GMT")
+		w.Header().Set("ETag", `"etag"`)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// newShareRouter serves the share API and the share links of manager.
+func newShareRouter(manager *s3manager.MultiS3Manager) *mux.Router {
+	templates := os.DirFS(filepath.Join("..", "..", "..", "web", "template"))
+
+	r := mux.NewRouter()
+	r.Handle("/{instance}/api/buckets/{bucketName}/shares", s3manager.HandleListSharesWithManager(manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/shares", s3manager.HandleCreateShareWithManager(manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/shares/{token}", s3manager.HandleRevokeShareWithManager(manager)).Methods(http.MethodDelete)
+	r.Handle("/s/{token}", s3manager.HandleShare(manager, templates, "")).Methods(http.MethodGet, http.MethodPost)
+	return r
+}
+
+func TestShareLinks(t *testing.T) {
+	t.Parallel()
+
+	ts := newFakeObjectServer(t)
+
+	newManager := func(t *testing.T, storePath string) *s3manager.MultiS3Manager {
+		t.Helper()
+		is := is.New(t)
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{fakeS3InstanceConfig("primary", ts)})
+		is.NoErr(err)
+		is.NoErr(manager.UseShareStore(s3manager.NewFileShareStore(storePath)))
+		return manager
+	}
+
+	createShare := func(t *testing.T, r http.Handler, body string) (*httptest.ResponseRecorder, s3manager.ShareLink) {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/shares", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		var link s3manager.ShareLink
+		if rr.Code == http.StatusCreated {
+			_ = json.NewDecoder(bytes.NewReader(rr.Body.Bytes())).Decode(&link)
+		}
+		return rr, link
+	}
+
+	download := func(r http.Handler, token string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/s/"+token, nil)
+		if form != nil {
+			req = httptest.NewRequest(http.MethodPost, "/s/"+token, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("streams a shared object until it was downloaded as often as allowed", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		r := newShareRouter(newManager(t, filepath.Join(t.TempDir(), "shares.json")))
+
+		rr, link := createShare(t, r, `{"key": "docs/report.pdf", "expiry": 3600, "max_downloads": 1}`)
+		is.Equal(http.StatusCreated, rr.Code)
+		is.True(link.Token != "")
+		is.Equal(1, link.MaxDownloads)
+
+		rr = download(r, link.Token, nil)
+		is.Equal(http.StatusOK, rr.Code)
+		is.Equal("content", rr.Body.String())
+		is.Equal("application/pdf", rr.Header().Get("Content-Type"))
+		is.Equal(`attachment; filename=report.pdf`, rr.Header().Get("Content-Disposition"))
+
+		rr = download(r, link.Token, nil)
+		is.Equal(http.StatusNotFound, rr.Code) // download limit reached
+	})
+
+	t.Run("asks for the password of protected shares", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		r := newShareRouter(newManager(t, filepath.Join(t.TempDir(), "shares.json")))
+
+		rr, link := createShare(t, r, `{"key": "docs/report.pdf", "expiry": 3600, "password": "secret"}`)
+		is.Equal(http.StatusCreated, rr.Code)
+		is.True(link.PasswordProtected)
+
+		rr = download(r, link.Token, nil)
+		is.Equal(http.StatusOK, rr.Code)
+		is.True(strings.Contains(rr.Body.String(), `type="password"`))
+		is.Equal("", rr.Header().Get("Content-Disposition")) // nothing is downloaded without the password
+
+		rr = download(r, link.Token, url.Values{"password": {"wrong"}})
+		is.Equal(http.StatusUnauthorized, rr.Code)
+		is.True(strings.Contains(rr.Body.String(), "Wrong password"))
+
+		rr = download(r, link.Token, url.Values{"password": {"secret"}})
+		is.Equal(http.StatusOK, rr.Code)
+		is.Equal("content", rr.Body.String())
+	})
+
+	t.Run("lists and revokes shares", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		r := newShareRouter(newManager(t, filepath.Join(t.TempDir(), "shares.json")))
+
+		_, first := createShare(t, r, `{"key": "docs/report.pdf", "expiry": 3600}`)
+		_, second := createShare(t, r, `{"key": "docs/report.pdf", "expiry": 3600}`)
+
+		req := httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/bucket/shares/"+first.Token, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		is.Equal(http.StatusNoContent, rr.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/primary/api/buckets/bucket/shares", nil)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		is.Equal(http.StatusOK, rr.Code)
+		var links []s3manager.ShareLink
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&links))
+		is.Equal(1, len(links))
+		is.Equal(second.Token, links[0].Token)
+
+		is.Equal(http.StatusNotFound, download(r, first.Token, nil).Code)
+
+		req = httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/other/shares/"+second.Token, nil)
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		is.Equal(http.StatusNotFound, rr.Code) // shares can only be revoked through their bucket
+	})
+
+	t.Run("only lets editors revoke shares of other users", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		manager := newManager(t, filepath.Join(t.TempDir(), "shares.json"))
+		policy, err := s3manager.NewAccessPolicy([]s3manager.RoleBinding{
+			{Users: []string{"alice", "bob"}, Role: "viewer", Instance: "primary"},
+			{Users: []string{"carol"}, Role: "editor", Instance: "primary"},
+		})
+		is.NoErr(err)
+		manager.SetAccessPolicy(policy)
+		r := newShareRouter(manager)
+
+		as := func(name string, req *http.Request) *http.Request {
+			return req.WithContext(s3manager.ContextWithUser(req.Context(), &s3manager.User{Name: name}))
+		}
+		revoke := func(name, token string) int {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, as(name, httptest.NewRequest(http.MethodDelete, "/primary/api/buckets/bucket/shares/"+token, nil)))
+			return rr.Code
+		}
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, as("alice", httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/shares", strings.NewReader(`{"key": "docs/report.pdf", "expiry": 3600}`))))
+		is.Equal(http.StatusCreated, rr.Code)
+		var first s3manager.ShareLink
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&first))
+		rr = httptest.NewRecorder()
+		r.ServeHTTP(rr, as("alice", httptest.NewRequest(http.MethodPost, "/primary/api/buckets/bucket/shares", strings.NewReader(`{"key": "docs/report.pdf", "expiry": 3600}`))))
+		is.Equal(http.StatusCreated, rr.Code)
+		var second s3manager.ShareLink
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&second))
+
+		is.Equal(http.StatusForbidden, revoke("bob", first.Token))    // viewers can't revoke shares of others
+		is.Equal(http.StatusNoContent, revoke("alice", first.Token))  // creators can revoke their shares
+		is.Equal(http.StatusNoContent, revoke("carol", second.Token)) // editors can revoke any share
+	})
+
+	t.Run("keeps shares across restarts", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		path := filepath.Join(t.TempDir(), "shares.json")
+		_, link := createShare(t, newShareRouter(newManager(t, path)), `{"key": "docs/report.pdf", "expiry": 3600}`)
+
+		rr := download(newShareRouter(newManager(t, path)), link.Token, nil)
+		is.Equal(http.StatusOK, rr.Code)
+		is.Equal("content", rr.Body.String())
+	})
+
+	t.Run("does not serve expired shares", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		path := filepath.Join(t.TempDir(), "shares.json")
+		is.NoErr(s3manager.NewFileShareStore(path).Save([]s3manager.Share{{
+			Token:    "expired",
+			Instance: "primary",
+			Bucket:   "bucket",
+			Key:      "docs/report.pdf",
+		}}))
+
+		is.Equal(http.StatusNotFound, download(newShareRouter(newManager(t, path)), "expired", nil).Code)
+	})
+
+	t.Run("rejects invalid shares", func(t *testing.T) {
+		t.Parallel()
+
+		cases := []struct {
+			it                   string
+			body                 string
+			expectedStatusCode   int
+			expectedBodyContains string
+		}{
+			{
+				it:                   "rejects a missing key",
+				body:                 `{"expiry": 3600}`,
+				expectedStatusCode:   http.StatusBadRequest,
+				expectedBodyContains: "key must not be empty",
+			},
+			{
+				it:                   "rejects a missing expiry",
+				body:                 `{"key": "docs/report.pdf"}`,
+				expectedStatusCode:   http.StatusBadRequest,
+				expectedBodyContains: "expiry must be a positive number of seconds",
+			},
+			{
+				it:                   "rejects an expiry longer than presigned URLs may be valid",
+				body:                 `{"key": "docs/report.pdf", "expiry": 604801}`,
+				expectedStatusCode:   http.StatusBadRequest,
+				expectedBodyContains: "expiry must not exceed 604800 seconds",
+			},
+			{
+				it:                   "rejects versions if they are not shown",
+				body:                 `{"key": "docs/report.pdf", "expiry": 3600, "version_id": "v1"}`,
+				expectedStatusCode:   http.StatusBadRequest,
+				expectedBodyContains: "version_id requires versions",
+			},
+			{
+				it:                   "returns 404 for a missing object",
+				body:                 `{"key": "missing.pdf", "expiry": 3600}`,
+				expectedStatusCode:   http.StatusNotFound,
+				expectedBodyContains: "The specified key does not exist",
+			},
+		}
+
+		r := newShareRouter(newManager(t, filepath.Join(t.TempDir(), "shares.json")))
+		for _, tc := range cases {
+			t.Run(tc.it, func(t *testing.T) {
+				t.Parallel()
+				is := is.New(t)
+
+				rr, _ := createShare(t, r, tc.body)
+				is.Equal(tc.expectedStatusCode, rr.Code)
+				is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+			})
+		}
+	})
+
+	t.Run("responds with 404 without a share store", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{fakeS3InstanceConfig("primary", ts)})
+		is.NoErr(err)
+		r := newShareRouter(manager)
+
+		rr, _ := createShare(t, r, `{"key": "docs/report.pdf", "expiry": 3600}`)
+		is.Equal(http.StatusNotFound, rr.Code)
+		is.Equal(http.StatusNotFound, download(r, "token", nil).Code)
+	})
+}
+
+func TestFileShareStore(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	path := filepath.Join(t.TempDir(), "shares.json")
+	store := s3manager.NewFileShareStore(path)
+
+	shares, err := store.Load()
+	is.NoErr(err)
+	is.Equal(0, len(shares)) // nothing stored yet
+
+	is.NoErr(store.Save([]s3manager.Share{{Token: "token", Instance: "primary", Bucket: "bucket", Key: "key"}}))
+	shares, err = store.Load()
+	is.NoErr(err)
+	is.Equal("token", shares[0].Token)
+
+	info, err := os.Stat(path)
+	is.NoErr(err)
+	is.Equal(os.FileMode(0o600), info.Mode().Perm()) // tokens are only readable by the owner
+}
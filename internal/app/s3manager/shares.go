@@ -0,0 +1,189 @@
+package s3manager
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrShareNotFound is returned for share links that don't exist, were revoked,
+// expired or were downloaded as often as allowed.
+var ErrShareNotFound = errors.New("share not found")
+
+// Share is a link issued by s3manager that lets anybody who knows its token
+// download an object until it expires, is revoked or was downloaded
+// MaxDownloads times. Downloads are streamed through s3manager, so the S3
+// endpoint is never exposed.
+type Share struct {
+	Token        string    `json:"token"`
+	Instance     string    `json:"instance"`
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	VersionID    string    `json:"version_id,omitempty"`
+	CreatedBy    string    `json:"created_by,omitempty"`
+	Created      time.Time `json:"created"`
+	Expires      time.Time `json:"expires"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	MaxDownloads int       `json:"max_downloads,omitempty"`
+	Downloads    int       `json:"downloads"`
+}
+
+// Active reports whether the share can still be downloaded at now.
+func (s Share) Active(now time.Time) bool {
+	return now.Before(s.Expires) && (s.MaxDownloads == 0 || s.Downloads < s.MaxDownloads)
+}
+
+// ShareStore persists share links.
+type ShareStore interface {
+	// Load returns all stored shares.
+	Load() ([]Share, error)
+	// Save replaces the stored shares with shares.
+	Save(shares []Share) error
+}
+
+// FileShareStore is a ShareStore that keeps the shares in a JSON file. The
+// file contains the tokens of the shares and is only readable by its owner.
+type FileShareStore struct {
+	path string
+}
+
+// NewFileShareStore creates a FileShareStore backed by the file at path. The
+// file is created on the first Save.
+func NewFileShareStore(path string) *FileShareStore {
+	return &FileShareStore{path: path}
+}
+
+// Load implements ShareStore.
+func (s *FileShareStore) Load() ([]Share, error) {
+	var shares []Share
+	if err := loadJSONFile(s.path, "share store", &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// Save implements ShareStore. The file is replaced atomically, so a failed
+// write never leaves a truncated store behind.
+func (s *FileShareStore) Save(shares []Share) error {
+	if shares == nil {
+		shares = []Share{}
+	}
+	return saveJSONFile(s.path, "share store", shares)
+}
+
+// shareRegistry holds the active shares and persists every change to its
+// store. Inactive shares are dropped whenever the shares are saved.
+type shareRegistry struct {
+	mu     sync.Mutex
+	store  ShareStore
+	shares []Share
+}
+
+// newShareToken returns a random, URL safe share token.
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// add stores share.
+func (s *shareRegistry) add(share Share) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save(append(slices.Clone(s.shares), share))
+}
+
+// get returns the active share with token.
+func (s *shareRegistry) get(token string, now time.Time) (Share, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(token, now)
+	if i < 0 {
+		return Share{}, false
+	}
+	return s.shares[i], true
+}
+
+// list returns the active shares of bucket on instance, oldest first.
+func (s *shareRegistry) list(instance, bucket string, now time.Time) []Share {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var shares []Share
+	for _, share := range s.shares {
+		if share.Instance == instance && share.Bucket == bucket && share.Active(now) {
+			shares = append(shares, share)
+		}
+	}
+	return shares
+}
+
+// revoke removes the share with token from bucket on instance.
+func (s *shareRegistry) revoke(instance, bucket, token string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(token, now)
+	if i < 0 || s.shares[i].Instance != instance || s.shares[i].Bucket != bucket {
+		return ErrShareNotFound
+	}
+	return s.save(slices.Delete(slices.Clone(s.shares), i, i+1))
+}
+
+// claimDownload counts a download of the share with token, failing with
+// ErrShareNotFound if it may not be downloaded anymore.
+func (s *shareRegistry) claimDownload(token string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(token, now)
+	if i < 0 {
+		return ErrShareNotFound
+	}
+	shares := slices.Clone(s.shares)
+	shares[i].Downloads++
+	return s.save(shares)
+}
+
+// index returns the index of the active share with token, or -1.
+func (s *shareRegistry) index(token string, now time.Time) int {
+	return slices.IndexFunc(s.shares, func(share Share) bool {
+		return share.Token == token && share.Active(now)
+	})
+}
+
+// save persists the active shares and keeps them if that succeeds. s.mu must
+// be held.
+func (s *shareRegistry) save(shares []Share) error {
+	now := time.Now()
+	shares = slices.DeleteFunc(shares, func(share Share) bool { return !share.Active(now) })
+	if err := s.store.Save(shares); err != nil {
+		return err
+	}
+	s.shares = shares
+	return nil
+}
+
+// UseShareStore loads the share links from store and persists all future
+// changes to it. Share links can only be created once a store is used.
+func (m *MultiS3Manager) UseShareStore(store ShareStore) error {
+	shares, err := store.Load()
+	if err != nil {
+		return err
+	}
+	m.shares.Store(&shareRegistry{store: store, shares: shares})
+	return nil
+}
+
+// HasShareStore reports whether share links can be created.
+func (m *MultiS3Manager) HasShareStore() bool {
+	return m.shares.Load() != nil
+}
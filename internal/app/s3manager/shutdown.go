@@ -0,0 +1,151 @@
+package s3manager
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Drainer shuts HTTP servers down gracefully. Once draining, readiness probes
+// fail so load balancers stop sending new requests, and requests in flight
+// as well as background tasks, such as jobs and transfers, get time to finish
+// before they are cancelled. A nil *Drainer runs background tasks until they
+// are done.
+type Drainer struct {
+	draining atomic.Bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	tasks   sync.WaitGroup
+	stopped bool
+}
+
+// NewDrainer creates a Drainer that is not draining yet.
+func NewDrainer() *Drainer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Drainer{ctx: ctx, cancel: cancel}
+}
+
+// Draining reports whether Shutdown was called.
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// BaseContext can be used as http.Server.BaseContext, so that the contexts of
+// requests that outlive the shutdown timeout are cancelled.
+func (d *Drainer) BaseContext(net.Listener) context.Context {
+	return d.ctx
+}
+
+// Context returns the context background tasks run in, which is cancelled
+// once the shutdown timeout expires.
+func (d *Drainer) Context() context.Context {
+	if d == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// Go runs task in the background. Shutdown waits for it along with the
+// requests in flight. Tasks started after the servers stopped are not waited
+// for.
+func (d *Drainer) Go(task func()) {
+	if d == nil {
+		go task()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		go task()
+		return
+	}
+	d.tasks.Go(task)
+}
+
+// Middleware makes readiness probes fail while draining.
+func (d *Drainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Draining() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Shutdown starts draining and keeps serving for delay, so load balancers
+// notice the failing readiness probe. Then servers stop accepting requests,
+// and requests in flight and background tasks have until timeout to finish
+// before their contexts are cancelled and their connections closed. Shutdown
+// returns once the cancelled tasks returned.
+func (d *Drainer) Shutdown(delay, timeout time.Duration, servers ...*http.Server) error {
+	d.draining.Store(true)
+	log.Printf("Shutting down in %s, waiting up to %s for requests in flight, jobs and transfers", delay, timeout)
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, srv := range servers {
+		wg.Go(func() {
+			err := srv.Shutdown(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("Cancelling requests still in flight after %s", timeout)
+				d.cancel()
+				err = srv.Close()
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+	tasks := make(chan struct{})
+	go func() {
+		d.tasks.Wait()
+		close(tasks)
+	}()
+	select {
+	case <-tasks:
+	case <-ctx.Done():
+		log.Printf("Cancelling jobs and transfers still running after %s", timeout)
+		d.cancel()
+		<-tasks
+	}
+	d.cancel()
+
+	return errors.Join(errs...)
+}
+
+// UseDrainer runs jobs and transfers with drainer, so a shutdown waits for
+// them. It must be called before jobs and transfers are started, i.e. before
+// UseTransferStore.
+func (m *MultiS3Manager) UseDrainer(drainer *Drainer) {
+	m.jobs.mu.Lock()
+	m.jobs.drainer = drainer
+	m.jobs.mu.Unlock()
+
+	m.transfers.mu.Lock()
+	m.transfers.drainer = drainer
+	m.transfers.mu.Unlock()
+}
@@ -0,0 +1,144 @@
+package s3manager_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestDrainer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fails readiness once draining", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		drainer := s3manager.NewDrainer()
+		readyz := drainer.Middleware(s3manager.HandleHealthz())
+
+		rr := httptest.NewRecorder()
+		readyz.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		is.Equal(http.StatusOK, rr.Code)
+
+		is.NoErr(drainer.Shutdown(0, time.Second))
+		is.True(drainer.Draining())
+
+		rr = httptest.NewRecorder()
+		readyz.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		is.Equal(http.StatusServiceUnavailable, rr.Code)
+	})
+
+	t.Run("lets requests in flight finish", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		drainer := s3manager.NewDrainer()
+		started := make(chan struct{})
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			_, _ = io.WriteString(w, "done")
+		}))
+		ts.Config.BaseContext = drainer.BaseContext
+		ts.Start()
+		t.Cleanup(ts.Close)
+
+		type result struct {
+			body string
+			err  error
+		}
+		results := make(chan result, 1)
+		go func() {
+			resp, err := http.Get(ts.URL)
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, err := io.ReadAll(resp.Body)
+			results <- result{body: string(body), err: err}
+		}()
+
+		<-started
+		is.NoErr(drainer.Shutdown(0, 5*time.Second, ts.Config))
+
+		res := <-results
+		is.NoErr(res.err)
+		is.Equal("done", res.body)
+	})
+
+	t.Run("cancels requests that outlive the timeout", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		drainer := s3manager.NewDrainer()
+		started := make(chan struct{})
+		cancelled := make(chan struct{})
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+			close(cancelled)
+		}))
+		ts.Config.BaseContext = drainer.BaseContext
+		ts.Start()
+		t.Cleanup(ts.Close)
+
+		go func() {
+			resp, err := http.Get(ts.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		<-started
+		is.NoErr(drainer.Shutdown(0, 50*time.Millisecond, ts.Config))
+
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("request context was not cancelled")
+		}
+	})
+	t.Run("lets background tasks finish", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		drainer := s3manager.NewDrainer()
+		done := make(chan struct{})
+		drainer.Go(func() {
+			time.Sleep(100 * time.Millisecond)
+			close(done)
+		})
+
+		is.NoErr(drainer.Shutdown(0, 5*time.Second))
+		select {
+		case <-done:
+		default:
+			t.Fatal("shutdown did not wait for the task")
+		}
+	})
+
+	t.Run("cancels background tasks that outlive the timeout", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		drainer := s3manager.NewDrainer()
+		cancelled := make(chan struct{})
+		drainer.Go(func() {
+			<-drainer.Context().Done()
+			close(cancelled)
+		})
+
+		is.NoErr(drainer.Shutdown(0, 50*time.Millisecond))
+		select {
+		case <-cancelled:
+		default:
+			t.Fatal("shutdown returned before the task")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package s3manager
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// SSEType describes a type of server side encryption.
+type SSEType struct {
+	Type string
+	Key  string
+}
+
+// BucketSSEConfig configures the server side encryption of the buckets
+// matching one of its patterns, overriding the encryption of the instance.
+type BucketSSEConfig struct {
+	Buckets []string `mapstructure:"buckets" json:"buckets"`
+	SseType string   `mapstructure:"sse_type" json:"sse_type"`
+	SseKey  string   `mapstructure:"sse_key" json:"sse_key"`
+}
+
+// BucketSSE is the server side encryption of the buckets matching one of
+// Buckets. Patterns use path.Match syntax.
+type BucketSSE struct {
+	Buckets []string
+	SSE     SSEType
+}
+
+// SSEFor returns the server side encryption of bucket: that of the first
+// BucketSSE matching it, or else that of the instance.
+func (f Features) SSEFor(bucket string) SSEType {
+	for _, bucketSSE := range f.BucketSSE {
+		if matchesAny(bucketSSE.Buckets, bucket) {
+			return bucketSSE.SSE
+		}
+	}
+	return f.SSE
+}
+
+// serverSideEncryption returns the encryption objects are stored with.
+func serverSideEncryption(sseInfo SSEType) (encrypt.ServerSide, error) {
+	switch sseInfo.Type {
+	case "KMS":
+		sse, err := encrypt.NewSSEKMS(sseInfo.Key, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error setting SSE-KMS key: %w", err)
+		}
+		return sse, nil
+	case "SSE":
+		return encrypt.NewSSE(), nil
+	case "SSE-C":
+		sse, err := encrypt.NewSSEC([]byte(sseInfo.Key))
+		if err != nil {
+			return nil, fmt.Errorf("error setting SSE-C key: %w", err)
+		}
+		return sse, nil
+	default:
+		return nil, nil
+	}
+}
+
+// readEncryption returns the encryption to send when reading objects stored
+// with sseInfo. S3 decrypts SSE and KMS encrypted objects transparently, but
+// needs the customer provided key of SSE-C encrypted ones.
+func readEncryption(sseInfo SSEType) (encrypt.ServerSide, error) {
+	if sseInfo.Type != "SSE-C" {
+		return nil, nil
+	}
+	return serverSideEncryption(sseInfo)
+}
+
+// presigningAllowed responds with 400 and returns false if objects are
+// encrypted with a customer provided key, which would have to be handed out
+// with the presigned URL.
+func presigningAllowed(w http.ResponseWriter, sseInfo SSEType) bool {
+	if sseInfo.Type == "SSE-C" {
+		http.Error(w, "presigned URLs are not supported for objects encrypted with SSE-C", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func newBucketSSE(configs []BucketSSEConfig) []BucketSSE {
+	var bucketSSE []BucketSSE
+	for _, config := range configs {
+		bucketSSE = append(bucketSSE, BucketSSE{Buckets: config.Buckets, SSE: SSEType{Type: config.SseType, Key: config.SseKey}})
+	}
+	return bucketSSE
+}
@@ -0,0 +1,197 @@
+package s3manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TransferRequest represents the request body for starting a transfer.
+type TransferRequest struct {
+	SourceInstance      string `json:"source_instance"`
+	SourceBucket        string `json:"source_bucket"`
+	SourcePrefix        string `json:"source_prefix"`
+	DestinationInstance string `json:"destination_instance"`
+	DestinationBucket   string `json:"destination_bucket"`
+	DestinationPrefix   string `json:"destination_prefix"`
+	// IfExists is either "skip" (the default), "overwrite" or "fail".
+	IfExists string `json:"if_exists"`
+}
+
+// transferRole returns the role of the user on bucket of the instance with
+// name, which is RoleNone if the instance doesn't exist anymore.
+func transferRole(manager *MultiS3Manager, r *http.Request, name, bucket string) Role {
+	instance, err := manager.GetInstance(name)
+	if err != nil || !instance.Buckets.Allows(bucket) {
+		return RoleNone
+	}
+	return manager.RoleFor(r, instance, bucket)
+}
+
+// canViewTransfer reports whether the user may view either bucket of
+// transfer.
+func canViewTransfer(manager *MultiS3Manager, r *http.Request, transfer Transfer) bool {
+	return transferRole(manager, r, transfer.SourceInstance, transfer.SourceBucket) >= RoleViewer ||
+		transferRole(manager, r, transfer.DestinationInstance, transfer.DestinationBucket) >= RoleViewer
+}
+
+// HandleListTransfers lists the transfers the user may view that are running
+// or finished recently.
+func HandleListTransfers(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transfers := []Transfer{}
+		for _, transfer := range manager.transfers.list() {
+			if canViewTransfer(manager, r, transfer) {
+				transfers = append(transfers, transfer)
+			}
+		}
+		writeJSON(w, http.StatusOK, transfers)
+	}
+}
+
+// HandleGetTransfer returns the progress of a transfer.
+func HandleGetTransfer(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transfer, err := manager.transfers.get(mux.Vars(r)["transferId"])
+		if errors.Is(err, ErrTransferNotFound) || !canViewTransfer(manager, r, transfer) {
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, transfer)
+	}
+}
+
+// HandleCreateTransfer starts a transfer of the objects below a prefix of a
+// bucket to a bucket of another instance. It requires the viewer role on the
+// source and the uploader role on the destination bucket.
+func HandleCreateTransfer(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TransferRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+			return
+		}
+		if req.IfExists == "" {
+			req.IfExists = "skip"
+		}
+		switch {
+		case req.SourceInstance == "" || req.SourceBucket == "" || req.DestinationInstance == "" || req.DestinationBucket == "":
+			http.Error(w, "source and destination instance and bucket must not be empty", http.StatusBadRequest)
+			return
+		case req.SourceInstance == req.DestinationInstance:
+			http.Error(w, "source and destination must be different instances", http.StatusBadRequest)
+			return
+		case req.SourcePrefix != "" && !strings.HasSuffix(req.SourcePrefix, "/"):
+			http.Error(w, "source_prefix must end with a slash", http.StatusBadRequest)
+			return
+		case req.DestinationPrefix != "" && !strings.HasSuffix(req.DestinationPrefix, "/"):
+			http.Error(w, "destination_prefix must end with a slash", http.StatusBadRequest)
+			return
+		case req.IfExists != "skip" && req.IfExists != "overwrite" && req.IfExists != "fail":
+			http.Error(w, "if_exists must be skip, overwrite or fail", http.StatusBadRequest)
+			return
+		}
+
+		source, err := manager.GetInstance(req.SourceInstance)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Instance not found: %s", err.Error()), http.StatusNotFound)
+			return
+		}
+		destination, err := manager.GetInstance(req.DestinationInstance)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Instance not found: %s", err.Error()), http.StatusNotFound)
+			return
+		}
+		if !authorize(manager, w, r, source, req.SourceBucket, RoleViewer) ||
+			!authorize(manager, w, r, destination, req.DestinationBucket, RoleUploader) {
+			return
+		}
+
+		// Fail early if a bucket can't be used, e.g. because it doesn't
+		// exist.
+		if _, err := destinationExists(r.Context(), source.Client, req.SourceBucket, req.SourcePrefix); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		if _, err := destinationExists(r.Context(), destination.Client, req.DestinationBucket, req.DestinationPrefix); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		transfer := Transfer{
+			SourceInstance:      source.Name,
+			SourceBucket:        req.SourceBucket,
+			SourcePrefix:        req.SourcePrefix,
+			DestinationInstance: destination.Name,
+			DestinationBucket:   req.DestinationBucket,
+			DestinationPrefix:   req.DestinationPrefix,
+			IfExists:            req.IfExists,
+		}
+		if user, ok := UserFromContext(r.Context()); ok {
+			transfer.CreatedBy = user.Name
+		}
+		transfer, err = manager.startTransfer(transfer)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, transfer)
+	}
+}
+
+// HandleCancelTransfer cancels a transfer. It requires the uploader role on
+// the destination bucket. Objects that were already transferred stay at the
+// destination.
+func HandleCancelTransfer(manager *MultiS3Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["transferId"]
+		transfer, err := manager.transfers.get(id)
+		if errors.Is(err, ErrTransferNotFound) || !canViewTransfer(manager, r, transfer) {
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+			return
+		}
+		if transferRole(manager, r, transfer.DestinationInstance, transfer.DestinationBucket) < RoleUploader {
+			http.Error(w, fmt.Sprintf("Forbidden: %s role required", RoleUploader), http.StatusForbidden)
+			return
+		}
+		if err := manager.transfers.cancel(id); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleTransfersView renders the page that starts transfers and shows
+// their progress.
+func HandleTransfersView(manager *MultiS3Manager, templates fs.FS, rootURL string) http.HandlerFunc {
+	type pageData struct {
+		RootURL     string
+		CurrentS3   *S3Instance
+		S3Instances []*S3Instance
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := pageData{
+			RootURL:     rootURL,
+			S3Instances: manager.GetAccessibleInstances(r),
+		}
+
+		t, err := template.ParseFS(templates, "layout.html.tmpl", "transfers.html.tmpl")
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error parsing template files: %w", err))
+			return
+		}
+		err = t.ExecuteTemplate(w, "layout", data)
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error executing template: %w", err))
+			return
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package s3manager_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+)
+
+// newTransferRouter serves the transfer API of manager.
+func newTransferRouter(manager *s3manager.MultiS3Manager) *mux.Router {
+	r := mux.NewRouter()
+	r.Handle("/api/transfers", s3manager.HandleListTransfers(manager)).Methods(http.MethodGet)
+	r.Handle("/api/transfers", s3manager.HandleCreateTransfer(manager)).Methods(http.MethodPost)
+	r.Handle("/api/transfers/{transferId}", s3manager.HandleGetTransfer(manager)).Methods(http.MethodGet)
+	r.Handle("/api/transfers/{transferId}", s3manager.HandleCancelTransfer(manager)).Methods(http.MethodDelete)
+	return r
+}
+
+// waitForTransfer polls the transfer with id until it finished.
+func waitForTransfer(t *testing.T, r http.Handler, id string) s3manager.Transfer {
+	t.Helper()
+	is := is.New(t)
+
+	var transfer s3manager.Transfer
+	deadline := time.Now().Add(5 * time.Second)
+	for transfer.Status == "" || transfer.Status == s3manager.JobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/transfers/"+id, nil))
+		is.Equal(http.StatusOK, rr.Code)
+		is.NoErr(json.NewDecoder(rr.Body).Decode(&transfer))
+	}
+	return transfer
+}
+
+func TestHandleCreateTransfer(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		config               func(*s3manager.S3InstanceConfig)
+		body                 string
+		expectedStatusCode   int
+		expectedBodyContains string
+	}{
+		{
+			it:                 "starts a transfer",
+			body:               `{"source_instance": "onprem", "source_bucket": "bucket", "source_prefix": "docs/", "destination_instance": "cloud", "destination_bucket": "archive"}`,
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			it:                   "requires instances and buckets",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "source and destination instance and bucket must not be empty",
+		},
+		{
+			it:                   "requires different instances",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "onprem", "destination_bucket": "archive"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "source and destination must be different instances",
+		},
+		{
+			it:                   "requires the source prefix to be a folder",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "source_prefix": "docs", "destination_instance": "cloud", "destination_bucket": "archive"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "source_prefix must end with a slash",
+		},
+		{
+			it:                   "requires the destination prefix to be a folder",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud", "destination_bucket": "archive", "destination_prefix": "docs"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "destination_prefix must end with a slash",
+		},
+		{
+			it:                   "requires a known policy for existing objects",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud", "destination_bucket": "archive", "if_exists": "rename"}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "if_exists must be skip, overwrite or fail",
+		},
+		{
+			it:                   "returns 404 for an unknown instance",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "unknown", "destination_bucket": "archive"}`,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedBodyContains: "Instance not found",
+		},
+		{
+			it:                   "returns 404 for a missing bucket",
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud", "destination_bucket": "missing"}`,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedBodyContains: "The specified bucket does not exist",
+		},
+		{
+			it:                   "requires access to the destination bucket",
+			config:               func(config *s3manager.S3InstanceConfig) { config.DeniedBuckets = []string{"archive"} },
+			body:                 `{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud", "destination_bucket": "archive"}`,
+			expectedStatusCode:   http.StatusForbidden,
+			expectedBodyContains: "access to bucket archive is not allowed",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			source := httptest.NewServer(&fakeBuckets{objects: map[string][]string{"bucket": {}}})
+			t.Cleanup(source.Close)
+			destination := httptest.NewServer(&fakeBuckets{objects: map[string][]string{"archive": {}}})
+			t.Cleanup(destination.Close)
+
+			config := fakeS3InstanceConfig("cloud", destination)
+			if tc.config != nil {
+				tc.config(&config)
+			}
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{fakeS3InstanceConfig("onprem", source), config})
+			is.NoErr(err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			newTransferRouter(manager).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)                             // status code
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains)) // body
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	sourceBuckets := &fakeBuckets{
+		objects:   map[string][]string{"bucket": {"docs/a.txt", "docs/b.txt", "docs/bad.txt", "docs/c.txt", "docs/d.txt", "docs/worse.txt", "other.txt"}},
+		corrupted: []string{"bucket/docs/bad.txt", "bucket/docs/worse.txt"},
+	}
+	source := httptest.NewServer(sourceBuckets)
+	t.Cleanup(source.Close)
+	destinationBuckets := &fakeBuckets{
+		objects:   map[string][]string{"archive": {"migrated/a.txt", "migrated/c.txt", "migrated/d.txt", "migrated/worse.txt"}},
+		stale:     []string{"archive/migrated/c.txt", "archive/migrated/worse.txt"},
+		multipart: []string{"archive/migrated/d.txt"},
+	}
+	destination := httptest.NewServer(destinationBuckets)
+	t.Cleanup(destination.Close)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		fakeS3InstanceConfig("onprem", source),
+		fakeS3InstanceConfig("cloud", destination),
+	})
+	is.NoErr(err)
+	manager.SetTransferConcurrency(1)
+	r := newTransferRouter(manager)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(`{"source_instance": "onprem", "source_bucket": "bucket", "source_prefix": "docs/", "destination_instance": "cloud", "destination_bucket": "archive", "destination_prefix": "migrated/", "if_exists": "overwrite"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	is.Equal(http.StatusAccepted, rr.Code)
+
+	var transfer s3manager.Transfer
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&transfer))
+	is.Equal(s3manager.JobRunning, transfer.Status)
+
+	transfer = waitForTransfer(t, r, transfer.ID)
+	is.Equal(s3manager.JobFailed, transfer.Status) // objects are corrupted
+	is.Equal(6, transfer.Total)
+	is.Equal(3, transfer.Done)     // b.txt, the stale c.txt and d.txt, whose ETag can't be compared
+	is.Equal(1, transfer.Skipped)  // a.txt was transferred before
+	is.Equal(3, transfer.Verified) // checksums matched
+	is.Equal(int64(21), transfer.Bytes)
+	is.Equal(2, transfer.Failed)
+	is.Equal("docs/bad.txt", transfer.Errors[0].Key)
+	is.True(strings.Contains(transfer.Errors[0].Error, "checksum mismatch"))
+	is.Equal("docs/worse.txt", transfer.Errors[1].Key)
+	is.True(strings.Contains(transfer.Errors[1].Error, "replaced an existing object and is kept"))
+	is.Equal([]string{"migrated/a.txt", "migrated/b.txt", "migrated/c.txt", "migrated/d.txt", "migrated/worse.txt"}, destinationBuckets.keys("archive")) // only the corrupted copy the transfer created is removed
+
+	put := destinationBuckets.puts[0]
+	is.True(put.Get("Content-Md5") != "")                // destination verifies the content
+	is.Equal("text/plain", put.Get("Content-Type"))      // content type is kept
+	is.Equal("front", put.Get("X-Amz-Meta-Camera"))      // metadata is kept
+	is.Equal("project=launch", put.Get("X-Amz-Tagging")) // tags are kept
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/transfers", nil))
+	var transfers []s3manager.Transfer
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&transfers))
+	is.Equal(1, len(transfers))
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/transfers/unknown", nil))
+	is.Equal(http.StatusNotFound, rr.Code)
+}
+
+func TestTransferIfExists(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it              string
+		ifExists        string
+		expectedStatus  s3manager.JobStatus
+		expectedDone    int
+		expectedSkipped int
+		expectedError   string
+	}{
+		{
+			it:              "skips existing objects by default",
+			expectedStatus:  s3manager.JobSucceeded,
+			expectedDone:    1,
+			expectedSkipped: 1,
+		},
+		{
+			it:             "overwrites existing objects",
+			ifExists:       "overwrite",
+			expectedStatus: s3manager.JobSucceeded,
+			expectedDone:   2,
+		},
+		{
+			it:             "fails on existing objects",
+			ifExists:       "fail",
+			expectedStatus: s3manager.JobFailed,
+			expectedDone:   1,
+			expectedError:  "destination object already exists",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			source := httptest.NewServer(&fakeBuckets{objects: map[string][]string{"bucket": {"a.txt", "b.txt"}}})
+			t.Cleanup(source.Close)
+			destination := httptest.NewServer(&fakeBuckets{
+				objects: map[string][]string{"archive": {"a.txt"}},
+				stale:   []string{"archive/a.txt"},
+			})
+			t.Cleanup(destination.Close)
+
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+				fakeS3InstanceConfig("onprem", source),
+				fakeS3InstanceConfig("cloud", destination),
+			})
+			is.NoErr(err)
+			r := newTransferRouter(manager)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(`{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud", "destination_bucket": "archive", "if_exists": "`+tc.ifExists+`"}`))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			is.Equal(http.StatusAccepted, rr.Code)
+			var transfer s3manager.Transfer
+			is.NoErr(json.NewDecoder(rr.Body).Decode(&transfer))
+
+			transfer = waitForTransfer(t, r, transfer.ID)
+			is.Equal(tc.expectedStatus, transfer.Status)
+			is.Equal(tc.expectedDone, transfer.Done)
+			is.Equal(tc.expectedSkipped, transfer.Skipped)
+			if tc.expectedError != "" {
+				is.Equal(1, len(transfer.Errors))
+				is.Equal("a.txt", transfer.Errors[0].Key)
+				is.Equal(tc.expectedError, transfer.Errors[0].Error)
+			}
+		})
+	}
+}
+
+func TestTransferResumesAfterRestart(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	source := httptest.NewServer(&fakeBuckets{objects: map[string][]string{"bucket": {"a.txt", "b.txt"}}})
+	t.Cleanup(source.Close)
+	destinationBuckets := &fakeBuckets{objects: map[string][]string{"archive": {"a.txt"}}}
+	destination := httptest.NewServer(destinationBuckets)
+	t.Cleanup(destination.Close)
+
+	// The transfer was still running when s3manager stopped.
+	store := s3manager.NewFileTransferStore(filepath.Join(t.TempDir(), "transfers.json"))
+	is.NoErr(store.Save([]s3manager.Transfer{{
+		ID:                  "interrupted",
+		SourceInstance:      "onprem",
+		SourceBucket:        "bucket",
+		DestinationInstance: "cloud",
+		DestinationBucket:   "archive",
+		Status:              s3manager.JobRunning,
+		Total:               2,
+		Done:                1,
+		Started:             time.Now().Add(-time.Hour),
+	}}))
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		fakeS3InstanceConfig("onprem", source),
+		fakeS3InstanceConfig("cloud", destination),
+	})
+	is.NoErr(err)
+	is.NoErr(manager.UseTransferStore(store))
+
+	transfer := waitForTransfer(t, newTransferRouter(manager), "interrupted")
+	is.Equal(s3manager.JobSucceeded, transfer.Status)
+	is.True(transfer.Resumed != nil)
+	is.Equal(1, transfer.Skipped) // a.txt was transferred before the restart
+	is.Equal(1, transfer.Done)
+	is.Equal([]string{"a.txt", "b.txt"}, destinationBuckets.keys("archive"))
+
+	stored, err := store.Load()
+	is.NoErr(err)
+	is.Equal(1, len(stored))
+	is.Equal(s3manager.JobSucceeded, stored[0].Status) // outcome is persisted
+}
+
+func TestTransferInterruptedByShutdown(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	sourceBuckets := &fakeBuckets{objects: map[string][]string{"bucket": {"a.txt"}}}
+	downloading := make(chan struct{})
+	var once sync.Once
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/bucket/a.txt" {
+			// The download hangs until the shutdown cancels it.
+			once.Do(func() { close(downloading) })
+			<-r.Context().Done()
+			return
+		}
+		sourceBuckets.ServeHTTP(w, r)
+	}))
+	t.Cleanup(source.Close)
+	destination := httptest.NewServer(&fakeBuckets{objects: map[string][]string{"archive": {}}})
+	t.Cleanup(destination.Close)
+
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		fakeS3InstanceConfig("onprem", source),
+		fakeS3InstanceConfig("cloud", destination),
+	})
+	is.NoErr(err)
+	drainer := s3manager.NewDrainer()
+	manager.UseDrainer(drainer)
+	store := s3manager.NewFileTransferStore(filepath.Join(t.TempDir(), "transfers.json"))
+	is.NoErr(manager.UseTransferStore(store))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(`{"source_instance": "onprem", "source_bucket": "bucket", "destination_instance": "cloud", "destination_bucket": "archive"}`))
+	rr := httptest.NewRecorder()
+	newTransferRouter(manager).ServeHTTP(rr, req)
+	is.Equal(http.StatusAccepted, rr.Code)
+
+	<-downloading
+	is.NoErr(drainer.Shutdown(0, 50*time.Millisecond))
+
+	stored, err := store.Load()
+	is.NoErr(err)
+	is.Equal(1, len(stored))
+	is.Equal(s3manager.JobRunning, stored[0].Status) // resumed on the next start
+	is.Equal(1, stored[0].Total)                     // progress is persisted
+}
+
+func TestHandleTransfersView(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	ts := httptest.NewServer(&fakeBuckets{objects: map[string][]string{}})
+	t.Cleanup(ts.Close)
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{
+		fakeS3InstanceConfig("onprem", ts),
+		fakeS3InstanceConfig("cloud", ts),
+	})
+	is.NoErr(err)
+	templates := os.DirFS(filepath.Join("..", "..", "..", "web", "template"))
+
+	rr := httptest.NewRecorder()
+	s3manager.HandleTransfersView(manager, templates, "").ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/transfers", nil))
+
+	is.Equal(http.StatusOK, rr.Code)
+	is.True(strings.Contains(rr.Body.String(), `<option value="onprem" selected>`)) // source instance
+	is.True(strings.Contains(rr.Body.String(), `<option value="cloud" selected>`))  // destination instance
+}
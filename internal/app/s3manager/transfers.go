@@ -0,0 +1,528 @@
+package s3manager
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ErrTransferNotFound is returned for transfers that don't exist or are not
+// visible to the user.
+var ErrTransferNotFound = errors.New("transfer not found")
+
+const (
+	// defaultTransferConcurrency is the number of objects all transfers
+	// stream at once unless configured otherwise.
+	defaultTransferConcurrency = 4
+	// transferSaveInterval is how often the progress of running transfers
+	// is persisted.
+	transferSaveInterval = 5 * time.Second
+)
+
+// md5ETag matches ETags that are the MD5 digest of their object, as opposed
+// to those of multipart uploads.
+var md5ETag = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Transfer is a job that streams the objects below a prefix of a bucket to
+// a bucket of another S3 instance. IfExists is "skip", "overwrite" or "fail"
+// and tells what happens to objects that exist at the destination. Verified
+// counts the transferred objects whose checksum matched that of the source.
+// Resumed is set when a transfer was interrupted by a restart and picked up
+// again.
+type Transfer struct {
+	ID                  string     `json:"id"`
+	SourceInstance      string     `json:"source_instance"`
+	SourceBucket        string     `json:"source_bucket"`
+	SourcePrefix        string     `json:"source_prefix"`
+	DestinationInstance string     `json:"destination_instance"`
+	DestinationBucket   string     `json:"destination_bucket"`
+	DestinationPrefix   string     `json:"destination_prefix"`
+	IfExists            string     `json:"if_exists"`
+	CreatedBy           string     `json:"created_by,omitempty"`
+	Status              JobStatus  `json:"status"`
+	Total               int        `json:"total"`
+	Done                int        `json:"done"`
+	Skipped             int        `json:"skipped"`
+	Failed              int        `json:"failed"`
+	Verified            int        `json:"verified"`
+	Bytes               int64      `json:"bytes"`
+	Errors              []JobError `json:"errors,omitempty"`
+	Error               string     `json:"error,omitempty"`
+	Started             time.Time  `json:"started"`
+	Resumed             *time.Time `json:"resumed,omitempty"`
+	Finished            *time.Time `json:"finished,omitempty"`
+}
+
+// TransferStore persists transfers, so running transfers can be resumed
+// after a restart.
+type TransferStore interface {
+	// Load returns all stored transfers.
+	Load() ([]Transfer, error)
+	// Save replaces the stored transfers with transfers.
+	Save(transfers []Transfer) error
+}
+
+// FileTransferStore is a TransferStore that keeps the transfers in a JSON
+// file.
+type FileTransferStore struct {
+	path string
+}
+
+// NewFileTransferStore creates a FileTransferStore backed by the file at
+// path. The file is created on the first Save.
+func NewFileTransferStore(path string) *FileTransferStore {
+	return &FileTransferStore{path: path}
+}
+
+// Load implements TransferStore.
+func (s *FileTransferStore) Load() ([]Transfer, error) {
+	var transfers []Transfer
+	if err := loadJSONFile(s.path, "transfer store", &transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// Save implements TransferStore. The file is replaced atomically, so a
+// failed write never leaves a truncated store behind.
+func (s *FileTransferStore) Save(transfers []Transfer) error {
+	if transfers == nil {
+		transfers = []Transfer{}
+	}
+	return saveJSONFile(s.path, "transfer store", transfers)
+}
+
+// transferRegistry holds the transfers of a MultiS3Manager and persists them
+// to its store, if any. Finished transfers are dropped after jobRetention.
+// The zero value keeps the transfers in memory only.
+type transferRegistry struct {
+	mu        sync.Mutex
+	store     TransferStore
+	transfers []*runningTransfer
+	saved     time.Time
+	// slots limits the number of objects streamed at once across all
+	// transfers.
+	slots chan struct{}
+	// drainer runs the transfers, so they are waited for on shutdown.
+	drainer *Drainer
+}
+
+// runningTransfer is a transfer along with the function that cancels it.
+// transfer is guarded by the mutex of the registry.
+type runningTransfer struct {
+	transfer Transfer
+	cancel   context.CancelFunc
+}
+
+// semaphore returns the channel that limits the objects streamed at once.
+func (r *transferRegistry) semaphore() chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.slots == nil {
+		r.slots = make(chan struct{}, defaultTransferConcurrency)
+	}
+	return r.slots
+}
+
+// background returns the Drainer that runs the transfers.
+func (r *transferRegistry) background() *Drainer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.drainer
+}
+
+// add registers and persists transfer and returns the context it runs in.
+func (r *transferRegistry) add(transfer Transfer) (*runningTransfer, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(r.drainer.Context())
+	running := &runningTransfer{transfer: transfer, cancel: cancel}
+	r.transfers = append(r.transfers, running)
+	if err := r.save(); err != nil {
+		r.transfers = r.transfers[:len(r.transfers)-1]
+		cancel()
+		return nil, nil, err
+	}
+	return running, ctx, nil
+}
+
+// update applies change to transfer. Progress is persisted every
+// transferSaveInterval.
+func (r *transferRegistry) update(transfer *runningTransfer, change func(*Transfer)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&transfer.transfer)
+	if time.Since(r.saved) >= transferSaveInterval {
+		r.saveLogged()
+	}
+}
+
+// finish records and persists the outcome of transfer.
+func (r *transferRegistry) finish(transfer *runningTransfer, canceled, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	t := &transfer.transfer
+	t.Finished = &now
+	switch {
+	case canceled != nil:
+		t.Status = JobCanceled
+	case err != nil:
+		t.Status = JobFailed
+		t.Error = err.Error()
+	case t.Failed > 0:
+		t.Status = JobFailed
+	default:
+		t.Status = JobSucceeded
+	}
+	r.saveLogged()
+}
+
+// interrupt persists the progress of transfer, which was stopped by a
+// shutdown. It stays running, so it is resumed by the next start.
+func (r *transferRegistry) interrupt(transfer *runningTransfer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log.Printf("Transfer %s was interrupted and is resumed on the next start", transfer.transfer.ID)
+	r.saveLogged()
+}
+
+// list returns all transfers, oldest first.
+func (r *transferRegistry) list() []Transfer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transfers := make([]Transfer, 0, len(r.transfers))
+	for _, t := range r.transfers {
+		transfers = append(transfers, t.snapshot())
+	}
+	return transfers
+}
+
+// get returns the transfer with id.
+func (r *transferRegistry) get(id string) (Transfer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.transfers, func(t *runningTransfer) bool { return t.transfer.ID == id })
+	if i < 0 {
+		return Transfer{}, ErrTransferNotFound
+	}
+	return r.transfers[i].snapshot(), nil
+}
+
+// cancel stops the transfer with id. Objects that are being streamed are
+// aborted.
+func (r *transferRegistry) cancel(id string) error {
+	r.mu.Lock()
+	i := slices.IndexFunc(r.transfers, func(t *runningTransfer) bool { return t.transfer.ID == id })
+	var transfer *runningTransfer
+	if i >= 0 {
+		transfer = r.transfers[i]
+	}
+	r.mu.Unlock()
+
+	if transfer == nil {
+		return ErrTransferNotFound
+	}
+	transfer.cancel()
+	return nil
+}
+
+// save drops expired transfers and persists the others. r.mu must be held.
+func (r *transferRegistry) save() error {
+	r.transfers = slices.DeleteFunc(r.transfers, func(t *runningTransfer) bool {
+		return t.transfer.Finished != nil && time.Since(*t.transfer.Finished) > jobRetention
+	})
+	r.saved = time.Now()
+	if r.store == nil {
+		return nil
+	}
+	transfers := make([]Transfer, 0, len(r.transfers))
+	for _, t := range r.transfers {
+		transfers = append(transfers, t.transfer)
+	}
+	return r.store.Save(transfers)
+}
+
+// saveLogged saves the transfers and logs failures, which only cost
+// progress that is recovered when a transfer is resumed. r.mu must be held.
+func (r *transferRegistry) saveLogged() {
+	if err := r.save(); err != nil {
+		log.Printf("error saving transfers: %v", err)
+	}
+}
+
+// snapshot returns a copy of the transfer that is safe to use without
+// holding the mutex of the registry.
+func (t *runningTransfer) snapshot() Transfer {
+	transfer := t.transfer
+	transfer.Errors = slices.Clone(transfer.Errors)
+	return transfer
+}
+
+// SetTransferConcurrency limits the number of objects all transfers stream
+// at once to n. It must be called before transfers are started.
+func (m *MultiS3Manager) SetTransferConcurrency(n int) {
+	m.transfers.mu.Lock()
+	defer m.transfers.mu.Unlock()
+
+	m.transfers.slots = make(chan struct{}, n)
+}
+
+// UseTransferStore loads the transfers from store, persists all future
+// changes to it and resumes the transfers that were running when s3manager
+// stopped.
+func (m *MultiS3Manager) UseTransferStore(store TransferStore) error {
+	transfers, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	type resumption struct {
+		running  *runningTransfer
+		ctx      context.Context
+		transfer Transfer
+	}
+	var resumptions []resumption
+
+	m.transfers.mu.Lock()
+	m.transfers.store = store
+	drainer := m.transfers.drainer
+	now := time.Now().UTC()
+	for _, transfer := range transfers {
+		running := &runningTransfer{transfer: transfer, cancel: func() {}}
+		if transfer.Status == JobRunning {
+			var ctx context.Context
+			ctx, running.cancel = context.WithCancel(drainer.Context())
+			running.transfer.Resumed = &now
+			resumptions = append(resumptions, resumption{running: running, ctx: ctx, transfer: running.transfer})
+		}
+		m.transfers.transfers = append(m.transfers.transfers, running)
+	}
+	m.transfers.mu.Unlock()
+
+	for _, r := range resumptions {
+		drainer.Go(func() { m.runTransfer(r.ctx, r.running, r.transfer) })
+	}
+	return nil
+}
+
+// startTransfer registers transfer and runs it in the background.
+func (m *MultiS3Manager) startTransfer(transfer Transfer) (Transfer, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Transfer{}, err
+	}
+	transfer.ID = id
+	transfer.Status = JobRunning
+	transfer.Started = time.Now().UTC()
+
+	running, ctx, err := m.transfers.add(transfer)
+	if err != nil {
+		return Transfer{}, err
+	}
+	m.transfers.background().Go(func() { m.runTransfer(ctx, running, transfer) })
+	return transfer, nil
+}
+
+// runTransfer transfers the objects of transfer until all are done or the
+// transfer is canceled. A transfer stopped by a shutdown is kept running.
+func (m *MultiS3Manager) runTransfer(ctx context.Context, running *runningTransfer, transfer Transfer) {
+	defer running.cancel()
+	err := m.transferObjects(ctx, running, transfer)
+	if m.transfers.background().Context().Err() != nil {
+		m.transfers.interrupt(running)
+		return
+	}
+	m.transfers.finish(running, ctx.Err(), err)
+}
+
+// transferObjects streams every object below the source prefix of transfer
+// to the destination. The source listing is streamed and every destination
+// key is looked up on its own, so prefixes of any size are transferred
+// without holding their listings in memory. Objects that were transferred
+// before, e.g. before a restart, are skipped, while other existing objects
+// are handled according to the IfExists policy of transfer. Objects that
+// fail to transfer are reported and skipped.
+func (m *MultiS3Manager) transferObjects(ctx context.Context, running *runningTransfer, transfer Transfer) error {
+	source, err := m.GetInstance(transfer.SourceInstance)
+	if err != nil {
+		return fmt.Errorf("error getting source instance: %w", err)
+	}
+	destination, err := m.GetInstance(transfer.DestinationInstance)
+	if err != nil {
+		return fmt.Errorf("error getting destination instance: %w", err)
+	}
+	dstSSE, err := readEncryption(destination.Features.SSEFor(transfer.DestinationBucket))
+	if err != nil {
+		return err
+	}
+
+	// A resumed transfer counts from scratch, as objects that were done
+	// before are skipped now.
+	m.transfers.update(running, func(t *Transfer) {
+		t.Total, t.Done, t.Skipped, t.Failed, t.Verified, t.Bytes = 0, 0, 0, 0, 0, 0
+		t.Errors = nil
+	})
+
+	slots := m.transfers.semaphore()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for object := range source.Client.ListObjects(ctx, transfer.SourceBucket, minio.ListObjectsOptions{Prefix: transfer.SourcePrefix, Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("error listing objects: %w", object.Err)
+		}
+		m.transfers.update(running, func(t *Transfer) { t.Total++ })
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			key := transfer.DestinationPrefix + strings.TrimPrefix(object.Key, transfer.SourcePrefix)
+			skipped, verified := false, false
+			target, err := destination.Client.StatObject(ctx, transfer.DestinationBucket, key, minio.StatObjectOptions{ServerSideEncryption: dstSSE})
+			switch code := minio.ToErrorResponse(err).Code; {
+			case err != nil && code != "NoSuchKey":
+				err = fmt.Errorf("error getting destination object: %w", err)
+			case err != nil:
+				verified, err = transferObject(ctx, source, destination, transfer, object, key, false)
+			case transferred(object, target):
+				skipped = true
+			case transfer.IfExists == "overwrite":
+				verified, err = transferObject(ctx, source, destination, transfer, object, key, true)
+			case transfer.IfExists == "fail" && target.LastModified.Before(transfer.Started):
+				err = errors.New("destination object already exists")
+			case transfer.IfExists == "fail":
+				// The object was written after the transfer started, most
+				// likely by the transfer itself before it was interrupted,
+				// but its content can't be compared.
+				verified, err = transferObject(ctx, source, destination, transfer, object, key, true)
+			default:
+				skipped = true
+			}
+			m.transfers.update(running, func(t *Transfer) {
+				switch {
+				case ctx.Err() != nil:
+					// Objects aborted by canceling are not failures.
+				case err != nil:
+					t.Failed++
+					if len(t.Errors) < maxJobErrors {
+						t.Errors = append(t.Errors, JobError{Key: object.Key, Error: err.Error()})
+					}
+				case skipped:
+					t.Skipped++
+				default:
+					t.Done++
+					t.Bytes += object.Size
+					if verified {
+						t.Verified++
+					}
+				}
+			})
+		}()
+	}
+	return nil
+}
+
+// transferred reports whether target is a transferred copy of object: it
+// has the same size and both ETags are the same MD5 digest. Other ETags, e.g.
+// those of multipart uploads, can't be compared, so such objects are never
+// taken for transferred copies.
+func transferred(object, target minio.ObjectInfo) bool {
+	return object.Size == target.Size && md5ETag.MatchString(object.ETag) && object.ETag == target.ETag
+}
+
+// transferObject streams object from source to key on destination, keeping
+// its metadata, tags and content type. The destination verifies the MD5
+// digest of every part it receives. It reports whether the content also
+// matched the ETag of the source, which is only possible if that is an MD5
+// digest. Copies that don't match are removed if the transfer created them,
+// i.e. if key didn't exist before or the bucket keeps the version they
+// replaced. Otherwise they are kept, so an existing object is never deleted,
+// and transferred again when the transfer is restarted.
+func transferObject(ctx context.Context, source, destination *S3Instance, transfer Transfer, object minio.ObjectInfo, key string, exists bool) (bool, error) {
+	srcSSE, err := readEncryption(source.Features.SSEFor(transfer.SourceBucket))
+	if err != nil {
+		return false, err
+	}
+	dstSSE, err := serverSideEncryption(destination.Features.SSEFor(transfer.DestinationBucket))
+	if err != nil {
+		return false, err
+	}
+
+	reader, err := source.Client.GetObject(ctx, transfer.SourceBucket, object.Key, minio.GetObjectOptions{ServerSideEncryption: srcSSE})
+	if err != nil {
+		return false, fmt.Errorf("error getting object: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+	info, err := reader.Stat()
+	if err != nil {
+		return false, fmt.Errorf("error getting object: %w", err)
+	}
+	tags, err := source.Client.GetObjectTagging(ctx, transfer.SourceBucket, object.Key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error getting object tags: %w", err)
+	}
+
+	hash := md5.New() //nolint:gosec
+	opts := minio.PutObjectOptions{
+		UserMetadata:         info.UserMetadata,
+		UserTags:             tags.ToMap(),
+		ContentType:          info.ContentType,
+		ContentEncoding:      info.Metadata.Get("Content-Encoding"),
+		ContentDisposition:   info.Metadata.Get("Content-Disposition"),
+		ContentLanguage:      info.Metadata.Get("Content-Language"),
+		CacheControl:         info.Metadata.Get("Cache-Control"),
+		ServerSideEncryption: dstSSE,
+		SendContentMd5:       true,
+	}
+	uploaded, err := destination.Client.PutObject(ctx, transfer.DestinationBucket, key, io.TeeReader(reader, hash), info.Size, opts)
+	if err != nil {
+		return false, fmt.Errorf("error putting object: %w", err)
+	}
+
+	// The ETags of encrypted objects are no MD5 digests, even if they look
+	// like one.
+	encrypted := info.Metadata.Get("X-Amz-Server-Side-Encryption") != "" ||
+		info.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != ""
+	sum := hex.EncodeToString(hash.Sum(nil))
+	switch {
+	case uploaded.Size != info.Size:
+		err = fmt.Errorf("size mismatch: transferred %d of %d bytes", uploaded.Size, info.Size)
+	case !encrypted && md5ETag.MatchString(info.ETag) && sum != info.ETag:
+		err = fmt.Errorf("checksum mismatch: transferred MD5 %s, expected %s", sum, info.ETag)
+	default:
+		return !encrypted && md5ETag.MatchString(info.ETag), nil
+	}
+	if exists && uploaded.VersionID == "" {
+		return false, fmt.Errorf("%w; corrupted copy replaced an existing object and is kept", err)
+	}
+	if removeErr := destination.Client.RemoveObject(ctx, transfer.DestinationBucket, key, minio.RemoveObjectOptions{VersionID: uploaded.VersionID}); removeErr != nil {
+		return false, fmt.Errorf("%w; error removing corrupted copy: %w", err, removeErr)
+	}
+	return false, err
+}
@@ -0,0 +1,210 @@
+package s3manager_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestMultiS3ManagerTransport(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	clientCertFile, clientKeyFile, clientCAs := writeClientCertificate(t, dir)
+
+	// tlsInstanceConfig returns the configuration of an instance served by the
+	// TLS server ts, trusting its certificate.
+	tlsInstanceConfig := func(t *testing.T, ts *httptest.Server) s3manager.S3InstanceConfig {
+		t.Helper()
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600)
+		is.New(t).NoErr(err)
+
+		config := fakeS3InstanceConfig("primary", ts)
+		config.Endpoint = strings.TrimPrefix(ts.URL, "https://")
+		config.UseSSL = true
+		config.CAFile = caFile
+		return config
+	}
+
+	cases := []struct {
+		it            string
+		server        func(t *testing.T) *httptest.Server
+		config        func(t *testing.T, ts *httptest.Server) s3manager.S3InstanceConfig
+		expectedError string
+	}{
+		{
+			it: "trusts endpoints signed by the CA bundle",
+			server: func(t *testing.T) *httptest.Server {
+				t.Helper()
+				return httptest.NewTLSServer(fakeS3Handler(false))
+			},
+			config: tlsInstanceConfig,
+		},
+		{
+			it: "rejects endpoints not signed by a trusted CA",
+			server: func(t *testing.T) *httptest.Server {
+				t.Helper()
+				return httptest.NewTLSServer(fakeS3Handler(false))
+			},
+			config: func(t *testing.T, ts *httptest.Server) s3manager.S3InstanceConfig {
+				t.Helper()
+				config := tlsInstanceConfig(t, ts)
+				config.CAFile = ""
+				return config
+			},
+			expectedError: "certificate signed by unknown authority",
+		},
+		{
+			it: "authenticates with a client certificate",
+			server: func(t *testing.T) *httptest.Server {
+				t.Helper()
+				ts := httptest.NewUnstartedServer(fakeS3Handler(false))
+				ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+				ts.StartTLS()
+				return ts
+			},
+			config: func(t *testing.T, ts *httptest.Server) s3manager.S3InstanceConfig {
+				t.Helper()
+				config := tlsInstanceConfig(t, ts)
+				config.ClientCertFile, config.ClientKeyFile = clientCertFile, clientKeyFile
+				return config
+			},
+		},
+		{
+			it: "requires the minimum TLS version",
+			server: func(t *testing.T) *httptest.Server {
+				t.Helper()
+				ts := httptest.NewUnstartedServer(fakeS3Handler(false))
+				ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+				ts.StartTLS()
+				return ts
+			},
+			config: func(t *testing.T, ts *httptest.Server) s3manager.S3InstanceConfig {
+				t.Helper()
+				config := tlsInstanceConfig(t, ts)
+				config.TLSMinVersion = "1.3"
+				return config
+			},
+			expectedError: "protocol version not supported",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			ts := tc.server(t)
+			t.Cleanup(ts.Close)
+
+			manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{tc.config(t, ts)})
+			is.NoErr(err)
+			instance, err := manager.GetInstance("primary")
+			is.NoErr(err)
+
+			_, err = instance.Client.ListBuckets(context.Background())
+			if tc.expectedError == "" {
+				is.NoErr(err)
+			} else {
+				is.True(err != nil)
+				is.True(strings.Contains(err.Error(), tc.expectedError)) // error names the TLS failure
+			}
+		})
+	}
+
+	t.Run("sends requests through the proxy", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		var proxied atomic.Int32
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Host == "s3.internal:9000" {
+				proxied.Add(1)
+			}
+			fakeS3Handler(false)(w, r)
+		}))
+		t.Cleanup(proxy.Close)
+
+		config := fakeS3InstanceConfig("primary", proxy)
+		config.Endpoint = "s3.internal:9000"
+		config.ProxyURL = proxy.URL
+		config.MaxIdleConns, config.MaxIdleConnsPerHost, config.IdleConnTimeout = 4, 2, 30
+		is.NoErr(config.Validate())
+
+		manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+		is.NoErr(err)
+		instance, err := manager.GetInstance("primary")
+		is.NoErr(err)
+
+		_, err = instance.Client.ListBuckets(context.Background())
+		is.NoErr(err)
+		is.Equal(int32(1), proxied.Load())
+	})
+
+	t.Run("fails for an unreadable CA bundle", func(t *testing.T) {
+		t.Parallel()
+		is := is.New(t)
+
+		config := fakeS3InstanceConfig("primary", newFakeS3Server(t, false))
+		config.UseSSL = true
+		config.CAFile = filepath.Join(dir, "missing.pem")
+
+		_, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+		is.True(err != nil)
+	})
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key
+// to dir and returns their paths and a pool that trusts the certificate.
+func writeClientCertificate(t *testing.T, dir string) (string, string, *x509.CertPool) {
+	t.Helper()
+	is := is.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	is.NoErr(err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "s3manager"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	is.NoErr(err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	is.NoErr(err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	is.NoErr(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	keyFile := filepath.Join(dir, "client-key.pem")
+	is.NoErr(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	certificate, err := x509.ParseCertificate(der)
+	is.NoErr(err)
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+
+	return certFile, keyFile, pool
+}
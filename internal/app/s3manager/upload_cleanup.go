@@ -0,0 +1,93 @@
+package s3manager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// listIncompleteUploads lists all multipart uploads of a bucket that were
+// neither completed nor aborted.
+func listIncompleteUploads(ctx context.Context, s3 S3, bucketName, prefix string) ([]minio.ObjectMultipartInfo, error) {
+	var (
+		uploads                   []minio.ObjectMultipartInfo
+		keyMarker, uploadIDMarker string
+	)
+	for {
+		result, err := s3.ListMultipartUploads(ctx, bucketName, prefix, keyMarker, uploadIDMarker, "", 1000)
+		if err != nil {
+			return nil, fmt.Errorf("error listing multipart uploads: %w", err)
+		}
+		uploads = append(uploads, result.Uploads...)
+		if !result.IsTruncated {
+			return uploads, nil
+		}
+		keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+	}
+}
+
+// abortUploadsInitiatedBefore aborts all incomplete multipart uploads of a
+// bucket that were initiated before cutoff and returns how many it aborted.
+func abortUploadsInitiatedBefore(ctx context.Context, s3 S3, bucketName string, cutoff time.Time) (int, error) {
+	uploads, err := listIncompleteUploads(ctx, s3, bucketName, "")
+	if err != nil {
+		return 0, err
+	}
+
+	aborted := 0
+	for _, upload := range uploads {
+		if !upload.Initiated.Before(cutoff) {
+			continue
+		}
+		if err := s3.AbortMultipartUpload(ctx, bucketName, upload.Key, upload.UploadID); err != nil {
+			return aborted, fmt.Errorf("error aborting upload of %s: %w", upload.Key, err)
+		}
+		aborted++
+	}
+	return aborted, nil
+}
+
+// AbortStaleUploads aborts the incomplete multipart uploads of all accessible
+// buckets of all instances that were initiated longer than age ago. Errors
+// are logged, so that one unreachable instance doesn't stop the cleanup.
+func (m *MultiS3Manager) AbortStaleUploads(ctx context.Context, age time.Duration) {
+	cutoff := time.Now().Add(-age)
+	for _, instance := range m.GetAllInstances() {
+		buckets, err := instance.Client.ListBuckets(ctx)
+		if err != nil {
+			log.Printf("error cleaning up uploads of S3 instance %s: %v", instance.Name, err)
+			continue
+		}
+		for _, bucket := range buckets {
+			if !instance.Buckets.Allows(bucket.Name) {
+				continue
+			}
+			aborted, err := abortUploadsInitiatedBefore(ctx, instance.Client, bucket.Name, cutoff)
+			if err != nil {
+				log.Printf("error cleaning up uploads of bucket %s of S3 instance %s: %v", bucket.Name, instance.Name, err)
+			}
+			if aborted > 0 {
+				log.Printf("Aborted %d stale uploads of bucket %s of S3 instance %s", aborted, bucket.Name, instance.Name)
+			}
+		}
+	}
+}
+
+// RunUploadCleanup aborts stale uploads right away and then every interval,
+// until ctx is done.
+func (m *MultiS3Manager) RunUploadCleanup(ctx context.Context, interval, age time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.AbortStaleUploads(ctx, age)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package s3manager_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/matryer/is"
+)
+
+func TestMultiS3ManagerAbortStaleUploads(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	stale := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	fresh := time.Now().UTC().Format(time.RFC3339)
+
+	var (
+		mu      sync.Mutex
+		aborted []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			_, _ = fmt.Fprint(w, `<ListAllMyBucketsResult><Owner><ID>owner</ID></Owner><Buckets>`+
+				`<Bucket><Name>images</Name></Bucket><Bucket><Name>secret</Name></Bucket>`+
+				`</Buckets></ListAllMyBucketsResult>`)
+		case r.Method == http.MethodGet && r.URL.Path == "/images/" && r.URL.Query().Has("uploads"):
+			_, _ = fmt.Fprintf(w, `<ListMultipartUploadsResult><Bucket>images</Bucket>`+
+				`<Upload><Key>old.img</Key><UploadId>old-id</UploadId><Initiated>%s</Initiated></Upload>`+
+				`<Upload><Key>new.img</Key><UploadId>new-id</UploadId><Initiated>%s</Initiated></Upload>`+
+				`</ListMultipartUploadsResult>`, stale, fresh)
+		case r.Method == http.MethodDelete && r.URL.Path == "/images/old.img":
+			mu.Lock()
+			aborted = append(aborted, r.URL.Query().Get("uploadId"))
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	config := fakeS3InstanceConfig("uploads", ts)
+	config.DeniedBuckets = []string{"secret"}
+	manager, err := s3manager.NewMultiS3Manager([]s3manager.S3InstanceConfig{config})
+	is.NoErr(err)
+
+	manager.AbortStaleUploads(context.Background(), 24*time.Hour)
+
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal([]string{"old-id"}, aborted) // only the stale upload of the allowed bucket
+}
@@ -0,0 +1,239 @@
+package s3manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// maxUploadParts is the maximum number of parts of a multipart upload.
+const maxUploadParts = 10000
+
+// UploadSession describes a resumable upload. Its state is the multipart
+// upload in S3, so it survives restarts of both the browser and the server.
+type UploadSession struct {
+	UploadID string       `json:"upload_id"`
+	Path     string       `json:"path"`
+	PartSize int64        `json:"part_size,omitempty"`
+	Parts    []UploadPart `json:"parts,omitempty"`
+}
+
+// UploadPart is a part of an upload session that was stored in S3.
+type UploadPart struct {
+	PartNumber int    `json:"part_number"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+}
+
+// HandleInitiateUpload starts a resumable upload. The client uploads the
+// object in parts of the returned size, each in its own request, so a failed
+// part can be retried without starting over.
+func HandleInitiateUpload(s3 S3, sseInfo SSEType) http.HandlerFunc {
+	type request struct {
+		Path        string `json:"path"`
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName := mux.Vars(r)["bucketName"]
+
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleHTTPError(w, fmt.Errorf("error decoding body JSON: %w", err))
+			return
+		}
+		if req.Path == "" || len(req.Path) > maxPathLength {
+			http.Error(w, fmt.Sprintf("path must be between 1 and %d bytes long", maxPathLength), http.StatusBadRequest)
+			return
+		}
+		if req.Size < 0 {
+			http.Error(w, "size must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		opts := minio.PutObjectOptions{ContentType: contentType(req.ContentType, req.Path)}
+		var err error
+		opts.ServerSideEncryption, err = serverSideEncryption(sseInfo)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		uploadID, err := s3.NewMultipartUpload(r.Context(), bucketName, req.Path, opts)
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error initiating upload: %w", err))
+			return
+		}
+
+		// Parts must be large enough for the object to fit into the maximum
+		// number of parts.
+		partSize := max(uploadPartSize, (req.Size+maxUploadParts-1)/maxUploadParts)
+		writeJSON(w, http.StatusCreated, UploadSession{UploadID: uploadID, Path: req.Path, PartSize: partSize})
+	}
+}
+
+// HandleListUploadParts lists the parts of an upload session that were
+// stored already, so that a client can resume it.
+func HandleListUploadParts(s3 S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName, uploadID, path, ok := uploadSessionVars(w, r)
+		if !ok {
+			return
+		}
+
+		parts, err := listUploadParts(r.Context(), s3, bucketName, path, uploadID)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+
+		session := UploadSession{UploadID: uploadID, Path: path, Parts: make([]UploadPart, len(parts))}
+		for i, part := range parts {
+			session.Parts[i] = UploadPart{PartNumber: part.PartNumber, Size: part.Size, ETag: part.ETag}
+		}
+		writeJSON(w, http.StatusOK, session)
+	}
+}
+
+// HandleUploadPart stores the request body as a part of an upload session.
+// Uploading a part again replaces it.
+func HandleUploadPart(s3 S3, sseInfo SSEType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName, uploadID, path, ok := uploadSessionVars(w, r)
+		if !ok {
+			return
+		}
+
+		partNumber, err := strconv.Atoi(mux.Vars(r)["partNumber"])
+		if err != nil || partNumber < 1 || partNumber > maxUploadParts {
+			http.Error(w, fmt.Sprintf("part number must be between 1 and %d", maxUploadParts), http.StatusBadRequest)
+			return
+		}
+		if r.ContentLength < 0 {
+			http.Error(w, "the size of a part must be known", http.StatusLengthRequired)
+			return
+		}
+
+		var opts minio.PutObjectPartOptions
+		// Only the customer provided key has to be sent with every part.
+		if sseInfo.Type == "SSE-C" {
+			opts.SSE, err = serverSideEncryption(sseInfo)
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+		}
+
+		part, err := s3.PutObjectPart(r.Context(), bucketName, path, uploadID, partNumber, r.Body, r.ContentLength, opts)
+		if err != nil {
+			handleHTTPError(w, fmt.Errorf("error uploading part %d: %w", partNumber, err))
+			return
+		}
+		writeJSON(w, http.StatusOK, UploadPart{PartNumber: part.PartNumber, Size: part.Size, ETag: part.ETag})
+	}
+}
+
+// HandleCompleteUpload assembles the parts of an upload session into the
+// object. The parts are taken from S3, so the client doesn't have to keep
+// track of them.
+func HandleCompleteUpload(s3 S3, sseInfo SSEType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName, uploadID, path, ok := uploadSessionVars(w, r)
+		if !ok {
+			return
+		}
+
+		parts, err := listUploadParts(r.Context(), s3, bucketName, path, uploadID)
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		if len(parts) == 0 {
+			http.Error(w, "no part uploaded", http.StatusBadRequest)
+			return
+		}
+
+		completeParts := make([]minio.CompletePart, len(parts))
+		var size int64
+		for i, part := range parts {
+			completeParts[i] = minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag}
+			size += part.Size
+		}
+
+		var opts minio.PutObjectOptions
+		if sseInfo.Type == "SSE-C" {
+			opts.ServerSideEncryption, err = serverSideEncryption(sseInfo)
+			if err != nil {
+				handleHTTPError(w, err)
+				return
+			}
+		}
+
+		if _, err := s3.CompleteMultipartUpload(r.Context(), bucketName, path, uploadID, completeParts, opts); err != nil {
+			handleHTTPError(w, fmt.Errorf("error completing upload: %w", err))
+			return
+		}
+		writeJSON(w, http.StatusCreated, UploadResult{Path: path, Size: size})
+	}
+}
+
+// HandleAbortUpload aborts an upload session and deletes its parts.
+func HandleAbortUpload(s3 S3) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName, uploadID, path, ok := uploadSessionVars(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s3.AbortMultipartUpload(r.Context(), bucketName, path, uploadID); err != nil {
+			handleHTTPError(w, fmt.Errorf("error aborting upload: %w", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// uploadSessionVars returns the bucket, upload ID and object path of a request
+// to an upload session. It responds with 400 and returns false if the path is
+// missing.
+func uploadSessionVars(w http.ResponseWriter, r *http.Request) (string, string, string, bool) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "path must not be empty", http.StatusBadRequest)
+		return "", "", "", false
+	}
+	return mux.Vars(r)["bucketName"], mux.Vars(r)["uploadId"], path, true
+}
+
+// listUploadParts lists all parts of a multipart upload in order.
+func listUploadParts(ctx context.Context, s3 S3, bucketName, path, uploadID string) ([]minio.ObjectPart, error) {
+	var parts []minio.ObjectPart
+	marker := 0
+	for {
+		result, err := s3.ListObjectParts(ctx, bucketName, path, uploadID, marker, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("error listing parts: %w", err)
+		}
+		parts = append(parts, result.ObjectParts...)
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// writeJSON responds with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error encoding JSON: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package s3manager_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/cloudlena/s3manager/internal/app/s3manager/mocks"
+	"github.com/gorilla/mux"
+	"github.com/matryer/is"
+	"github.com/minio/minio-go/v7"
+)
+
+// uploadSessionRouter routes the upload session API to handlers using s3.
+func uploadSessionRouter(s3 s3manager.S3, sseInfo s3manager.SSEType) *mux.Router {
+	r := mux.NewRouter()
+	r.Handle("/buckets/{bucketName}/uploads", s3manager.HandleInitiateUpload(s3, sseInfo)).Methods(http.MethodPost)
+	r.Handle("/buckets/{bucketName}/uploads/{uploadId}", s3manager.HandleListUploadParts(s3)).Methods(http.MethodGet)
+	r.Handle("/buckets/{bucketName}/uploads/{uploadId}", s3manager.HandleAbortUpload(s3)).Methods(http.MethodDelete)
+	r.Handle("/buckets/{bucketName}/uploads/{uploadId}/parts/{partNumber}", s3manager.HandleUploadPart(s3, sseInfo)).Methods(http.MethodPut)
+	r.Handle("/buckets/{bucketName}/uploads/{uploadId}/complete", s3manager.HandleCompleteUpload(s3, sseInfo)).Methods(http.MethodPost)
+	return r
+}
+
+func TestHandleInitiateUpload(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		body                 string
+		newMultipartErr      error
+		expectedStatusCode   int
+		expectedContentType  string
+		expectedPartSize     int64
+		expectedBodyContains string
+	}{
+		{
+			it:                  "starts an upload session",
+			body:                `{"path": "images/disk", "size": 1000}`,
+			expectedStatusCode:  http.StatusCreated,
+			expectedContentType: "application/octet-stream",
+			expectedPartSize:    16 << 20,
+		},
+		{
+			it:                  "grows parts to fit huge objects into 10000 parts",
+			body:                `{"path": "images/disk.img", "size": 500000000000, "content_type": "application/x-raw-disk-image"}`,
+			expectedStatusCode:  http.StatusCreated,
+			expectedContentType: "application/x-raw-disk-image",
+			expectedPartSize:    50000000,
+		},
+		{
+			it:                  "guesses the content type from the path",
+			body:                `{"path": "notes.txt", "content_type": "application/octet-stream"}`,
+			expectedStatusCode:  http.StatusCreated,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedPartSize:    16 << 20,
+		},
+		{
+			it:                   "rejects a missing path",
+			body:                 `{"size": 1000}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path must be between 1 and 1024 bytes long",
+		},
+		{
+			it:                   "rejects a negative size",
+			body:                 `{"path": "disk.img", "size": -1}`,
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "size must not be negative",
+		},
+		{
+			it:                   "returns error if there is an S3 error",
+			body:                 `{"path": "disk"}`,
+			newMultipartErr:      errS3,
+			expectedContentType:  "application/octet-stream",
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedBodyContains: "mocked s3 error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3 := &mocks.S3Mock{
+				NewMultipartUploadFunc: func(_ context.Context, bucket, object string, opts minio.PutObjectOptions) (string, error) {
+					is.Equal("BUCKET-NAME", bucket)
+					is.Equal(tc.expectedContentType, opts.ContentType)
+					return "upload-1", tc.newMultipartErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/buckets/BUCKET-NAME/uploads", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			uploadSessionRouter(s3, s3manager.SSEType{}).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+			if tc.expectedStatusCode == http.StatusCreated {
+				var session s3manager.UploadSession
+				is.NoErr(json.NewDecoder(rr.Body).Decode(&session))
+				is.Equal("upload-1", session.UploadID)
+				is.Equal(tc.expectedPartSize, session.PartSize)
+			}
+		})
+	}
+}
+
+func TestHandleUploadPart(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		url                  string
+		sseInfo              s3manager.SSEType
+		putObjectPartErr     error
+		expectedStatusCode   int
+		expectedBodyContains string
+	}{
+		{
+			it:                   "uploads a part",
+			url:                  "/buckets/BUCKET-NAME/uploads/upload-1/parts/3?path=disk.img",
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"part_number":3`,
+		},
+		{
+			it:                   "sends the customer provided key with every part",
+			url:                  "/buckets/BUCKET-NAME/uploads/upload-1/parts/3?path=disk.img",
+			sseInfo:              s3manager.SSEType{Type: "SSE-C", Key: "32byteslongsecretkeymustprovided"},
+			expectedStatusCode:   http.StatusOK,
+			expectedBodyContains: `"part_number":3`,
+		},
+		{
+			it:                   "rejects an invalid part number",
+			url:                  "/buckets/BUCKET-NAME/uploads/upload-1/parts/10001?path=disk.img",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "part number must be between 1 and 10000",
+		},
+		{
+			it:                   "rejects a missing path",
+			url:                  "/buckets/BUCKET-NAME/uploads/upload-1/parts/1",
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "path must not be empty",
+		},
+		{
+			it:                   "returns 404 if the upload doesn't exist",
+			url:                  "/buckets/BUCKET-NAME/uploads/upload-1/parts/1?path=disk.img",
+			putObjectPartErr:     minio.ErrorResponse{Code: "NoSuchUpload", Message: "The specified upload does not exist."},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedBodyContains: "The specified upload does not exist.",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			s3 := &mocks.S3Mock{
+				PutObjectPartFunc: func(_ context.Context, _, object, uploadID string, partID int, data io.Reader, size int64, opts minio.PutObjectPartOptions) (minio.ObjectPart, error) {
+					is.Equal("disk.img", object)
+					is.Equal("upload-1", uploadID)
+					is.Equal(int64(len("part content")), size)
+					is.Equal(tc.sseInfo.Type == "SSE-C", opts.SSE != nil)
+					content, err := io.ReadAll(data)
+					is.NoErr(err)
+					is.Equal("part content", string(content))
+					return minio.ObjectPart{PartNumber: partID, Size: size, ETag: "etag"}, tc.putObjectPartErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPut, tc.url, strings.NewReader("part content"))
+			rr := httptest.NewRecorder()
+			uploadSessionRouter(s3, tc.sseInfo).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+		})
+	}
+}
+
+func TestHandleCompleteUpload(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		it                   string
+		pages                []minio.ListObjectPartsResult
+		completeErr          error
+		expectedStatusCode   int
+		expectedParts        []int
+		expectedBodyContains string
+	}{
+		{
+			it: "completes the upload with all stored parts",
+			pages: []minio.ListObjectPartsResult{
+				{ObjectParts: []minio.ObjectPart{{PartNumber: 1, Size: 10, ETag: "a"}, {PartNumber: 2, Size: 10, ETag: "b"}}, IsTruncated: true, NextPartNumberMarker: 2},
+				{ObjectParts: []minio.ObjectPart{{PartNumber: 3, Size: 5, ETag: "c"}}},
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedParts:        []int{1, 2, 3},
+			expectedBodyContains: `"size":25`,
+		},
+		{
+			it:                   "rejects completing an upload without parts",
+			pages:                []minio.ListObjectPartsResult{{}},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedBodyContains: "no part uploaded",
+		},
+		{
+			it: "returns error if there is an S3 error",
+			pages: []minio.ListObjectPartsResult{
+				{ObjectParts: []minio.ObjectPart{{PartNumber: 1, Size: 10, ETag: "a"}}},
+			},
+			completeErr:          errS3,
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedParts:        []int{1},
+			expectedBodyContains: "mocked s3 error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.it, func(t *testing.T) {
+			t.Parallel()
+			is := is.New(t)
+
+			var completed []int
+			s3 := &mocks.S3Mock{
+				ListObjectPartsFunc: func(_ context.Context, _, _, _ string, partNumberMarker, _ int) (minio.ListObjectPartsResult, error) {
+					for i, page := range tc.pages {
+						if i == 0 && partNumberMarker == 0 || i > 0 && tc.pages[i-1].NextPartNumberMarker == partNumberMarker {
+							return page, nil
+						}
+					}
+					t.Fatalf("unexpected part number marker %d", partNumberMarker)
+					return minio.ListObjectPartsResult{}, nil
+				},
+				CompleteMultipartUploadFunc: func(_ context.Context, _, object, _ string, parts []minio.CompletePart, _ minio.PutObjectOptions) (minio.UploadInfo, error) {
+					is.Equal("disk.img", object)
+					for _, part := range parts {
+						completed = append(completed, part.PartNumber)
+					}
+					return minio.UploadInfo{}, tc.completeErr
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/buckets/BUCKET-NAME/uploads/upload-1/complete?path=disk.img", nil)
+			rr := httptest.NewRecorder()
+			uploadSessionRouter(s3, s3manager.SSEType{}).ServeHTTP(rr, req)
+
+			is.Equal(tc.expectedStatusCode, rr.Code)
+			is.Equal(tc.expectedParts, completed)
+			is.True(strings.Contains(rr.Body.String(), tc.expectedBodyContains))
+		})
+	}
+}
+
+func TestHandleListUploadPartsAndAbortUpload(t *testing.T) {
+	t.Parallel()
+	is := is.New(t)
+
+	aborted := false
+	s3 := &mocks.S3Mock{
+		ListObjectPartsFunc: func(context.Context, string, string, string, int, int) (minio.ListObjectPartsResult, error) {
+			if aborted {
+				return minio.ListObjectPartsResult{}, minio.ErrorResponse{Code: "NoSuchUpload", Message: "The specified upload does not exist."}
+			}
+			return minio.ListObjectPartsResult{ObjectParts: []minio.ObjectPart{{PartNumber: 1, Size: 10, ETag: "a"}}}, nil
+		},
+		AbortMultipartUploadFunc: func(_ context.Context, bucket, object, uploadID string) error {
+			is.Equal("BUCKET-NAME", bucket)
+			is.Equal("disk.img", object)
+			is.Equal("upload-1", uploadID)
+			aborted = true
+			return nil
+		},
+	}
+	router := uploadSessionRouter(s3, s3manager.SSEType{})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/buckets/BUCKET-NAME/uploads/upload-1?path=disk.img", nil))
+	is.Equal(http.StatusOK, rr.Code)
+	var session s3manager.UploadSession
+	is.NoErr(json.NewDecoder(rr.Body).Decode(&session))
+	is.Equal([]s3manager.UploadPart{{PartNumber: 1, Size: 10, ETag: "a"}}, session.Parts)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/buckets/BUCKET-NAME/uploads/upload-1?path=disk.img", nil))
+	is.Equal(http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/buckets/BUCKET-NAME/uploads/upload-1?path=disk.img", nil))
+	is.Equal(http.StatusNotFound, rr.Code)
+}
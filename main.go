@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 	_ "time/tzdata"
 
 	"github.com/cloudlena/adapters/logging"
 	"github.com/cloudlena/s3manager/internal/app/s3manager"
+	"github.com/fsnotify/fsnotify"
 	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
 )
@@ -25,22 +32,464 @@ var staticFS embed.FS
 
 type configuration struct {
 	S3Instances   []s3manager.S3InstanceConfig
-	AllowDelete   bool
-	ForceDownload bool
-	ListRecursive bool
-	ShowVersions  bool
-	ShowMetadata  bool
 	Port          string
 	Timeout       int32
-	SseType       string
-	SseKey        string
-	BucketName    string
+	AuthType      string
+	HtpasswdFile  string
+	OIDC          s3manager.OIDCConfig
+	RoleBindings  []s3manager.RoleBinding
+	InstanceStore string
+	ShareStore    string
+	TransferStore string
+	Transfers     int
+	HealthCheck   time.Duration
+	HealthTimeout time.Duration
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSSelfSigned bool
+	HTTP2         bool
+	RedirectPort  string
+	DrainDelay    time.Duration
+	DrainTimeout  time.Duration
+	UploadCleanup time.Duration
+	UploadMaxAge  time.Duration
+}
+
+// instanceKeys lists the settings of an S3 instance as used in the
+// configuration file. Upper-cased and prefixed with the instance number, they
+// are also the names of the numbered environment variables (e.g. 1_ENDPOINT).
+var instanceKeys = []string{
+	"name",
+	"endpoint",
+	"use_iam",
+	"iam_endpoint",
+	"access_key_id",
+	"secret_access_key",
+	"credentials_provider",
+	"credentials_file",
+	"credentials_profile",
+	"sts_endpoint",
+	"role_arn",
+	"role_session_name",
+	"web_identity_token_file",
+	"region",
+	"use_ssl",
+	"skip_ssl_verification",
+	"ca_file",
+	"client_cert_file",
+	"client_key_file",
+	"tls_min_version",
+	"proxy_url",
+	"max_idle_conns",
+	"max_idle_conns_per_host",
+	"idle_conn_timeout",
+	"signature_type",
+	"allowed_buckets",
+	"denied_buckets",
+	"allow_delete",
+	"force_download",
+	"list_recursive",
+	"show_versions",
+	"show_metadata",
+	"sse_type",
+	"sse_key",
+	"max_presign_expiry",
+}
+
+// secretInstanceKeys lists the instance settings that can also be read from a
+// file, given by the setting suffixed with _file (e.g. 1_SECRET_ACCESS_KEY_FILE).
+var secretInstanceKeys = []string{
+	"access_key_id",
+	"secret_access_key",
+	"sse_key",
+}
+
+// readSecret returns the setting key or, if key_FILE is set, the contents of
+// that file. This keeps secrets out of the process environment.
+func readSecret(key string) (string, error) {
+	file := viper.GetString(key + "_FILE")
+	if file == "" {
+		return viper.GetString(key), nil
+	}
+	return readSecretFile(key, file)
+}
+
+// readSecretFile reads the secret key from file, dropping the trailing newline
+// most editors and secret stores add.
+func readSecretFile(key, file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s from file: %w", key, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// instanceDefaults returns the settings an instance uses unless it overrides
+// them. The feature flags default to their global settings (e.g. ALLOW_DELETE).
+func instanceDefaults() (map[string]any, error) {
+	viper.SetDefault("ALLOW_DELETE", true)
+	viper.SetDefault("FORCE_DOWNLOAD", true)
+	viper.SetDefault("SHOW_VERSIONS", false)
+	viper.SetDefault("SHOW_METADATA", true)
+
+	sseKey, err := readSecret("SSE_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"use_ssl":               true,
+		"skip_ssl_verification": false,
+		"signature_type":        "V4",
+		"allow_delete":          viper.GetBool("ALLOW_DELETE"),
+		"force_download":        viper.GetBool("FORCE_DOWNLOAD"),
+		"list_recursive":        viper.GetBool("LIST_RECURSIVE"),
+		"show_versions":         viper.GetBool("SHOW_VERSIONS"),
+		"show_metadata":         viper.GetBool("SHOW_METADATA"),
+		"sse_type":              viper.GetString("SSE_TYPE"),
+		"sse_key":               sseKey,
+		"max_presign_expiry":    viper.GetInt("MAX_PRESIGN_EXPIRY"),
+	}, nil
 }
 
 func parseConfiguration() configuration {
 	viper.AutomaticEnv()
 
-	// Parse S3 instances from numbered environment variables
+	// Read the optional configuration file. Its top-level keys are the
+	// lower-cased names of the environment variables, which take precedence.
+	if configFile := viper.GetString("CONFIG_FILE"); configFile != "" {
+		viper.SetConfigFile(configFile)
+		if err := viper.ReadInConfig(); err != nil {
+			log.Fatalf("error reading configuration file: %v", err)
+		}
+	}
+
+	s3Instances, err := loadInstances()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	viper.SetDefault("PORT", "8080")
+	port := viper.GetString("PORT")
+
+	viper.SetDefault("TIMEOUT", 600)
+	timeout := viper.GetInt32("TIMEOUT")
+
+	viper.SetDefault("AUTH_TYPE", "")
+	authType := viper.GetString("AUTH_TYPE")
+
+	htpasswdFile := viper.GetString("AUTH_HTPASSWD_FILE")
+
+	viper.SetDefault("OIDC_SCOPES", "openid profile email")
+	viper.SetDefault("OIDC_GROUPS_CLAIM", "groups")
+	viper.SetDefault("SESSION_TIMEOUT", 8*60*60)
+	oidcClientSecret, err := readSecret("OIDC_CLIENT_SECRET")
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	sessionSecret, err := readSecret("SESSION_SECRET")
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	oidcConfig := s3manager.OIDCConfig{
+		IssuerURL:     viper.GetString("OIDC_ISSUER_URL"),
+		ClientID:      viper.GetString("OIDC_CLIENT_ID"),
+		ClientSecret:  oidcClientSecret,
+		RedirectURL:   viper.GetString("OIDC_REDIRECT_URL"),
+		Scopes:        strings.Fields(viper.GetString("OIDC_SCOPES")),
+		GroupsClaim:   viper.GetString("OIDC_GROUPS_CLAIM"),
+		SessionSecret: []byte(sessionSecret),
+		SessionTTL:    time.Duration(viper.GetInt("SESSION_TIMEOUT")) * time.Second,
+	}
+
+	var roleBindings []s3manager.RoleBinding
+	if accessPolicyFile := viper.GetString("ACCESS_POLICY_FILE"); accessPolicyFile != "" {
+		if authType == "" {
+			log.Fatal("ACCESS_POLICY_FILE requires AUTH_TYPE to be set")
+		}
+		policyConfig := viper.New()
+		policyConfig.SetConfigFile(accessPolicyFile)
+		if err := policyConfig.ReadInConfig(); err != nil {
+			log.Fatalf("error reading access policy file: %v", err)
+		}
+		if err := policyConfig.UnmarshalKey("roleBindings", &roleBindings); err != nil {
+			log.Fatalf("error parsing access policy file: %v", err)
+		}
+		if len(roleBindings) == 0 {
+			log.Fatal("access policy file does not contain any roleBindings")
+		}
+	}
+	if viper.IsSet("role_bindings") {
+		if authType == "" {
+			log.Fatal("role_bindings require auth_type to be set")
+		}
+		var fileRoleBindings []s3manager.RoleBinding
+		if err := viper.UnmarshalKey("role_bindings", &fileRoleBindings); err != nil {
+			log.Fatalf("invalid configuration file: role_bindings: %v", err)
+		}
+		roleBindings = append(roleBindings, fileRoleBindings...)
+	}
+
+	viper.SetDefault("INSTANCE_STORE_FILE", "")
+	instanceStore := viper.GetString("INSTANCE_STORE_FILE")
+	if instanceStore != "" && authType == "" {
+		// Without authentication, everyone could register instances.
+		log.Fatal("INSTANCE_STORE_FILE requires AUTH_TYPE to be set")
+	}
+
+	viper.SetDefault("SHARE_STORE_FILE", "")
+	shareStore := viper.GetString("SHARE_STORE_FILE")
+
+	viper.SetDefault("TRANSFER_STORE_FILE", "")
+	transferStore := viper.GetString("TRANSFER_STORE_FILE")
+
+	viper.SetDefault("TRANSFER_CONCURRENCY", 4)
+	transfers := viper.GetInt("TRANSFER_CONCURRENCY")
+	if transfers < 1 {
+		log.Fatal("invalid configuration: TRANSFER_CONCURRENCY must be at least 1")
+	}
+
+	viper.SetDefault("HEALTH_CHECK_INTERVAL", 30)
+	healthCheck := time.Duration(viper.GetInt("HEALTH_CHECK_INTERVAL")) * time.Second
+
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", 5)
+	healthTimeout := time.Duration(viper.GetInt("HEALTH_CHECK_TIMEOUT")) * time.Second
+
+	tlsCertFile := viper.GetString("TLS_CERT_FILE")
+	tlsKeyFile := viper.GetString("TLS_KEY_FILE")
+	tlsSelfSigned := viper.GetBool("TLS_SELF_SIGNED")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		log.Fatal("invalid configuration: TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if tlsSelfSigned && tlsCertFile != "" {
+		log.Fatal("invalid configuration: TLS_SELF_SIGNED cannot be combined with TLS_CERT_FILE")
+	}
+
+	viper.SetDefault("HTTP2", true)
+	http2 := viper.GetBool("HTTP2")
+
+	redirectPort := viper.GetString("HTTP_REDIRECT_PORT")
+	if redirectPort != "" && tlsCertFile == "" && !tlsSelfSigned {
+		log.Fatal("invalid configuration: HTTP_REDIRECT_PORT requires TLS_CERT_FILE or TLS_SELF_SIGNED")
+	}
+
+	viper.SetDefault("SHUTDOWN_DELAY", 5)
+	drainDelay := time.Duration(viper.GetInt("SHUTDOWN_DELAY")) * time.Second
+
+	viper.SetDefault("SHUTDOWN_TIMEOUT", 25)
+	drainTimeout := time.Duration(viper.GetInt("SHUTDOWN_TIMEOUT")) * time.Second
+
+	viper.SetDefault("UPLOAD_CLEANUP_INTERVAL", 3600)
+	uploadCleanup := time.Duration(viper.GetInt("UPLOAD_CLEANUP_INTERVAL")) * time.Second
+
+	viper.SetDefault("UPLOAD_CLEANUP_AGE", 0)
+	uploadMaxAge := time.Duration(viper.GetInt("UPLOAD_CLEANUP_AGE")) * time.Second
+
+	return configuration{
+		S3Instances:   s3Instances,
+		Port:          port,
+		Timeout:       timeout,
+		AuthType:      authType,
+		HtpasswdFile:  htpasswdFile,
+		OIDC:          oidcConfig,
+		RoleBindings:  roleBindings,
+		InstanceStore: instanceStore,
+		ShareStore:    shareStore,
+		TransferStore: transferStore,
+		Transfers:     transfers,
+		HealthCheck:   healthCheck,
+		HealthTimeout: healthTimeout,
+		TLSCertFile:   tlsCertFile,
+		TLSKeyFile:    tlsKeyFile,
+		TLSSelfSigned: tlsSelfSigned,
+		HTTP2:         http2,
+		RedirectPort:  redirectPort,
+		DrainDelay:    drainDelay,
+		DrainTimeout:  drainTimeout,
+		UploadCleanup: uploadCleanup,
+		UploadMaxAge:  uploadMaxAge,
+	}
+}
+
+// loadInstances reads and validates the S3 instances from the configuration
+// file or, if it does not list any, from numbered environment variables. It
+// is called again whenever the instances are reloaded, so it must not exit.
+func loadInstances() ([]s3manager.S3InstanceConfig, error) {
+	var s3Instances []s3manager.S3InstanceConfig
+	var err error
+	if viper.IsSet("instances") {
+		s3Instances, err = parseInstancesFromFile()
+	} else {
+		s3Instances, err = parseInstancesFromEnv()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s3Instances) == 0 {
+		return nil, fmt.Errorf("no S3 instances configured. Please provide numbered environment variables like 1_NAME, 1_ENDPOINT, etc. or a CONFIG_FILE listing instances")
+	}
+
+	if bucketName := viper.GetString("BUCKET_NAME"); bucketName != "" {
+		if err := s3manager.RestrictToBucket(s3Instances, bucketName); err != nil {
+			return nil, fmt.Errorf("error applying BUCKET_NAME: %w", err)
+		}
+	}
+
+	if err := s3manager.ValidateInstanceConfigs(s3Instances); err != nil {
+		return nil, err
+	}
+
+	return s3Instances, nil
+}
+
+// reloadInstances re-reads the configuration file, if any, and loads the S3
+// instances from it.
+func reloadInstances() ([]s3manager.S3InstanceConfig, error) {
+	if viper.ConfigFileUsed() != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading configuration file: %w", err)
+		}
+	}
+	return loadInstances()
+}
+
+// watchReloadTriggers sends on the returned channel whenever the process
+// receives SIGHUP or the configuration file changes.
+func watchReloadTriggers(ctx context.Context) (<-chan struct{}, error) {
+	trigger := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				log.Println("received SIGHUP, reloading S3 instances")
+				notify()
+			}
+		}
+	}()
+
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return trigger, nil
+	}
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Watch the directory rather than the file itself, so editors and
+	// orchestrators that replace the file instead of writing to it are
+	// noticed too. Kubernetes mounts a ConfigMap as a symlink into a data
+	// directory that is swapped atomically, which only shows as events on
+	// other names, so the target of the file is compared on every event.
+	resolved, _ := filepath.EvalSymlinks(configFile)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	go func() {
+		defer func() { _ = watcher.Close() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				changed := filepath.Clean(event.Name) == configFile && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename)
+				if target, err := filepath.EvalSymlinks(configFile); err == nil && target != resolved {
+					resolved, changed = target, true
+				}
+				if changed {
+					log.Println("configuration file changed, reloading S3 instances")
+					notify()
+				}
+			case err := <-watcher.Errors:
+				log.Printf("error watching configuration file: %v", err)
+			}
+		}
+	}()
+
+	return trigger, nil
+}
+
+// parseInstancesFromFile reads the instances listed in the configuration file.
+// Numbered environment variables (e.g. 2_SECRET_ACCESS_KEY) override the
+// settings of the instance at that position.
+func parseInstancesFromFile() ([]s3manager.S3InstanceConfig, error) {
+	var entries []map[string]any
+	if err := viper.UnmarshalKey("instances", &entries); err != nil {
+		return nil, fmt.Errorf("instances: must be a list of instances: %w", err)
+	}
+
+	defaults, err := instanceDefaults()
+	if err != nil {
+		return nil, err
+	}
+	s3Instances := make([]s3manager.S3InstanceConfig, 0, len(entries))
+	for i, entry := range entries {
+		secretFiles := make(map[string]string)
+		for _, key := range secretInstanceKeys {
+			if file, ok := entry[key+"_file"].(string); ok {
+				secretFiles[key] = file
+				delete(entry, key+"_file")
+			}
+			if file, ok := os.LookupEnv(fmt.Sprintf("%d_%s_FILE", i+1, strings.ToUpper(key))); ok {
+				secretFiles[key] = file
+			}
+		}
+
+		instanceConfig := viper.New()
+		for key, value := range defaults {
+			instanceConfig.SetDefault(key, value)
+		}
+		if err := instanceConfig.MergeConfigMap(entry); err != nil {
+			return nil, fmt.Errorf("instances[%d]: %w", i, err)
+		}
+		for _, key := range instanceKeys {
+			if value, ok := os.LookupEnv(fmt.Sprintf("%d_%s", i+1, strings.ToUpper(key))); ok {
+				instanceConfig.Set(key, value)
+			}
+		}
+		for key, file := range secretFiles {
+			value, err := readSecretFile(key, file)
+			if err != nil {
+				return nil, fmt.Errorf("instances[%d]: %w", i, err)
+			}
+			instanceConfig.Set(key, value)
+		}
+
+		var s3Instance s3manager.S3InstanceConfig
+		if err := instanceConfig.UnmarshalExact(&s3Instance); err != nil {
+			return nil, fmt.Errorf("instances[%d]: %w", i, err)
+		}
+		s3Instances = append(s3Instances, s3Instance)
+	}
+
+	return s3Instances, nil
+}
+
+// parseInstancesFromEnv discovers instances from numbered environment
+// variables, stopping at the first number without NAME or ENDPOINT.
+func parseInstancesFromEnv() ([]s3manager.S3InstanceConfig, error) {
+	defaults, err := instanceDefaults()
+	if err != nil {
+		return nil, err
+	}
 	var s3Instances []s3manager.S3InstanceConfig
 	for i := 1; ; i++ {
 		prefix := fmt.Sprintf("%d_", i)
@@ -59,96 +508,136 @@ func parseConfiguration() configuration {
 			break
 		}
 
-		accessKeyID := viper.GetString(prefix + "ACCESS_KEY_ID")
-		secretAccessKey := viper.GetString(prefix + "SECRET_ACCESS_KEY")
+		accessKeyID, err := readSecret(prefix + "ACCESS_KEY_ID")
+		if err != nil {
+			return nil, err
+		}
+		secretAccessKey, err := readSecret(prefix + "SECRET_ACCESS_KEY")
+		if err != nil {
+			return nil, err
+		}
+		credentialsProvider := viper.GetString(prefix + "CREDENTIALS_PROVIDER")
 		useIam := viper.GetBool(prefix + "USE_IAM")
 		iamEndpoint := viper.GetString(prefix + "IAM_ENDPOINT")
 		region := viper.GetString(prefix + "REGION")
 
-		viper.SetDefault(prefix+"USE_SSL", true)
-		useSSL := viper.GetBool(prefix + "USE_SSL")
+		for key, value := range defaults {
+			viper.SetDefault(prefix+strings.ToUpper(key), value)
+		}
 
-		viper.SetDefault(prefix+"SKIP_SSL_VERIFICATION", false)
+		useSSL := viper.GetBool(prefix + "USE_SSL")
 		skipSSLVerification := viper.GetBool(prefix + "SKIP_SSL_VERIFICATION")
-
-		viper.SetDefault(prefix+"SIGNATURE_TYPE", "V4")
 		signatureType := viper.GetString(prefix + "SIGNATURE_TYPE")
 
-		if !useIam {
+		allowedBuckets := splitList(viper.GetString(prefix + "ALLOWED_BUCKETS"))
+		deniedBuckets := splitList(viper.GetString(prefix + "DENIED_BUCKETS"))
+
+		sseKey, err := readSecret(prefix + "SSE_KEY")
+		if err != nil {
+			return nil, err
+		}
+
+		if !useIam && (credentialsProvider == "" || credentialsProvider == s3manager.CredentialsStatic) {
 			if accessKeyID == "" {
-				log.Fatalf("please provide %sACCESS_KEY_ID for instance %s", prefix, name)
+				return nil, fmt.Errorf("please provide %sACCESS_KEY_ID for instance %s", prefix, name)
 			}
 			if secretAccessKey == "" {
-				log.Fatalf("please provide %sSECRET_ACCESS_KEY for instance %s", prefix, name)
+				return nil, fmt.Errorf("please provide %sSECRET_ACCESS_KEY for instance %s", prefix, name)
 			}
 		}
 
 		s3Instances = append(s3Instances, s3manager.S3InstanceConfig{
-			Name:                name,
-			Endpoint:            endpoint,
-			UseIam:              useIam,
-			IamEndpoint:         iamEndpoint,
-			AccessKeyID:         accessKeyID,
-			SecretAccessKey:     secretAccessKey,
-			Region:              region,
-			UseSSL:              useSSL,
-			SkipSSLVerification: skipSSLVerification,
-			SignatureType:       signatureType,
+			Name:                 name,
+			Endpoint:             endpoint,
+			UseIam:               useIam,
+			IamEndpoint:          iamEndpoint,
+			AccessKeyID:          accessKeyID,
+			SecretAccessKey:      secretAccessKey,
+			CredentialsProvider:  credentialsProvider,
+			CredentialsFile:      viper.GetString(prefix + "CREDENTIALS_FILE"),
+			CredentialsProfile:   viper.GetString(prefix + "CREDENTIALS_PROFILE"),
+			STSEndpoint:          viper.GetString(prefix + "STS_ENDPOINT"),
+			RoleARN:              viper.GetString(prefix + "ROLE_ARN"),
+			RoleSessionName:      viper.GetString(prefix + "ROLE_SESSION_NAME"),
+			WebIdentityTokenFile: viper.GetString(prefix + "WEB_IDENTITY_TOKEN_FILE"),
+			Region:               region,
+			UseSSL:               useSSL,
+			SkipSSLVerification:  skipSSLVerification,
+			CAFile:               viper.GetString(prefix + "CA_FILE"),
+			ClientCertFile:       viper.GetString(prefix + "CLIENT_CERT_FILE"),
+			ClientKeyFile:        viper.GetString(prefix + "CLIENT_KEY_FILE"),
+			TLSMinVersion:        viper.GetString(prefix + "TLS_MIN_VERSION"),
+			ProxyURL:             viper.GetString(prefix + "PROXY_URL"),
+			MaxIdleConns:         viper.GetInt(prefix + "MAX_IDLE_CONNS"),
+			MaxIdleConnsPerHost:  viper.GetInt(prefix + "MAX_IDLE_CONNS_PER_HOST"),
+			IdleConnTimeout:      viper.GetInt(prefix + "IDLE_CONN_TIMEOUT"),
+			SignatureType:        signatureType,
+			AllowedBuckets:       allowedBuckets,
+			DeniedBuckets:        deniedBuckets,
+			AllowDelete:          viper.GetBool(prefix + "ALLOW_DELETE"),
+			ForceDownload:        viper.GetBool(prefix + "FORCE_DOWNLOAD"),
+			ListRecursive:        viper.GetBool(prefix + "LIST_RECURSIVE"),
+			ShowVersions:         viper.GetBool(prefix + "SHOW_VERSIONS"),
+			ShowMetadata:         viper.GetBool(prefix + "SHOW_METADATA"),
+			SseType:              viper.GetString(prefix + "SSE_TYPE"),
+			SseKey:               sseKey,
+			MaxPresignExpiry:     viper.GetInt(prefix + "MAX_PRESIGN_EXPIRY"),
 		})
 	}
+	return s3Instances, nil
+}
 
-	if len(s3Instances) == 0 {
-		log.Fatal("no S3 instances configured. Please provide numbered environment variables like 1_NAME, 1_ENDPOINT, etc.")
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	var items []string
+	for item := range strings.SplitSeq(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
+}
 
-	viper.SetDefault("ALLOW_DELETE", true)
-	allowDelete := viper.GetBool("ALLOW_DELETE")
-
-	viper.SetDefault("FORCE_DOWNLOAD", true)
-	forceDownload := viper.GetBool("FORCE_DOWNLOAD")
-
-	listRecursive := viper.GetBool("LIST_RECURSIVE")
-
-	viper.SetDefault("SHOW_VERSIONS", false)
-	showVersions := viper.GetBool("SHOW_VERSIONS")
-
-	viper.SetDefault("SHOW_METADATA", true)
-	showMetadata := viper.GetBool("SHOW_METADATA")
-
-	viper.SetDefault("PORT", "8080")
-	port := viper.GetString("PORT")
-
-	viper.SetDefault("TIMEOUT", 600)
-	timeout := viper.GetInt32("TIMEOUT")
-
-	viper.SetDefault("SSE_TYPE", "")
-	sseType := viper.GetString("SSE_TYPE")
-
-	viper.SetDefault("SSE_KEY", "")
-	sseKey := viper.GetString("SSE_KEY")
-
-	viper.SetDefault("BUCKET_NAME", "")
-	bucketName := viper.GetString("BUCKET_NAME")
-
-	return configuration{
-		S3Instances:   s3Instances,
-		AllowDelete:   allowDelete,
-		ForceDownload: forceDownload,
-		ListRecursive: listRecursive,
-		ShowVersions:  showVersions,
-		ShowMetadata:  showMetadata,
-		Port:          port,
-		Timeout:       timeout,
-		SseType:       sseType,
-		SseKey:        sseKey,
-		BucketName:    bucketName,
+// newAuthenticator sets up the authenticator selected by AUTH_TYPE. It returns
+// nil if authentication is disabled.
+func newAuthenticator(configuration configuration, rootURL string) (s3manager.Authenticator, error) {
+	switch configuration.AuthType {
+	case "":
+		return nil, nil
+	case "basic":
+		if configuration.HtpasswdFile == "" {
+			return nil, fmt.Errorf("please provide AUTH_HTPASSWD_FILE for basic authentication")
+		}
+		f, err := os.Open(configuration.HtpasswdFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening htpasswd file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		return s3manager.NewBasicAuthenticator(f, "S3 Manager")
+	case "oidc":
+		oidcConfig := configuration.OIDC
+		oidcConfig.RootURL = rootURL
+		// Sessions are stored in a cookie, so only keep the groups the
+		// access policy refers to.
+		if configuration.RoleBindings != nil {
+			oidcConfig.Groups = []string{}
+			for _, b := range configuration.RoleBindings {
+				oidcConfig.Groups = append(oidcConfig.Groups, b.Groups...)
+			}
+		}
+		return s3manager.NewOIDCAuthenticator(context.Background(), oidcConfig, nil)
+	default:
+		return nil, fmt.Errorf("invalid AUTH_TYPE: %s", configuration.AuthType)
 	}
 }
 
 func main() {
 	configuration := parseConfiguration()
 
-	sseType := s3manager.SSEType{Type: configuration.SseType, Key: configuration.SseKey}
+	// Stop background work and shut down gracefully on SIGTERM or SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	serverTimeout := time.Duration(configuration.Timeout) * time.Second
 
 	// Set up templates
@@ -167,6 +656,54 @@ func main() {
 	if err != nil {
 		log.Fatalln(fmt.Errorf("error creating multi s3 manager: %w", err))
 	}
+	if configuration.RoleBindings != nil {
+		accessPolicy, err := s3manager.NewAccessPolicy(configuration.RoleBindings)
+		if err != nil {
+			log.Fatalln(fmt.Errorf("error creating access policy: %w", err))
+		}
+		s3Manager.SetAccessPolicy(accessPolicy)
+	}
+	if configuration.InstanceStore != "" {
+		err := s3Manager.UseInstanceStore(s3manager.NewFileInstanceStore(configuration.InstanceStore))
+		if err != nil {
+			log.Fatalln(fmt.Errorf("error loading instance store: %w", err))
+		}
+	}
+	if configuration.ShareStore != "" {
+		err := s3Manager.UseShareStore(s3manager.NewFileShareStore(configuration.ShareStore))
+		if err != nil {
+			log.Fatalln(fmt.Errorf("error loading share store: %w", err))
+		}
+	}
+	// Jobs and transfers are waited for on shutdown like requests in flight.
+	drainer := s3manager.NewDrainer()
+	s3Manager.UseDrainer(drainer)
+	s3Manager.SetTransferConcurrency(configuration.Transfers)
+	if configuration.TransferStore != "" {
+		err := s3Manager.UseTransferStore(s3manager.NewFileTransferStore(configuration.TransferStore))
+		if err != nil {
+			log.Fatalln(fmt.Errorf("error loading transfer store: %w", err))
+		}
+	}
+
+	// Reload S3 instances on SIGHUP or when the configuration file changes
+	reloadTrigger, err := watchReloadTriggers(ctx)
+	if err != nil {
+		log.Fatalln(fmt.Errorf("error watching configuration: %w", err))
+	}
+	go s3Manager.Watch(ctx, reloadInstances, reloadTrigger)
+
+	// Periodically check the health of all S3 instances
+	readyz := s3manager.HandleHealthz()
+	if configuration.HealthCheck > 0 {
+		go s3Manager.RunHealthChecks(ctx, configuration.HealthCheck, configuration.HealthTimeout)
+		readyz = s3manager.HandleReadyz(s3Manager)
+	}
+
+	// Periodically abort uploads that were never completed
+	if configuration.UploadMaxAge > 0 && configuration.UploadCleanup > 0 {
+		go s3Manager.RunUploadCleanup(ctx, configuration.UploadCleanup, configuration.UploadMaxAge)
+	}
 
 	// Check for a root URL to insert into HTML templates in case of reverse proxying
 	rootURL, rootSet := os.LookupEnv("ROOT_URL")
@@ -179,11 +716,11 @@ func main() {
 
 	// Root redirects to first instance's buckets page
 	r.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		instances := s3Manager.GetAllInstances()
+		instances := s3Manager.GetAccessibleInstances(r)
 		if len(instances) > 0 {
-			http.Redirect(w, r, rootURL+"/"+instances[0].Name+"/buckets", http.StatusPermanentRedirect)
+			http.Redirect(w, r, rootURL+"/"+instances[0].Name+"/buckets", http.StatusTemporaryRedirect)
 		} else {
-			http.Error(w, "No S3 instances configured", http.StatusInternalServerError)
+			http.Error(w, "No accessible S3 instances configured", http.StatusForbidden)
 		}
 	})).Methods(http.MethodGet)
 
@@ -191,35 +728,153 @@ func main() {
 
 	// S3 instance management endpoints
 	r.Handle("/api/s3-instances", s3manager.HandleGetS3Instances(s3Manager)).Methods(http.MethodGet)
+	if configuration.InstanceStore != "" {
+		r.Handle("/api/s3-instances", s3manager.HandleCreateS3Instance(s3Manager)).Methods(http.MethodPost)
+		r.Handle("/api/s3-instances/{instance}", s3manager.HandleUpdateS3Instance(s3Manager)).Methods(http.MethodPut)
+		r.Handle("/api/s3-instances/{instance}", s3manager.HandleDeleteS3Instance(s3Manager)).Methods(http.MethodDelete)
+	}
+
+	// Transfers between S3 instances
+	r.Handle("/transfers", s3manager.HandleTransfersView(s3Manager, templates, rootURL)).Methods(http.MethodGet)
+	r.Handle("/api/transfers", s3manager.HandleListTransfers(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/api/transfers", s3manager.HandleCreateTransfer(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/api/transfers/{transferId}", s3manager.HandleGetTransfer(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/api/transfers/{transferId}", s3manager.HandleCancelTransfer(s3Manager)).Methods(http.MethodDelete)
 
 	// S3 management endpoints (with instance in URL)
-	r.Handle("/{instance}/buckets", s3manager.HandleBucketsViewWithManager(s3Manager, templates, configuration.AllowDelete, rootURL, configuration.BucketName)).Methods(http.MethodGet)
-	r.PathPrefix("/{instance}/buckets/").Handler(s3manager.HandleBucketViewWithManager(s3Manager, templates, configuration.AllowDelete, configuration.ListRecursive, rootURL, configuration.ShowVersions, configuration.ShowMetadata)).Methods(http.MethodGet)
+	r.Handle("/{instance}/buckets", s3manager.HandleBucketsViewWithManager(s3Manager, templates, rootURL)).Methods(http.MethodGet)
+	r.PathPrefix("/{instance}/buckets/").Handler(s3manager.HandleBucketViewWithManager(s3Manager, templates, rootURL)).Methods(http.MethodGet)
 	r.Handle("/{instance}/api/buckets", s3manager.HandleCreateBucketWithManager(s3Manager)).Methods(http.MethodPost)
-	if configuration.AllowDelete {
-		r.Handle("/{instance}/api/buckets/{bucketName}", s3manager.HandleDeleteBucketWithManager(s3Manager)).Methods(http.MethodDelete)
-	}
-	r.Handle("/{instance}/api/buckets/{bucketName}/objects", s3manager.HandleCreateObjectWithManager(s3Manager, sseType)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}", s3manager.HandleDeleteBucketWithManager(s3Manager)).Methods(http.MethodDelete)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects", s3manager.HandleCreateObjectWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/folders", s3manager.HandleCreateFolderWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/upload-policy", s3manager.HandleGenerateUploadPolicyWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads", s3manager.HandleListIncompleteUploadsWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads", s3manager.HandleAbortIncompleteUploadsWithManager(s3Manager)).Methods(http.MethodDelete)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads", s3manager.HandleInitiateUploadWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads/{uploadId}", s3manager.HandleListUploadPartsWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads/{uploadId}", s3manager.HandleAbortUploadWithManager(s3Manager)).Methods(http.MethodDelete)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads/{uploadId}/parts/{partNumber}", s3manager.HandleUploadPartWithManager(s3Manager)).Methods(http.MethodPut)
+	r.Handle("/{instance}/api/buckets/{bucketName}/uploads/{uploadId}/complete", s3manager.HandleCompleteUploadWithManager(s3Manager)).Methods(http.MethodPost)
 	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/url", s3manager.HandleGenerateURLWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/upload-url", s3manager.HandleGenerateUploadURLWithManager(s3Manager)).Methods(http.MethodGet)
 	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/public-access", s3manager.HandleCheckPublicAccessWithManager(s3Manager)).Methods(http.MethodGet)
-	if configuration.ShowMetadata {
-		r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/metadata", s3manager.HandleGetObjectMetadataWithManager(s3Manager)).Methods(http.MethodGet)
-	}
-	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", s3manager.HandleGetObjectWithManager(s3Manager, configuration.ForceDownload, configuration.ShowVersions)).Methods(http.MethodGet)
-	if configuration.AllowDelete {
-		r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", s3manager.HandleDeleteObjectWithManager(s3Manager)).Methods(http.MethodDelete)
-		r.Handle("/{instance}/api/buckets/{bucketName}/objects/bulk-delete", s3manager.HandleBulkDeleteObjectsWithManager(s3Manager)).Methods(http.MethodPost)
-	}
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}/metadata", s3manager.HandleGetObjectMetadataWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", s3manager.HandleGetObjectWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects/{objectName:.*}", s3manager.HandleDeleteObjectWithManager(s3Manager)).Methods(http.MethodDelete)
+	r.Handle("/{instance}/api/buckets/{bucketName}/objects/bulk-delete", s3manager.HandleBulkDeleteObjectsWithManager(s3Manager)).Methods(http.MethodPost)
 	r.Handle("/{instance}/api/buckets/{bucketName}/objects/bulk-download", s3manager.HandleBulkDownloadObjectsWithManager(s3Manager)).Methods(http.MethodPost)
 	r.Handle("/{instance}/api/buckets/{bucketName}/policy", s3manager.HandleGetBucketPolicyWithManager(s3Manager)).Methods(http.MethodGet)
 	r.Handle("/{instance}/api/buckets/{bucketName}/policy", s3manager.HandlePutBucketPolicyWithManager(s3Manager)).Methods(http.MethodPut)
+	r.Handle("/{instance}/api/buckets/{bucketName}/move", s3manager.HandleMoveObjectWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/copy", s3manager.HandleCopyObjectsWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/delete-prefix", s3manager.HandleDeletePrefixWithManager(s3Manager)).Methods(http.MethodPost)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs", s3manager.HandleListJobsWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs/{jobId}", s3manager.HandleGetJobWithManager(s3Manager)).Methods(http.MethodGet)
+	r.Handle("/{instance}/api/buckets/{bucketName}/jobs/{jobId}", s3manager.HandleCancelJobWithManager(s3Manager)).Methods(http.MethodDelete)
+	if configuration.ShareStore != "" {
+		r.Handle("/{instance}/api/buckets/{bucketName}/shares", s3manager.HandleListSharesWithManager(s3Manager)).Methods(http.MethodGet)
+		r.Handle("/{instance}/api/buckets/{bucketName}/shares", s3manager.HandleCreateShareWithManager(s3Manager)).Methods(http.MethodPost)
+		r.Handle("/{instance}/api/buckets/{bucketName}/shares/{token}", s3manager.HandleRevokeShareWithManager(s3Manager)).Methods(http.MethodDelete)
+	}
+
+	var handler http.Handler = r
+	authenticator, err := newAuthenticator(configuration, rootURL)
+	if err != nil {
+		log.Fatalln(fmt.Errorf("error setting up authentication: %w", err))
+	}
+	if authenticator != nil {
+		handler = authenticator.Middleware(handler)
+	}
+
+	lr := logging.Handler(os.Stdout)(handler)
+
+	// Share links are served without authentication.
+	shares := mux.NewRouter()
+	shares.Handle("/s/{token}", s3manager.HandleShare(s3Manager, templates, rootURL)).Methods(http.MethodGet, http.MethodPost)
+
+	// Probes are served without authentication and logging. Readiness fails
+	// as soon as the server starts shutting down.
+	root := http.NewServeMux()
+	root.Handle("GET /healthz", s3manager.HandleHealthz())
+	root.Handle("GET /readyz", drainer.Middleware(readyz))
+	root.Handle("/", lr)
+	if configuration.ShareStore != "" {
+		root.Handle("/s/", logging.Handler(os.Stdout)(shares))
+	}
 
-	lr := logging.Handler(os.Stdout)(r)
 	srv := &http.Server{
 		Addr:         ":" + configuration.Port,
-		Handler:      lr,
+		Handler:      root,
 		ReadTimeout:  serverTimeout,
 		WriteTimeout: serverTimeout,
+		BaseContext:  drainer.BaseContext,
+	}
+	servers := []*http.Server{srv}
+
+	tlsConfig, err := newServerTLSConfig(ctx, configuration)
+	if err != nil {
+		log.Fatalln(fmt.Errorf("error setting up TLS: %w", err))
+	}
+	serve := srv.ListenAndServe
+	if tlsConfig != nil {
+		srv.TLSConfig = tlsConfig
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(configuration.HTTP2)
+		serve = func() error { return srv.ListenAndServeTLS("", "") }
+
+		if configuration.RedirectPort != "" {
+			redirect := &http.Server{
+				Addr:              ":" + configuration.RedirectPort,
+				Handler:           s3manager.HandleHTTPSRedirect(configuration.Port),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			servers = append(servers, redirect)
+			go listen(redirect.ListenAndServe)
+		}
+	}
+	go listen(serve)
+
+	<-ctx.Done()
+	stop()
+	if err := drainer.Shutdown(configuration.DrainDelay, configuration.DrainTimeout, servers...); err != nil {
+		log.Fatalln(fmt.Errorf("error shutting down: %w", err))
+	}
+	log.Println("Shut down")
+}
+
+// listen runs serve and exits if it fails for another reason than a shutdown.
+func listen(serve func() error) {
+	if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+// newServerTLSConfig sets up serving HTTPS with the configured certificate,
+// which is reloaded until ctx is done. It returns nil if the server should use
+// plain HTTP.
+func newServerTLSConfig(ctx context.Context, configuration configuration) (*tls.Config, error) {
+	switch {
+	case configuration.TLSCertFile != "":
+		reloader, err := s3manager.NewCertificateReloader(configuration.TLSCertFile, configuration.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		go reloader.Run(ctx)
+		return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: reloader.GetCertificate}, nil
+	case configuration.TLSSelfSigned:
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+		certificate, err := s3manager.SelfSignedCertificate(hosts...)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Serving HTTPS with a self-signed certificate for %s, which browsers will warn about", strings.Join(hosts, ", "))
+		return &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{certificate}}, nil
+	default:
+		return nil, nil
 	}
-	log.Fatal(srv.ListenAndServe())
 }